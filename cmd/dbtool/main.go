@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/db"
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+const usage = `Usage: go run ./cmd/dbtool [-dsn DSN] [-log-level LEVEL] [-log-format text|json] <command> [args]
+
+Commands:
+  migrate                create or upgrade the schema
+  rebuild-collabs        recompute the collaborations table from track_artists
+  collabs <artist>       list an artist's strongest collaborators (Spotify ID or exact name)
+  centrality             compute degree, closeness and betweenness centrality into artist_metrics
+                         and list the most central artists and the center of each genre
+  metrics <artist>       show an artist's stored centrality
+  bacon <artist> [center]
+                         an artist's number relative to a center (default: the stored center)
+  components             label connected components and detect communities into artist_groups,
+                         and list the largest communities with their genres
+`
+
+func main() {
+	var dsn string
+	var limit, samples int
+	var seed int64
+	var center, logLevel, logFormat string
+	flag.StringVar(&dsn, "dsn", "", "MySQL DSN (defaults to MYSQL_DSN)")
+	flag.IntVar(&limit, "limit", 25, "Max rows to list")
+	flag.IntVar(&samples, "samples", 1000, "centrality: source artists to search on larger graphs (0 for exact)")
+	flag.StringVar(&center, "center", "", "centrality: artist to measure every artist's number from (default: the most central)")
+	flag.Int64Var(&seed, "seed", 1, "centrality, components: seed for sampling sources and ordering label propagation")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	flag.StringVar(&logFormat, "log-format", "text", "Log format: text or json")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	logger, err := sixdegrees.NewLogger(os.Stderr, logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	sixdegrees.SetLogger(logger)
+	log := logger.With("component", "dbtool")
+
+	store, err := db.Open(dsn)
+	if err != nil {
+		fatal(log, "failed to open database", "err", err)
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	switch cmd := flag.Arg(0); cmd {
+	case "migrate":
+		if err := store.Migrate(ctx); err != nil {
+			fatal(log, "migration failed", "err", err)
+		}
+		fmt.Println("Schema is up to date.")
+
+	case "rebuild-collabs":
+		start := time.Now()
+		n, err := store.RebuildCollaborations(ctx)
+		if err != nil {
+			fatal(log, "failed to rebuild collaborations", "err", err)
+		}
+		fmt.Printf("Rebuilt %d collaboration pairs in %s\n", n, time.Since(start).Round(time.Millisecond))
+
+	case "collabs":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		artist, err := resolveArtist(ctx, store, flag.Arg(1))
+		if err != nil {
+			fatal(log, "artist not found", "artist", flag.Arg(1), "err", err)
+		}
+		rows, err := store.ListCollaborators(ctx, artist.ID, limit)
+		if err != nil {
+			fatal(log, "failed to list collaborators", "err", err)
+		}
+		fmt.Printf("Collaborators of %s (%s):\n", artist.Name, artist.ID)
+		for i, c := range rows {
+			fmt.Printf("%3d. %-40s %3d shared tracks%s\n", i+1, artistName(ctx, store, c.Other(artist.ID)), c.SharedTrackCount, yearSpan(c))
+		}
+
+	case "centrality":
+		if err := store.Migrate(ctx); err != nil {
+			fatal(log, "migration failed", "err", err)
+		}
+		runCentrality(ctx, log, store, samples, seed, center, limit)
+
+	case "metrics":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		artist, err := resolveArtist(ctx, store, flag.Arg(1))
+		if err != nil {
+			fatal(log, "artist not found", "artist", flag.Arg(1), "err", err)
+		}
+		m, err := store.GetArtistMetrics(ctx, artist.ID)
+		if err != nil {
+			fatal(log, "no stored metrics; run centrality first", "artist", artist.Name, "err", err)
+		}
+		fmt.Printf("%s (%s):\n", artist.Name, artist.ID)
+		fmt.Printf("  collaborators %d, shared tracks %d\n", m.Degree, m.Strength)
+		fmt.Printf("  closeness %.4f, betweenness %.1f%s\n", m.Closeness, m.Betweenness, sampledNote(m.SampledSources))
+		if m.CenterID.Valid {
+			number := "unreachable"
+			if m.CenterDistance.Valid {
+				number = fmt.Sprint(m.CenterDistance.Int64)
+			}
+			fmt.Printf("  number relative to %s: %s\n", artistName(ctx, store, m.CenterID.String), number)
+		}
+
+	case "bacon":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		artist, err := resolveArtist(ctx, store, flag.Arg(1))
+		if err != nil {
+			fatal(log, "artist not found", "artist", flag.Arg(1), "err", err)
+		}
+		var centerID string
+		if flag.NArg() > 2 {
+			c, err := resolveArtist(ctx, store, flag.Arg(2))
+			if err != nil {
+				fatal(log, "center not found", "center", flag.Arg(2), "err", err)
+			}
+			centerID = c.ID
+		} else if m, err := store.GetArtistMetrics(ctx, artist.ID); err == nil && m.CenterID.Valid {
+			centerID = m.CenterID.String
+		} else {
+			fatal(log, "no stored center; run centrality first or name one", "artist", artist.Name)
+		}
+		g, err := store.LoadCollabGraph(ctx)
+		if err != nil {
+			fatal(log, "failed to load collaborations", "err", err)
+		}
+		path := g.Path(centerID, artist.ID)
+		if path == nil {
+			fmt.Printf("%s is not connected to %s in the stored graph\n", artist.Name, artistName(ctx, store, centerID))
+			return
+		}
+		names := make([]string, len(path))
+		for i, id := range path {
+			names[i] = artistName(ctx, store, id)
+		}
+		fmt.Printf("%s number of %s: %d\n  %s\n", names[0], artist.Name, len(path)-1, strings.Join(names, " → "))
+
+	case "components":
+		if err := store.Migrate(ctx); err != nil {
+			fatal(log, "migration failed", "err", err)
+		}
+		start := time.Now()
+		g, err := store.LoadCollabGraph(ctx)
+		if err != nil {
+			fatal(log, "failed to load collaborations", "err", err)
+		}
+		components, communities := g.Components(), g.Communities(seed)
+		if err := store.ReplaceArtistGroups(ctx, db.NewArtistGroups(components, communities)); err != nil {
+			fatal(log, "failed to save groups", "err", err)
+		}
+		fmt.Printf("%d artists in %d components and %d communities (%s)\n", g.Len(), len(components.Sizes), len(communities.Sizes), time.Since(start).Round(time.Millisecond))
+		if len(components.Sizes) > 0 {
+			fmt.Printf("Largest component: %d artists", components.Sizes[0])
+			if len(components.Sizes) > 1 {
+				fmt.Printf("; the other %d hold %d", len(components.Sizes)-1, g.Len()-components.Sizes[0])
+			}
+			fmt.Println()
+		}
+		list, err := store.ListCommunities(ctx, limit, 5)
+		if err != nil {
+			fatal(log, "failed to list communities", "err", err)
+		}
+		fmt.Printf("\nLargest communities:\n")
+		for _, c := range list {
+			genres := make([]string, len(c.Genres))
+			for i, gc := range c.Genres {
+				genres[i] = fmt.Sprintf("%s (%d)", gc.Genre, gc.Artists)
+			}
+			fmt.Printf("%3d. %4d artists  %s\n      e.g. %s\n", c.ID, c.Size, strings.Join(genres, ", "), strings.Join(c.Members, ", "))
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", cmd)
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+// runCentrality computes every artist's centrality in the stored graph,
+// replaces artist_metrics with it, and lists the most central artists.
+func runCentrality(ctx context.Context, log *slog.Logger, store *db.Store, samples int, seed int64, center string, limit int) {
+	start := time.Now()
+	g, err := store.LoadCollabGraph(ctx)
+	if err != nil {
+		fatal(log, "failed to load collaborations", "err", err)
+	}
+	if g.Len() == 0 {
+		fmt.Println("No collaborations stored; run rebuild-collabs after saving some searches.")
+		return
+	}
+	if samples >= g.Len() {
+		samples = 0
+	}
+	cs := g.Centrality(samples, seed)
+
+	var centerID string
+	if center != "" {
+		a, err := resolveArtist(ctx, store, center)
+		if err != nil {
+			fatal(log, "center not found", "center", center, "err", err)
+		}
+		if !g.Has(a.ID) {
+			fatal(log, "center has no stored collaborations", "center", a.Name)
+		}
+		centerID = a.ID
+	} else {
+		best := cs[0]
+		for _, c := range cs[1:] {
+			if c.Closeness > best.Closeness || (c.Closeness == best.Closeness && c.ID < best.ID) {
+				best = c
+			}
+		}
+		centerID = best.ID
+	}
+	dist := g.Distances(centerID)
+	rows := make([]db.ArtistMetrics, len(cs))
+	for i, c := range cs {
+		rows[i] = db.NewArtistMetrics(c, centerID, dist, samples)
+	}
+	if err := store.ReplaceArtistMetrics(ctx, rows); err != nil {
+		fatal(log, "failed to save metrics", "err", err)
+	}
+	fmt.Printf("Computed centrality for %d artists%s in %s\n", g.Len(), sampledNote(samples), time.Since(start).Round(time.Millisecond))
+	fmt.Printf("Center: %s (%d artists reachable)\n", artistName(ctx, store, centerID), len(dist)-1)
+
+	for _, by := range []string{"betweenness", "closeness", "degree"} {
+		top, err := store.TopArtistMetrics(ctx, by, limit)
+		if err != nil {
+			fatal(log, "failed to list central artists", "by", by, "err", err)
+		}
+		fmt.Printf("\nMost central by %s:\n", by)
+		for i, m := range top {
+			fmt.Printf("%3d. %-40s degree %4d  closeness %.4f  betweenness %.1f\n", i+1, artistName(ctx, store, m.ArtistID), m.Degree, m.Closeness, m.Betweenness)
+		}
+	}
+
+	artists, err := store.ListArtists(ctx)
+	if err != nil {
+		fatal(log, "failed to list artists", "err", err)
+	}
+	genres := make(map[string][]string, len(artists))
+	names := make(map[string]string, len(artists))
+	tagged := make(map[string]int)
+	for _, a := range artists {
+		names[a.ID] = a.Name
+		for genre := range a.Genres {
+			genres[a.ID] = append(genres[a.ID], genre)
+			if g.Has(a.ID) {
+				tagged[genre]++
+			}
+		}
+	}
+	centers := sixdegrees.GenreCenters(cs, genres)
+	byArtists := make([]string, 0, len(centers))
+	for genre := range centers {
+		byArtists = append(byArtists, genre)
+	}
+	sort.Slice(byArtists, func(i, j int) bool {
+		if tagged[byArtists[i]] != tagged[byArtists[j]] {
+			return tagged[byArtists[i]] > tagged[byArtists[j]]
+		}
+		return byArtists[i] < byArtists[j]
+	})
+	if len(byArtists) > limit {
+		byArtists = byArtists[:limit]
+	}
+	fmt.Printf("\nCenter of each genre (largest genres first):\n")
+	for _, genre := range byArtists {
+		c := centers[genre]
+		fmt.Printf("  %-30s %-40s closeness %.4f (%d artists)\n", genre, names[c.ID], c.Closeness, tagged[genre])
+	}
+}
+
+func sampledNote(samples int) string {
+	if samples == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (estimated from %d sources)", samples)
+}
+
+// artistName returns the stored name of an artist, or its ID when unknown.
+func artistName(ctx context.Context, store *db.Store, id string) string {
+	if a, err := store.GetArtistByID(ctx, id); err == nil {
+		return a.Name
+	}
+	return id
+}
+
+// resolveArtist accepts either a stored artist ID or an exact artist name.
+func resolveArtist(ctx context.Context, store *db.Store, key string) (db.DBArtist, error) {
+	if a, err := store.GetArtistByID(ctx, key); err == nil {
+		return a, nil
+	}
+	return store.FindArtistByName(ctx, key)
+}
+
+func yearSpan(c db.DBCollaboration) string {
+	switch {
+	case !c.FirstYear.Valid:
+		return ""
+	case c.FirstYear.Int64 == c.LastYear.Int64:
+		return fmt.Sprintf(" (%d)", c.FirstYear.Int64)
+	default:
+		return fmt.Sprintf(" (%d–%d)", c.FirstYear.Int64, c.LastYear.Int64)
+	}
+}
+
+// fatal logs msg at error level and exits.
+func fatal(log *slog.Logger, msg string, args ...any) {
+	log.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/db"
+)
+
+// communitiesView is passed to the communities template.
+type communitiesView struct {
+	Communities []db.Community `json:"communities"`
+	Message     string         `json:"message,omitempty"`
+}
+
+// handleCommunities lists the largest genre communities of the stored
+// graph, as computed by `dbtool components`.
+func (s *Server) handleCommunities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 25
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "limit must be a whole number", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	status := http.StatusOK
+	var view communitiesView
+	if s.dsn == "" {
+		status = http.StatusServiceUnavailable
+		view.Message = "Start the server with -dsn to see the communities of the stored graph."
+	} else if store, err := s.openStore(); err != nil {
+		s.log.Warn("failed to open database", "err", err)
+		status = http.StatusServiceUnavailable
+		view.Message = "The database is unavailable."
+	} else if view.Communities, err = store.ListCommunities(r.Context(), limit, 5); err != nil {
+		s.log.Warn("failed to list communities", "err", err)
+		status = http.StatusInternalServerError
+		view.Message = "Could not read the communities; has `dbtool components` been run?"
+	} else if len(view.Communities) == 0 {
+		view.Message = "No communities stored yet; run `go run ./cmd/dbtool components`."
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(view); err != nil {
+			s.log.Error("json encode error", "err", err)
+		}
+		return
+	}
+	w.WriteHeader(status)
+	if err := s.communitiesTmpl.Execute(w, view); err != nil {
+		s.log.Error("template execute error", "template", "communities", "err", err)
+	}
+}
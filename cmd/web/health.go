@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/db"
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+// healthTimeout bounds each dependency check.
+const healthTimeout = 2 * time.Second
+
+// check is the outcome of one dependency check.
+type check struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// healthReport is the body of /healthz and /readyz.
+type healthReport struct {
+	Status string           `json:"status"`
+	Checks map[string]check `json:"checks"`
+}
+
+// runChecks checks the Spotify token and, when a DSN is configured, the
+// database. It reports whether every check passed.
+func (s *Server) runChecks(ctx context.Context) (map[string]check, bool) {
+	checks := map[string]check{"spotify_token": result(spotify.CheckToken())}
+	if s.dsn != "" {
+		checks["database"] = result(s.pingDB(ctx))
+	}
+	for _, c := range checks {
+		if !c.OK {
+			return checks, false
+		}
+	}
+	return checks, true
+}
+
+// pingDB checks the database, connecting first if the server started while
+// it was down.
+func (s *Server) pingDB(ctx context.Context) error {
+	store, err := s.openStore()
+	if err != nil {
+		return err
+	}
+	return store.Ping(ctx)
+}
+
+// openStore returns the database connection, opening it on first use.
+func (s *Server) openStore() (*db.Store, error) {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	if s.store == nil {
+		store, err := db.Open(s.dsn)
+		if err != nil {
+			return nil, err
+		}
+		s.store = store
+	}
+	return s.store, nil
+}
+
+// handleHealthz reports liveness: it answers 200 while the process serves
+// requests and includes the dependency checks for information.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+	checks, _ := s.runChecks(ctx)
+	writeHealth(w, http.StatusOK, "ok", checks)
+}
+
+// handleReadyz answers 503 until the Spotify token is valid and the
+// database (if configured) is reachable.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+	checks, ok := s.runChecks(ctx)
+	if !ok {
+		writeHealth(w, http.StatusServiceUnavailable, "unavailable", checks)
+		return
+	}
+	writeHealth(w, http.StatusOK, "ready", checks)
+}
+
+func writeHealth(w http.ResponseWriter, status int, state string, checks map[string]check) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(healthReport{Status: state, Checks: checks}); err != nil {
+		slog.Default().Error("json encode error", "component", "web", "err", err)
+	}
+}
+
+func result(err error) check {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return check{Error: "timed out"}
+	}
+	if err != nil {
+		return check{Error: err.Error()}
+	}
+	return check{OK: true}
+}
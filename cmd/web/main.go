@@ -1,61 +1,193 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Jonnymurillo288/SixDegreesSpotify/db"
 	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
 	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
 )
 
-// Step represents a single hop between two artists
-type Step struct {
-	From  string
-	To    string
-	Track string
-}
-
 // ResultView is passed to the HTML template for displaying results
 type ResultView struct {
-	Start   string
-	Target  string
-	Hops    int
-	Steps   []Step
-	Message string
+	Start     string                `json:"start"`
+	Target    string                `json:"target"`
+	Mode      string                `json:"mode"`
+	Strategy  string                `json:"strategy,omitempty"`
+	Heuristic string                `json:"heuristic,omitempty"`
+	Hops      int                   `json:"hops"`
+	TotalCost float64               `json:"total_cost"`
+	Steps     []sixdegrees.PathStep `json:"steps"`
+	Message   string                `json:"message,omitempty"`
+
+	Stats     *sixdegrees.SearchStats `json:"stats,omitempty"`
+	Diagnosis *sixdegrees.Diagnosis   `json:"diagnosis,omitempty"` // why no path was found
+
+	// StoredGraph notes, after a failed search, that the stored graph does
+	// not connect the two artists either (with -dsn).
+	StoredGraph string `json:"stored_graph,omitempty"`
+
+	// Fingerprint records the search's parameters and starting graph.
+	Fingerprint *sixdegrees.Fingerprint `json:"fingerprint,omitempty"`
+
+	// Set after the path was saved as a playlist (see handlePlaylist).
+	Playlist      *spotify.Playlist `json:"playlist,omitempty"`
+	PlaylistError string            `json:"playlist_error,omitempty"`
+
+	PlayError string `json:"play_error,omitempty"` // why the path could not be played
+}
+
+// Weighted reports whether step costs are meaningful for display.
+func (v ResultView) Weighted() bool { return v.Strategy != "" }
+
+// Playable reports whether any hop has a Spotify track to save or play.
+func (v ResultView) Playable() bool {
+	for _, st := range v.Steps {
+		if st.TrackID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// StepsJSON encodes the steps for the result page's forms, which post the
+// path back instead of searching again.
+func (v ResultView) StepsJSON() string {
+	b, _ := json.Marshal(v.Steps)
+	return string(b)
+}
+
+// searchRequest holds the parsed /search form.
+type searchRequest struct {
+	ID            string // tags the request's log records
+	Start, Target string
+	Depth         int
+	Mode          string
+	Strategy      string
+	Heuristic     string
+
+	// Path constraints; artist lists hold one name per line.
+	Avoid, Via                   []string
+	AllowGenres, DenyGenres      []string
+	MinPopularity, MaxPopularity float64
+	FromYear, ToYear             int
+
+	Fetch spotify.FetchOptions // how much of each artist's catalog to fetch
+
+	Budget sixdegrees.Budget // the server's budget, possibly tightened by the form
 }
 
+// heuristicScale bounds A* estimates for web searches; see the CLI's
+// -heuristic-scale flag.
+const heuristicScale = 0.1
+
 // Server holds templates and serves HTTP requests
 type Server struct {
-	formTmpl   *template.Template
-	resultTmpl *template.Template
+	formTmpl        *template.Template
+	resultTmpl      *template.Template
+	playingTmpl     *template.Template
+	recommendTmpl   *template.Template
+	communitiesTmpl *template.Template
+
+	// mu serializes searches: they share the warm artist graph, which is not
+	// safe for concurrent use.
+	mu    sync.Mutex
+	known *sixdegrees.Helper
+	snap  *sixdegrees.Snapshot
+
+	metrics *metrics
+	log     *slog.Logger
+
+	// budget caps every search and exploration; a search form may only
+	// tighten it.
+	budget sixdegrees.Budget
+
+	// dsn is the database /readyz checks and stored groups are read from;
+	// store is opened from it lazily.
+	dsn     string
+	storeMu sync.Mutex
+	store   *db.Store
 }
 
 func main() {
+	var snapshotPath, dsn, logLevel, logFormat string
+	var budget sixdegrees.Budget
+	flag.StringVar(&snapshotPath, "snapshot", "", "Graph snapshot file to load at startup and append explored artists to")
+	flag.StringVar(&dsn, "dsn", "", "MySQL DSN whose reachability /readyz checks, and whose stored components and communities searches and /communities use")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	flag.StringVar(&logFormat, "log-format", "text", "Log format: text or json")
+	flag.IntVar(&budget.APICalls, "max-api-calls", 2000, "Spotify requests each search may make (0 for no limit)")
+	flag.IntVar(&budget.Expanded, "max-expanded", 500, "Artists whose tracks each search may fetch (0 for no limit)")
+	flag.DurationVar(&budget.Duration, "timeout", 2*time.Minute, "Wall time each search may take (0 for no limit)")
+	flag.Parse()
+
+	logger, err := sixdegrees.NewLogger(os.Stderr, logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	spotify.SetLogger(logger)
+	sixdegrees.SetLogger(logger)
+	log := logger.With("component", "web")
+
 	// Load templates
 	formTmpl := template.Must(template.ParseFiles("templates/path_form.html"))
 	resultTmpl := template.Must(template.ParseFiles("templates/path_result.html"))
-	s := &Server{formTmpl: formTmpl, resultTmpl: resultTmpl}
+	playingTmpl := template.Must(template.ParseFiles("templates/playing.html"))
+	recommendTmpl := template.Must(template.ParseFiles("templates/recommend.html"))
+	communitiesTmpl := template.Must(template.ParseFiles("templates/communities.html"))
+	s := &Server{formTmpl: formTmpl, resultTmpl: resultTmpl, playingTmpl: playingTmpl, recommendTmpl: recommendTmpl, communitiesTmpl: communitiesTmpl, known: sixdegrees.NewHelper(), metrics: newMetrics(), log: log, dsn: dsn, budget: budget}
+
+	if snapshotPath != "" {
+		loadStart := time.Now()
+		snap, err := sixdegrees.OpenSnapshot(snapshotPath, s.known)
+		if err != nil {
+			log.Error("failed to load snapshot", "path", snapshotPath, "err", err)
+			os.Exit(1)
+		}
+		s.snap = snap
+		log.Info("loaded snapshot", "path", snapshotPath, "artists", snap.Len(), "took", time.Since(loadStart).Round(time.Millisecond))
+	}
 
 	// Kick off background Spotify auth check (non-blocking)
 	go func() {
-		log.Println("Initializing Spotify auth (this may open a browser once)...")
+		log.Info("initializing Spotify auth (this may open a browser once)")
 		if _, err := spotify.SearchArtist("healthcheck"); err != nil {
-			log.Printf("Auth initialization warning: %v", err)
+			log.Warn("auth initialization failed", "err", err)
 		}
 	}()
 
 	// Register handlers
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleForm)
-	mux.HandleFunc("/search", s.handleSearch)
+	mux.HandleFunc("/", s.metrics.instrument("/", s.handleForm))
+	mux.HandleFunc("/search", s.metrics.instrument("/search", s.handleSearch))
+	mux.HandleFunc("/playlist", s.metrics.instrument("/playlist", s.handlePlaylist))
+	mux.HandleFunc("/play", s.metrics.instrument("/play", s.handlePlay))
+	mux.HandleFunc("/playback", s.metrics.instrument("/playback", s.handlePlayback))
+	mux.HandleFunc("/player", s.metrics.instrument("/player", s.handlePlayer))
+	mux.HandleFunc("/recommend", s.metrics.instrument("/recommend", s.handleRecommend))
+	mux.HandleFunc("/communities", s.metrics.instrument("/communities", s.handleCommunities))
+	mux.Handle("/metrics", s.metrics)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
 
 	addr := "127.0.0.1:8080"
-	log.Printf("Web UI listening on http://%s", addr)
+	log.Info("web UI listening", "url", "http://"+addr)
 	if err := http.ListenAndServe(addr, mux); err != nil {
-		log.Fatal(err)
+		log.Error("server stopped", "err", err)
+		os.Exit(1)
 	}
 }
 
@@ -65,8 +197,32 @@ func (s *Server) handleForm(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if err := s.formTmpl.Execute(w, nil); err != nil {
-		log.Printf("template execute error (form): %v", err)
+	data := struct {
+		Strategies, Heuristics []string
+		Budget                 sixdegrees.Budget // the server's limits, shown as placeholders
+
+		// Set by ?start=current: the playing track and its credited
+		// artists to pick the start from.
+		Track           string
+		Credits         []*sixdegrees.Artists
+		NowPlayingError string
+	}{
+		Strategies: sixdegrees.StrategyNames(),
+		Heuristics: sixdegrees.HeuristicNames(),
+		Budget:     s.budget,
+	}
+	if s.dsn != "" {
+		data.Heuristics = append(data.Heuristics, db.CenterHeuristic)
+	}
+	if strings.EqualFold(r.URL.Query().Get("start"), "current") {
+		track, credits, err := sixdegrees.NowPlaying()
+		if err != nil {
+			data.NowPlayingError = err.Error()
+		}
+		data.Track, data.Credits = track, credits
+	}
+	if err := s.formTmpl.Execute(w, data); err != nil {
+		s.log.Error("template execute error", "template", "form", "err", err)
 		http.Error(w, "Template error", http.StatusInternalServerError)
 	}
 }
@@ -82,94 +238,407 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	start := r.FormValue("start")
-	target := r.FormValue("find")
-	depthStr := r.FormValue("depth")
+	req := searchRequest{
+		Start:     r.FormValue("start"),
+		Target:    r.FormValue("find"),
+		Depth:     -1,
+		Mode:      r.FormValue("mode"),
+		Strategy:  r.FormValue("strategy"),
+		Heuristic: r.FormValue("heuristic"),
+	}
+	wantJSON := r.FormValue("format") == "json"
+	req.ID = sixdegrees.NewLogID()
+	w.Header().Set("X-Request-ID", req.ID)
 
-	if start == "" || target == "" {
+	if req.Start == "" || req.Target == "" {
 		http.Error(w, "Both 'start' and 'find' fields are required", http.StatusBadRequest)
 		return
 	}
 
-	depth := -1
-	if depthStr != "" {
+	if depthStr := r.FormValue("depth"); depthStr != "" {
 		if d, err := strconv.Atoi(depthStr); err == nil {
-			depth = d
+			req.Depth = d
+		}
+	}
+	if req.Mode == "" {
+		req.Mode = "bfs"
+	}
+	if req.Mode != "bfs" && req.Mode != "weighted" && req.Mode != "astar" {
+		http.Error(w, "mode must be bfs, weighted or astar", http.StatusBadRequest)
+		return
+	}
+	req.Avoid = splitList(r.FormValue("avoid"), "\n")
+	req.Via = splitList(r.FormValue("via"), "\n")
+	req.AllowGenres = splitList(r.FormValue("genres"), ",")
+	req.DenyGenres = splitList(r.FormValue("exclude_genres"), ",")
+	for field, dst := range map[string]*float64{"min_popularity": &req.MinPopularity, "max_popularity": &req.MaxPopularity} {
+		if v := r.FormValue(field); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				http.Error(w, field+" must be a number", http.StatusBadRequest)
+				return
+			}
+			*dst = f
+		}
+	}
+	groups, err := spotify.ParseIncludeGroups(strings.Join(r.Form["include_groups"], ","))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Fetch = spotify.FetchOptions{IncludeGroups: groups, Market: strings.ToUpper(strings.TrimSpace(r.FormValue("market")))}
+	for field, dst := range map[string]*int{"from_year": &req.FromYear, "to_year": &req.ToYear,
+		"album_limit": &req.Fetch.AlbumLimit, "track_limit": &req.Fetch.TrackLimit} {
+		if v := r.FormValue(field); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, field+" must be a whole number", http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	ranges := sixdegrees.Constraints{MinPopularity: req.MinPopularity, MaxPopularity: req.MaxPopularity,
+		FromYear: req.FromYear, ToYear: req.ToYear}
+	if err := ranges.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Budget = s.budget
+	for field, dst := range map[string]*int{"max_api_calls": &req.Budget.APICalls, "max_expanded": &req.Budget.Expanded} {
+		if v := r.FormValue(field); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, field+" must be a whole number", http.StatusBadRequest)
+				return
+			}
+			*dst = tighten(*dst, n)
+		}
+	}
+	if v := r.FormValue("timeout"); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "timeout must be a whole number of seconds", http.StatusBadRequest)
+			return
 		}
+		req.Budget.Duration = tighten(req.Budget.Duration, time.Duration(secs)*time.Second)
+	}
+	if expr := strings.TrimSpace(r.FormValue("strategy_expr")); expr != "" {
+		req.Strategy = expr
+	}
+	if req.Heuristic == "" {
+		req.Heuristic = "genre"
 	}
 
-	res, err := runSearch(start, target, depth)
+	log := s.log.With("request_id", req.ID)
+	log.Info("search started", "start", req.Start, "target", req.Target, "mode", req.Mode)
+	began := time.Now()
+	done := s.metrics.begin(req.Mode)
+	res, err := s.runSearch(req)
+	done(res, err)
+	if res != nil {
+		log.Info("search finished", "hops", res.Hops, "took", time.Since(began).Round(time.Millisecond), "message", res.Message)
+	}
 	if err != nil {
-		log.Printf("Search error: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = s.resultTmpl.Execute(w, ResultView{
-			Start: start, Target: target, Message: fmt.Sprintf("Error: %v", err),
+		log.Error("search failed", "err", err)
+		s.render(w, http.StatusInternalServerError, wantJSON, ResultView{
+			Start: req.Start, Target: req.Target, Mode: req.Mode, Message: fmt.Sprintf("Error: %v", err),
 		})
 		return
 	}
 
-	if res == nil || len(res.Steps) == 0 {
-		_ = s.resultTmpl.Execute(w, ResultView{
-			Start: start, Target: target, Message: "No path found",
+	if res == nil || (len(res.Steps) == 0 && res.Message == "") {
+		s.render(w, http.StatusOK, wantJSON, ResultView{
+			Start: req.Start, Target: req.Target, Mode: req.Mode, Message: "No path found",
 		})
 		return
 	}
 
-	if err := s.resultTmpl.Execute(w, res); err != nil {
-		log.Printf("template execute error (result): %v", err)
+	s.render(w, http.StatusOK, wantJSON, *res)
+}
+
+// handlePlaylist saves a found path, posted back from the result page, as a
+// private playlist and shows the result again with a link to it.
+func (s *Server) handlePlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form submission", http.StatusBadRequest)
+		return
+	}
+	view, ok := postedPath(r)
+	if !ok {
+		http.Error(w, "steps must be the JSON path from a search result", http.StatusBadRequest)
+		return
+	}
+	wantJSON := r.FormValue("format") == "json"
+
+	log := s.log.With("request_id", sixdegrees.NewLogID())
+	pl, err := sixdegrees.CreatePathPlaylist(view.Steps, false)
+	if err != nil {
+		log.Warn("failed to create playlist", "err", err)
+		view.PlaylistError = err.Error()
+		s.render(w, http.StatusBadGateway, wantJSON, view)
+		return
+	}
+	log.Info("created playlist", "playlist_id", pl.ID, "start", view.Start, "target", view.Target)
+	view.Playlist = &pl
+	s.render(w, http.StatusOK, wantJSON, view)
+}
+
+// postedPath rebuilds a result from the fields the result page posts back.
+func postedPath(r *http.Request) (ResultView, bool) {
+	view := ResultView{Start: r.FormValue("start"), Target: r.FormValue("target"), Mode: r.FormValue("mode"),
+		Strategy: r.FormValue("strategy"), Heuristic: r.FormValue("heuristic")}
+	if err := json.Unmarshal([]byte(r.FormValue("steps")), &view.Steps); err != nil || len(view.Steps) == 0 {
+		return view, false
+	}
+	view.Hops = len(view.Steps)
+	view.TotalCost = sixdegrees.TotalCost(view.Steps)
+	return view, true
+}
+
+// render writes a result as JSON or through the result template.
+func (s *Server) render(w http.ResponseWriter, status int, wantJSON bool, v ResultView) {
+	if wantJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			s.log.Error("json encode error", "err", err)
+		}
+		return
+	}
+	w.WriteHeader(status)
+	if err := s.resultTmpl.Execute(w, v); err != nil {
+		s.log.Error("template execute error", "template", "result", "err", err)
 	}
 }
 
 // Core search logic
-func runSearch(start, target string, depth int) (*ResultView, error) {
+func (s *Server) runSearch(req searchRequest) (*ResultView, error) {
+	start, target := req.Start, req.Target
+	var strategy sixdegrees.WeightStrategy
+	var heuristic sixdegrees.Heuristic
+	if req.Mode == "weighted" || req.Mode == "astar" {
+		st, err := sixdegrees.ParseStrategy(req.Strategy)
+		if err != nil {
+			return &ResultView{Start: start, Target: target, Mode: req.Mode, Message: err.Error()}, nil
+		}
+		strategy = st
+	}
+	if req.Mode == "astar" {
+		hr, err := s.heuristic(req.Heuristic)
+		if err != nil {
+			return &ResultView{Start: start, Target: target, Mode: req.Mode, Message: err.Error()}, nil
+		}
+		heuristic = hr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Look up artists
-	srcArtist := sixdegrees.InputArtist(start)
+	srcArtist, err := lookupStart(start)
+	if err != nil {
+		return &ResultView{Start: start, Target: target, Mode: req.Mode, Message: err.Error()}, nil
+	}
 	if srcArtist == nil || srcArtist.ID == "" {
-		return &ResultView{Start: start, Target: target, Message: "Start artist not found"}, nil
+		return &ResultView{Start: start, Target: target, Mode: req.Mode, Message: "Start artist not found"}, nil
 	}
 	dstArtist := sixdegrees.InputArtist(target)
 	if dstArtist == nil || dstArtist.ID == "" {
-		return &ResultView{Start: start, Target: target, Message: "Target artist not found"}, nil
+		return &ResultView{Start: start, Target: target, Mode: req.Mode, Message: "Target artist not found"}, nil
+	}
+
+	// Reuse the warm copy when this artist was already expanded
+	if known, ok := s.known.ArtistMap[srcArtist.Name]; ok && len(known.Tracks) > 0 {
+		known.ID, known.Popularity, known.Genres = srcArtist.ID, srcArtist.Popularity, srcArtist.Genres
+		srcArtist = known
+	}
+
+	constraints, msg := s.buildConstraints(req)
+	if msg != "" {
+		return &ResultView{Start: start, Target: target, Mode: req.Mode, Message: msg}, nil
+	}
+
+	// Run the actual graph search; it fetches the start artist's tracks
+	opts := sixdegrees.SearchOptions{MaxDepth: req.Depth, Known: s.known, Constraints: constraints, Fetch: req.Fetch,
+		Budget: req.Budget, Logger: slog.Default().With("request_id", req.ID)}
+	if req.Mode == "weighted" && s.dsn != "" {
+		if store, err := s.openStore(); err != nil {
+			s.log.Warn("failed to open database for stored collaborations", "request_id", req.ID, "err", err)
+		} else {
+			opts.Collabs = db.SearchCollabs{Store: store, Timeout: collabsTimeout}
+		}
+	}
+	searchMode := req.Mode
+	if strategy != nil {
+		searchMode += " " + req.Strategy
+	}
+	if heuristic != nil {
+		searchMode += " " + req.Heuristic + " " + strconv.FormatFloat(heuristicScale, 'g', -1, 64)
 	}
+	fp := sixdegrees.NewFingerprint(srcArtist, dstArtist, searchMode, opts, s.snap.Version())
 
-	// Fetch albums
-	albums, err := spotify.ArtistAlbums(srcArtist.ID, 15)
+	var helper *sixdegrees.Helper
+	var steps []sixdegrees.PathStep
+	var ok bool
+	switch {
+	case heuristic != nil:
+		helper, steps, ok = sixdegrees.RunAStarSearch(srcArtist, dstArtist, strategy, heuristic, opts)
+	case strategy != nil:
+		helper, steps, ok = sixdegrees.RunWeightedSearch(srcArtist, dstArtist, strategy, opts)
+	default:
+		var path []string
+		helper, path, ok = sixdegrees.RunSearchOpts(srcArtist, dstArtist, opts)
+		steps = helper.Steps(path)
+	}
+	if s.snap != nil {
+		if _, err := s.snap.Append(s.known); err != nil {
+			s.log.Warn("failed to update snapshot", "request_id", req.ID, "err", err)
+		}
+	}
+
+	view := &ResultView{Start: srcArtist.Name, Target: dstArtist.Name, Mode: req.Mode, Stats: helper.Stats, Fingerprint: &fp}
+	if strategy != nil {
+		view.Strategy = req.Strategy
+	}
+	if heuristic != nil {
+		view.Heuristic = req.Heuristic
+	}
+	if !ok || len(steps) == 0 {
+		view.Message = "No path found"
+		view.Diagnosis = helper.Diagnosis
+		view.StoredGraph = s.storedDisconnect(req.ID, srcArtist, dstArtist)
+		return view, nil
+	}
+	view.Hops = len(steps)
+	view.Steps = steps
+	view.TotalCost = sixdegrees.TotalCost(steps)
+	return view, nil
+}
+
+// collabsTimeout bounds loading stored collaborations for a weighted search,
+// or stored center distances for an A* one.
+const collabsTimeout = 10 * time.Second
+
+// heuristic resolves an A* heuristic by name, loading the center distances
+// from the database for db.CenterHeuristic.
+func (s *Server) heuristic(name string) (sixdegrees.Heuristic, error) {
+	if !strings.EqualFold(strings.TrimSpace(name), db.CenterHeuristic) {
+		return sixdegrees.HeuristicByName(name, heuristicScale)
+	}
+	if s.dsn == "" {
+		return nil, fmt.Errorf("the %s heuristic needs a database", db.CenterHeuristic)
+	}
+	store, err := s.openStore()
 	if err != nil {
-		return nil, fmt.Errorf("artist albums: %w", err)
+		return nil, err
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), collabsTimeout)
+	defer cancel()
+	return store.LoadCenterHeuristic(ctx, heuristicScale)
+}
 
-	h := sixdegrees.NewHelper()
+// storedDisconnect notes that the stored graph does not connect a and b
+// either, or returns "" when it may or no database is configured.
+func (s *Server) storedDisconnect(requestID string, a, b *sixdegrees.Artists) string {
+	if s.dsn == "" {
+		return ""
+	}
+	store, err := s.openStore()
+	if err != nil {
+		s.log.Warn("failed to check stored components", "request_id", requestID, "err", err)
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+	note, err := store.DisconnectedNote(ctx, a, b)
+	if err != nil {
+		s.log.Warn("failed to check stored components", "request_id", requestID, "err", err)
+	}
+	return note
+}
 
-	// Populate artist tracks
-	for _, album := range srcArtist.ParseAlbums(albums) {
-		tracks, err := spotify.GetAlbumTracks(album)
+// artistURIPrefix marks a start artist given by Spotify ID, as the form's
+// now-playing picker posts it.
+const artistURIPrefix = "spotify:artist:"
+
+// lookupStart resolves the start field: an artist name, a Spotify artist
+// URI, or "current" for the primary artist of the playing track.
+func lookupStart(start string) (*sixdegrees.Artists, error) {
+	switch {
+	case strings.EqualFold(start, "current"):
+		_, credits, err := sixdegrees.NowPlaying()
+		if err != nil {
+			return nil, err
+		}
+		return credits[0], nil
+	case strings.HasPrefix(start, artistURIPrefix):
+		id := strings.TrimPrefix(start, artistURIPrefix)
+		found, err := sixdegrees.LookupArtists([]string{id})
 		if err != nil {
-			log.Printf("Warning: failed to fetch tracks for album %s: %v", album, err)
-			continue
+			return nil, err
 		}
-		t, _ := srcArtist.CreateTracks(tracks, h)
-		srcArtist.Tracks = append(srcArtist.Tracks, t...)
+		return found[id], nil
 	}
+	return sixdegrees.InputArtist(start), nil
+}
 
-	// Run the actual graph search
-	helper, path, ok := sixdegrees.RunSearchOpts(srcArtist, dstArtist, depth, false)
-	if !ok || len(path) == 0 {
-		return &ResultView{Start: srcArtist.Name, Target: dstArtist.Name, Message: "No path found"}, nil
+// buildConstraints resolves the request's path constraints. It returns a
+// user-facing message instead when an artist cannot be found.
+func (s *Server) buildConstraints(req searchRequest) (*sixdegrees.Constraints, string) {
+	if len(req.Avoid) == 0 && len(req.Via) == 0 && len(req.AllowGenres) == 0 &&
+		len(req.DenyGenres) == 0 && req.MinPopularity == 0 && req.MaxPopularity == 0 &&
+		req.FromYear == 0 && req.ToYear == 0 {
+		return nil, ""
+	}
+	c := &sixdegrees.Constraints{
+		AllowGenres:   req.AllowGenres,
+		DenyGenres:    req.DenyGenres,
+		MinPopularity: req.MinPopularity,
+		MaxPopularity: req.MaxPopularity,
+		FromYear:      req.FromYear,
+		ToYear:        req.ToYear,
+	}
+	for _, name := range req.Avoid {
+		a := sixdegrees.InputArtist(name)
+		if a == nil || a.ID == "" {
+			return nil, fmt.Sprintf("Artist to avoid %q not found", name)
+		}
+		c.ExcludeIDs = append(c.ExcludeIDs, a.ID)
+	}
+	for _, name := range req.Via {
+		a := sixdegrees.InputArtist(name)
+		if a == nil || a.ID == "" {
+			return nil, fmt.Sprintf("Waypoint %q not found", name)
+		}
+		if known, ok := s.known.ArtistMap[a.Name]; ok && len(known.Tracks) > 0 {
+			a = known
+		}
+		c.Waypoints = append(c.Waypoints, a)
 	}
+	return c, ""
+}
 
-	// Build step list
-	steps := make([]Step, 0, len(path)-1)
-	for i := 1; i < len(path); i++ {
-		from := path[i-1]
-		to := path[i]
-		track := helper.Evidence[to]
-		steps = append(steps, Step{From: from, To: to, Track: track})
+// tighten lowers a budget limit to want when want is a tighter limit; a zero
+// limit is unlimited, and a non-positive want keeps the limit.
+func tighten[T int | time.Duration](limit, want T) T {
+	if want > 0 && (limit == 0 || want < limit) {
+		return want
 	}
+	return limit
+}
 
-	return &ResultView{
-		Start:  srcArtist.Name,
-		Target: dstArtist.Name,
-		Hops:   len(path) - 1,
-		Steps:  steps,
-	}, nil
+// splitList splits a form value on sep, dropping empty entries.
+func splitList(v, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(v, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
 }
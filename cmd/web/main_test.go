@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/db"
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+func TestResultPage_PostsPathBackForPlaylist(t *testing.T) {
+	tmpl := template.Must(template.ParseFiles("../../templates/path_result.html"))
+	view := ResultView{Start: "A", Target: "C", Mode: "bfs", Hops: 2, Steps: []sixdegrees.PathStep{
+		{From: "A", To: "B", Track: `Say "hi"`, TrackID: "t1", Cost: 1},
+		{From: "B", To: "C", Track: "bc", TrackID: "t2", Cost: 1},
+	}}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `formaction="/playlist"`) || !strings.Contains(buf.String(), `formaction="/play"`) {
+		t.Fatalf("no playlist form in\n%s", buf.String())
+	}
+
+	// The hidden steps field must decode back into the same path.
+	form := url.Values{"start": {"A"}, "target": {"C"}, "mode": {"bfs"}, "steps": {view.StepsJSON()}}
+	req := httptest.NewRequest("POST", "/playlist", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.ParseForm()
+	got, ok := postedPath(req)
+	if !ok || got.Hops != 2 || got.Steps[0].Track != `Say "hi"` || got.Steps[1].TrackID != "t2" {
+		t.Fatalf("posted path = %+v, %v", got, ok)
+	}
+
+	view.Playlist = &spotify.Playlist{ID: "pl", URL: "https://open.spotify.com/playlist/pl"}
+	buf.Reset()
+	if err := tmpl.Execute(&buf, view); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), `formaction="/playlist"`) || !strings.Contains(buf.String(), view.Playlist.URL) {
+		t.Fatalf("expected a playlist link instead of the button in\n%s", buf.String())
+	}
+}
+
+func TestResultPage_ExplainsMissingPath(t *testing.T) {
+	tmpl := template.Must(template.ParseFiles("../../templates/path_result.html"))
+	view := ResultView{Start: "A", Target: "D", Message: "No path found", Diagnosis: &sixdegrees.Diagnosis{
+		Reason: sixdegrees.StopDepthLimit, From: "A", To: "D", MaxDepth: 1, ByDepth: []int{1, 2}, Frontier: 2,
+		Closest: []sixdegrees.NearMiss{{Name: "B", Hops: 1, Genre: 1, PopularityFit: 1, Path: []sixdegrees.PathStep{{From: "A", To: "B"}}}},
+	}, StoredGraph: "A and D are not connected in the stored graph either"}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"depth limit of 1", "1: 2", "via A → B", "raise the depth limit", "not connected in the stored graph"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("no %q in\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestPlayingPage_MarksHopsByTrack(t *testing.T) {
+	tmpl := template.Must(template.ParseFiles("../../templates/playing.html"))
+	view := ResultView{Start: "A", Target: "B", Steps: []sixdegrees.PathStep{{From: "A", To: "B", Track: "ab", TrackID: "t1"}}}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `data-track="t1"`) {
+		t.Fatalf("hop not tagged with its track in\n%s", buf.String())
+	}
+}
+
+func TestFormPage_OffersPlayingCredits(t *testing.T) {
+	tmpl := template.Must(template.ParseFiles("../../templates/path_form.html"))
+	data := map[string]interface{}{
+		"Track":   "Song",
+		"Credits": []*sixdegrees.Artists{sixdegrees.CreateArtists("Host", "h1"), sixdegrees.CreateArtists("Local", "")},
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `<option value="spotify:artist:h1">Host</option>`) || !strings.Contains(out, `<option value="Local">Local</option>`) {
+		t.Fatalf("credits not offered in\n%s", out)
+	}
+}
+
+func TestRecommendPage_ExplainsWithPath(t *testing.T) {
+	tmpl := template.Must(template.ParseFiles("../../templates/recommend.html"))
+	view := RecommendView{Seed: "A", MinHops: 2, MaxHops: 2, Reached: 3, Recommendations: []sixdegrees.Recommendation{{
+		Name: "D", Hops: 2, Score: 0.8, SharedTracks: 2,
+		Path: []sixdegrees.PathStep{{From: "A", To: "B", Track: "ab", Year: 1999}, {From: "B", To: "D", Track: "bd"}},
+	}}}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<strong>D</strong>") || !strings.Contains(out, "A —[ab (1999)]→ B —[bd]→ D") {
+		t.Fatalf("recommendation not explained in\n%s", out)
+	}
+}
+
+func TestCommunitiesPage(t *testing.T) {
+	tmpl := template.Must(template.ParseFiles("../../templates/communities.html"))
+	s := &Server{communitiesTmpl: tmpl, log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	rec := httptest.NewRecorder()
+	s.handleCommunities(rec, httptest.NewRequest(http.MethodGet, "/communities", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "-dsn") {
+		t.Fatalf("expected 503 asking for -dsn, got %d\n%s", rec.Code, rec.Body.String())
+	}
+
+	view := communitiesView{Communities: []db.Community{{ID: 0, Size: 12, Genres: []db.GenreCount{{Genre: "jazz", Artists: 9}, {Genre: "soul", Artists: 4}}, Members: []string{"A", "B"}}}}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, view); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "<strong>jazz, soul</strong>") || !strings.Contains(out, "<div>A, B</div>") {
+		t.Fatalf("community not shown in\n%s", out)
+	}
+}
+
+func TestTighten_OnlyLowersServerBudget(t *testing.T) {
+	for _, c := range []struct{ limit, want, got int }{
+		{500, 100, 100}, {500, 1000, 500}, {500, 0, 500}, {0, 100, 100}, {0, 0, 0},
+	} {
+		if got := tighten(c.limit, c.want); got != c.got {
+			t.Errorf("tighten(%d, %d) = %d, want %d", c.limit, c.want, got, c.got)
+		}
+	}
+	if got := tighten(2*time.Minute, 30*time.Second); got != 30*time.Second {
+		t.Errorf("tighten(2m, 30s) = %v", got)
+	}
+}
+
+func TestHeuristic_CenterNeedsDatabase(t *testing.T) {
+	s := &Server{}
+	if h, err := s.heuristic("popularity"); err != nil || h == nil {
+		t.Fatalf("built-in heuristic: %v %v", h, err)
+	}
+	if _, err := s.heuristic(db.CenterHeuristic); err == nil || !strings.Contains(err.Error(), "database") {
+		t.Fatalf("expected the center heuristic to need a database, got %v", err)
+	}
+}
+
+func TestSearch_RejectsInvertedRanges(t *testing.T) {
+	s := &Server{}
+	for _, form := range []url.Values{
+		{"min_popularity": {"60"}, "max_popularity": {"20"}},
+		{"from_year": {"2020"}, "to_year": {"2010"}},
+	} {
+		form.Set("start", "A")
+		form.Set("find", "B")
+		r := httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.handleSearch(w, r)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), " is ") {
+			t.Errorf("%v: got %d %q, want 400", form, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+// Buckets for search latency in seconds and path length in hops.
+var (
+	latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	hopBuckets     = []float64{1, 2, 3, 4, 5, 6, 8, 10}
+)
+
+// histogram is a Prometheus-style histogram with fixed upper bounds.
+type histogram struct {
+	bounds []float64
+	counts []uint64 // per bucket, not cumulative; the last one is +Inf
+	sum    float64
+	n      uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.sum += v
+	h.n++
+}
+
+// write emits the bucket, sum and count series; labels is either empty or
+// a rendered label list such as `mode="bfs"`.
+func (h *histogram) write(w io.Writer, name, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var cum uint64
+	for i, b := range h.bounds {
+		cum += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s%sle=%q} %d\n", name, labels, sep, formatFloat(b), cum)
+	}
+	cum += h.counts[len(h.bounds)]
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, cum)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, braces(labels), formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, braces(labels), h.n)
+}
+
+// metrics collects what the web server exposes on /metrics. Spotify counters
+// are read from the spotify package when scraped.
+type metrics struct {
+	mu       sync.Mutex
+	latency  map[string]*histogram // by search mode
+	hops     *histogram            // path length of found paths
+	searches map[[2]string]int     // by mode and outcome
+	requests map[[2]string]int     // HTTP requests by route and status code
+	active   int                   // searches running or waiting for their turn
+
+	cacheHits, cacheMisses int // album track lists, summed over searches
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		latency:  make(map[string]*histogram),
+		hops:     newHistogram(hopBuckets),
+		searches: make(map[[2]string]int),
+		requests: make(map[[2]string]int),
+	}
+}
+
+// begin marks a search as active and returns a function that records its
+// outcome once it finishes.
+func (m *metrics) begin(mode string) func(view *ResultView, err error) {
+	began := time.Now()
+	m.mu.Lock()
+	m.active++
+	m.mu.Unlock()
+	return func(view *ResultView, err error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.active--
+		h, ok := m.latency[mode]
+		if !ok {
+			h = newHistogram(latencyBuckets)
+			m.latency[mode] = h
+		}
+		h.observe(time.Since(began).Seconds())
+
+		outcome := "not_found"
+		switch {
+		case err != nil:
+			outcome = "error"
+		case view != nil && len(view.Steps) > 0:
+			outcome = "found"
+			m.hops.observe(float64(view.Hops))
+		}
+		m.searches[[2]string{mode, outcome}]++
+		if view != nil && view.Stats != nil {
+			m.cacheHits += view.Stats.CacheHits
+			m.cacheMisses += view.Stats.CacheMisses
+		}
+	}
+}
+
+// instrument counts requests to route by status code.
+func (m *metrics) instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		m.mu.Lock()
+		m.requests[[2]string{route, strconv.Itoa(rec.status)}]++
+		m.mu.Unlock()
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// ServeHTTP writes every metric in the Prometheus text format.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w, spotify.Stats())
+}
+
+func (m *metrics) write(w io.Writer, calls spotify.CallStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	header(w, "sixdegrees_search_duration_seconds", "histogram", "Search latency by mode.")
+	modes := make([]string, 0, len(m.latency))
+	for mode := range m.latency {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		m.latency[mode].write(w, "sixdegrees_search_duration_seconds", label("mode", mode))
+	}
+	header(w, "sixdegrees_path_hops", "histogram", "Length of found paths in hops.")
+	m.hops.write(w, "sixdegrees_path_hops", "")
+
+	header(w, "sixdegrees_searches_total", "counter", "Searches by mode and outcome.")
+	for _, k := range sortedPairs(m.searches) {
+		fmt.Fprintf(w, "sixdegrees_searches_total{%s,%s} %d\n", label("mode", k[0]), label("outcome", k[1]), m.searches[k])
+	}
+	header(w, "sixdegrees_active_searches", "gauge", "Searches running or waiting for their turn.")
+	fmt.Fprintf(w, "sixdegrees_active_searches %d\n", m.active)
+
+	header(w, "sixdegrees_http_requests_total", "counter", "HTTP requests by route and status code.")
+	for _, k := range sortedPairs(m.requests) {
+		fmt.Fprintf(w, "sixdegrees_http_requests_total{%s,%s} %d\n", label("route", k[0]), label("code", k[1]), m.requests[k])
+	}
+
+	header(w, "sixdegrees_album_cache_hits_total", "counter", "Album track lists found in memory.")
+	fmt.Fprintf(w, "sixdegrees_album_cache_hits_total %d\n", m.cacheHits)
+	header(w, "sixdegrees_album_cache_misses_total", "counter", "Album track lists requested from Spotify.")
+	fmt.Fprintf(w, "sixdegrees_album_cache_misses_total %d\n", m.cacheMisses)
+	ratio := 0.0
+	if total := m.cacheHits + m.cacheMisses; total > 0 {
+		ratio = float64(m.cacheHits) / float64(total)
+	}
+	header(w, "sixdegrees_album_cache_hit_ratio", "gauge", "Share of album lookups served from memory.")
+	fmt.Fprintf(w, "sixdegrees_album_cache_hit_ratio %s\n", formatFloat(ratio))
+
+	header(w, "spotify_requests_total", "counter", "Spotify API requests by endpoint, including retries.")
+	endpoints := make([]string, 0, len(calls.Calls))
+	for endpoint := range calls.Calls {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		fmt.Fprintf(w, "spotify_requests_total{%s} %d\n", label("endpoint", endpoint), calls.Calls[endpoint])
+	}
+	header(w, "spotify_responses_total", "counter", "Spotify API responses by status code.")
+	codes := make([]int, 0, len(calls.Responses))
+	for code := range calls.Responses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "spotify_responses_total{%s} %d\n", label("code", strconv.Itoa(code)), calls.Responses[code])
+	}
+	header(w, "spotify_request_errors_total", "counter", "Spotify API requests that got no response.")
+	fmt.Fprintf(w, "spotify_request_errors_total %d\n", calls.Errors)
+	header(w, "spotify_throttle_waits_total", "counter", "Pauses for Spotify rate limiting.")
+	fmt.Fprintf(w, "spotify_throttle_waits_total %d\n", calls.ThrottleWaits)
+	header(w, "spotify_throttle_seconds_total", "counter", "Time spent in those pauses.")
+	fmt.Fprintf(w, "spotify_throttle_seconds_total %s\n", formatFloat(calls.Throttled.Seconds()))
+}
+
+// ================================= Helpers ==================================
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func label(name, value string) string { return name + "=" + strconv.Quote(value) }
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+func sortedPairs(m map[[2]string]int) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+func TestMetrics_Exposition(t *testing.T) {
+	m := newMetrics()
+	done := m.begin("bfs")
+	done(&ResultView{Hops: 3, Steps: make([]sixdegrees.PathStep, 3), Stats: &sixdegrees.SearchStats{CacheHits: 3, CacheMisses: 1}}, nil)
+	m.begin("astar")(&ResultView{Message: "No path found"}, nil)
+	m.begin("bfs") // still running
+
+	var buf bytes.Buffer
+	m.write(&buf, spotify.CallStats{
+		Calls:     map[string]int{"artists/{id}/albums": 4},
+		Responses: map[int]int{200: 3, 429: 1},
+		Throttled: 1500 * time.Millisecond,
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE sixdegrees_search_duration_seconds histogram\n",
+		`sixdegrees_search_duration_seconds_count{mode="bfs"} 1`,
+		`sixdegrees_path_hops_bucket{le="2"} 0`,
+		`sixdegrees_path_hops_bucket{le="3"} 1`,
+		`sixdegrees_path_hops_bucket{le="+Inf"} 1`,
+		"sixdegrees_path_hops_sum 3\n",
+		`sixdegrees_searches_total{mode="astar",outcome="not_found"} 1`,
+		`sixdegrees_searches_total{mode="bfs",outcome="found"} 1`,
+		"sixdegrees_active_searches 1\n",
+		"sixdegrees_album_cache_hit_ratio 0.75\n",
+		`spotify_requests_total{endpoint="artists/{id}/albums"} 4`,
+		`spotify_responses_total{code="429"} 1`,
+		"spotify_throttle_seconds_total 1.5\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
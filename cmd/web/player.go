@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+// nowPlaying is the /playback response the now-playing page polls.
+type nowPlaying struct {
+	Playing    bool    `json:"playing"`
+	TrackID    string  `json:"track_id,omitempty"`
+	Track      string  `json:"track,omitempty"`
+	Photo      string  `json:"photo,omitempty"`
+	ProgressMS float64 `json:"progress_ms"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// handlePlay starts playing a found path's evidence tracks, posted back from
+// the result page, and shows the now-playing view.
+func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form submission", http.StatusBadRequest)
+		return
+	}
+	view, ok := postedPath(r)
+	if !ok {
+		http.Error(w, "steps must be the JSON path from a search result", http.StatusBadRequest)
+		return
+	}
+	ids := sixdegrees.PathTrackIDs(view.Steps)
+	if len(ids) == 0 {
+		http.Error(w, "no hop has a Spotify track to play", http.StatusBadRequest)
+		return
+	}
+
+	log := s.log.With("request_id", sixdegrees.NewLogID())
+	if err := spotify.PlayTracks(ids); err != nil {
+		log.Warn("failed to play path", "err", err)
+		view.PlayError = err.Error() + " (is Spotify open on one of your devices?)"
+		s.render(w, http.StatusBadGateway, false, view)
+		return
+	}
+	log.Info("playing path", "tracks", len(ids), "start", view.Start, "target", view.Target)
+	if err := s.playingTmpl.Execute(w, view); err != nil {
+		s.log.Error("template execute error", "template", "playing", "err", err)
+	}
+}
+
+// handlePlayback reports what the user's device is playing.
+func (s *Server) handlePlayback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := spotify.GetPlayback()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(nowPlaying{
+		Playing:    q.Playing,
+		TrackID:    q.TrackID,
+		Track:      q.TrackName,
+		Photo:      q.TrackPhoto,
+		ProgressMS: q.Progress,
+		DurationMS: q.Duration,
+	}); err != nil {
+		s.log.Error("json encode error", "err", err)
+	}
+}
+
+// handlePlayer sends the posted action (play, pause, next or previous) to
+// the user's device.
+func (s *Server) handlePlayer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	action := r.FormValue("action")
+	switch action {
+	case "play", "pause", "next", "previous":
+	default:
+		http.Error(w, "action must be play, pause, next or previous", http.StatusBadRequest)
+		return
+	}
+	if err := spotify.Controller(action); err != nil {
+		s.log.Warn("player action failed", "action", action, "err", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
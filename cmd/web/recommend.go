@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+// RecommendView is passed to the recommend template, and is the JSON
+// response of /recommend?format=json.
+type RecommendView struct {
+	Seed            string                      `json:"seed"`
+	MinHops         int                         `json:"min_hops"`
+	MaxHops         int                         `json:"max_hops"`
+	Reached         int                         `json:"artists_reached"`
+	Recommendations []sixdegrees.Recommendation `json:"recommendations"`
+	Message         string                      `json:"message,omitempty"`
+
+	// Form values to show again.
+	Count                        int    `json:"-"`
+	Genres, ExcludeGenres        string `json:"-"`
+	MinPopularity, MaxPopularity string `json:"-"`
+
+	Stats *sixdegrees.SearchStats `json:"stats,omitempty"`
+}
+
+// handleRecommend shows the recommendation form and, once a seed is given,
+// artists a few hops from it with the path that reaches each.
+func (s *Server) handleRecommend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	view := RecommendView{
+		Seed:          strings.TrimSpace(q.Get("seed")),
+		MinHops:       2,
+		MaxHops:       2,
+		Count:         sixdegrees.DefaultRecommendations,
+		Genres:        q.Get("genres"),
+		ExcludeGenres: q.Get("exclude_genres"),
+		MinPopularity: q.Get("min_popularity"),
+		MaxPopularity: q.Get("max_popularity"),
+	}
+	wantJSON := q.Get("format") == "json"
+	for field, dst := range map[string]*int{"min_hops": &view.MinHops, "max_hops": &view.MaxHops, "count": &view.Count} {
+		if v := q.Get(field); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, field+" must be a whole number", http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	req := searchRequest{
+		ID:          sixdegrees.NewLogID(),
+		AllowGenres: splitList(view.Genres, ","),
+		DenyGenres:  splitList(view.ExcludeGenres, ","),
+		Avoid:       splitList(q.Get("avoid"), "\n"),
+	}
+	for field, dst := range map[string]*float64{"min_popularity": &req.MinPopularity, "max_popularity": &req.MaxPopularity} {
+		if v := q.Get(field); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				http.Error(w, field+" must be a number", http.StatusBadRequest)
+				return
+			}
+			*dst = f
+		}
+	}
+	w.Header().Set("X-Request-ID", req.ID)
+	if view.Seed == "" {
+		s.renderRecommend(w, http.StatusOK, wantJSON, view)
+		return
+	}
+
+	log := s.log.With("request_id", req.ID)
+	log.Info("recommend started", "seed", view.Seed, "min_hops", view.MinHops, "max_hops", view.MaxHops)
+	began := time.Now()
+	s.runRecommend(req, &view)
+	log.Info("recommend finished", "recommendations", len(view.Recommendations), "took", time.Since(began).Round(time.Millisecond), "message", view.Message)
+	s.renderRecommend(w, http.StatusOK, wantJSON, view)
+}
+
+// runRecommend fills view with recommendations for its seed, or with a
+// user-facing message explaining why there are none.
+func (s *Server) runRecommend(req searchRequest, view *RecommendView) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seed, err := lookupStart(view.Seed)
+	if err != nil {
+		view.Message = err.Error()
+		return
+	}
+	if seed == nil || seed.ID == "" {
+		view.Message = "Seed artist not found"
+		return
+	}
+	if known, ok := s.known.ArtistMap[seed.Name]; ok && len(known.Tracks) > 0 {
+		known.ID, known.Popularity, known.Genres = seed.ID, seed.Popularity, seed.Genres
+		seed = known
+	}
+	view.Seed = seed.Name
+	constraints, msg := s.buildConstraints(req)
+	if msg != "" {
+		view.Message = msg
+		return
+	}
+	opts := sixdegrees.SearchOptions{Known: s.known, Constraints: constraints, Budget: s.budget,
+		Logger: s.log.With("request_id", req.ID)}
+	n, recs, err := sixdegrees.Recommend(seed, view.MinHops, view.MaxHops, view.Count, opts)
+	if err != nil {
+		view.Message = err.Error()
+		return
+	}
+	if s.snap != nil {
+		if _, err := s.snap.Append(s.known); err != nil {
+			s.log.Warn("failed to update snapshot", "request_id", req.ID, "err", err)
+		}
+	}
+	view.Reached = len(n.Helper.DistTo) - 1
+	view.Recommendations = recs
+	view.Stats = n.Helper.Stats
+	if len(recs) == 0 {
+		view.Message = "No artists found at that distance"
+	}
+}
+
+// renderRecommend writes recommendations as JSON or through the recommend
+// template.
+func (s *Server) renderRecommend(w http.ResponseWriter, status int, wantJSON bool, v RecommendView) {
+	if wantJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			s.log.Error("json encode error", "err", err)
+		}
+		return
+	}
+	w.WriteHeader(status)
+	if err := s.recommendTmpl.Execute(w, v); err != nil {
+		s.log.Error("template execute error", "template", "recommend", "err", err)
+	}
+}
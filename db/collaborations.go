@@ -0,0 +1,307 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+// maxSampleTracks caps how many track IDs are kept as evidence per collaboration.
+const maxSampleTracks = 5
+
+// DBCollaboration is one row of the derived collaborations table: an unordered
+// artist pair with how many tracks they share and over which years.
+// ArtistA always sorts before ArtistB.
+type DBCollaboration struct {
+	ArtistA          string
+	ArtistB          string
+	SharedTrackCount int
+	FirstYear        sql.NullInt64
+	LastYear         sql.NullInt64
+	SampleTrackIDs   []string // stored as JSON TEXT, most recent first
+}
+
+// Other returns the collaborator of id in this pair.
+func (c DBCollaboration) Other(id string) string {
+	if c.ArtistA == id {
+		return c.ArtistB
+	}
+	return c.ArtistA
+}
+
+// EdgeContext converts the row into the metadata consumed by weight
+// strategies; SearchCollabs serves it to weighted searches.
+func (c DBCollaboration) EdgeContext(currentYear int) sixdegrees.EdgeContext {
+	ctx := sixdegrees.EdgeContext{SharedCount: c.SharedTrackCount}
+	if len(c.SampleTrackIDs) > 0 {
+		ctx.EvidenceID = c.SampleTrackIDs[0]
+	}
+	if c.LastYear.Valid {
+		ctx.RecencyScore = sixdegrees.RecencyScore(int(c.LastYear.Int64), currentYear)
+	}
+	return ctx
+}
+
+// collabPair orders two artist IDs the way the collaborations table stores them.
+func collabPair(x, y string) (string, string) {
+	if x > y {
+		return y, x
+	}
+	return x, y
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func upsertCollaboration(ctx context.Context, ex execer, c DBCollaboration) error {
+	a, b := collabPair(c.ArtistA, c.ArtistB)
+	if a == "" || b == "" || a == b {
+		return errors.New("two distinct artist ids required")
+	}
+	samples, _ := json.Marshal(c.SampleTrackIDs)
+	q := `INSERT INTO collaborations (artist_a, artist_b, shared_track_count, first_year, last_year, sample_track_ids)
+		VALUES (?,?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE shared_track_count=VALUES(shared_track_count), first_year=VALUES(first_year),
+			last_year=VALUES(last_year), sample_track_ids=VALUES(sample_track_ids)`
+	_, err := ex.ExecContext(ctx, q, a, b, c.SharedTrackCount, nullInt(c.FirstYear), nullInt(c.LastYear), string(samples))
+	return err
+}
+
+// UpsertCollaboration writes a precomputed collaboration row.
+func (s *Store) UpsertCollaboration(ctx context.Context, c DBCollaboration) error {
+	return upsertCollaboration(ctx, s.DB, c)
+}
+
+// addSharedTrack folds one shared track into the running aggregate for a pair.
+// Tracks must arrive most recent first so the samples keep the newest evidence.
+func (c *DBCollaboration) addSharedTrack(trackID string, year sql.NullInt64) {
+	c.SharedTrackCount++
+	if year.Valid {
+		if !c.FirstYear.Valid || year.Int64 < c.FirstYear.Int64 {
+			c.FirstYear = year
+		}
+		if !c.LastYear.Valid || year.Int64 > c.LastYear.Int64 {
+			c.LastYear = year
+		}
+	}
+	if len(c.SampleTrackIDs) < maxSampleTracks {
+		c.SampleTrackIDs = append(c.SampleTrackIDs, trackID)
+	}
+}
+
+// refreshBatch is how many artist pairs one refresh statement recomputes.
+const refreshBatch = 500
+
+// sampleSep separates the sample track IDs while they are aggregated.
+// JSON_QUOTE escapes control characters, so it never occurs inside a quoted
+// ID, and cutting the list at the maxSampleTracks-th separator keeps whole
+// IDs. The list starts with the newest tracks, so the samples survive
+// group_concat_max_len cutting off a long one.
+const sampleSep = "\x1e"
+
+// refreshCollabsSQL recomputes the collaborations rows of the pairs in its
+// IN list, which each sort artist_a before artist_b.
+var refreshCollabsSQL = `INSERT INTO collaborations (artist_a, artist_b, shared_track_count, first_year, last_year, sample_track_ids)
+	SELECT ta.artist_id, tb.artist_id, COUNT(*), MIN(t.release_year), MAX(t.release_year),
+		CONCAT('[', REPLACE(SUBSTRING_INDEX(
+			GROUP_CONCAT(JSON_QUOTE(t.id) ORDER BY t.release_year DESC, t.id SEPARATOR '` + sampleSep + `'),
+			'` + sampleSep + `', ` + strconv.Itoa(maxSampleTracks) + `), '` + sampleSep + `', ','), ']')
+	FROM track_artists ta
+	JOIN track_artists tb ON tb.track_id = ta.track_id
+	JOIN tracks t ON t.id = ta.track_id
+	WHERE (ta.artist_id, tb.artist_id) IN (%s)
+	GROUP BY ta.artist_id, tb.artist_id
+	ON DUPLICATE KEY UPDATE shared_track_count=VALUES(shared_track_count), first_year=VALUES(first_year),
+		last_year=VALUES(last_year), sample_track_ids=VALUES(sample_track_ids)`
+
+// RefreshCollaborations recomputes the rows of the given artist pairs from
+// track_artists, with one statement per refreshBatch pairs. It is idempotent,
+// so saving the same tracks twice does not inflate the counts. Pairs that no
+// longer share a track keep their old row until RebuildCollaborations.
+func (s *Store) RefreshCollaborations(ctx context.Context, pairs [][2]string) error {
+	for start := 0; start < len(pairs); start += refreshBatch {
+		batch := pairs[start:min(start+refreshBatch, len(pairs))]
+		args := make([]interface{}, 0, 2*len(batch))
+		for _, p := range batch {
+			a, b := collabPair(p[0], p[1])
+			if a == "" || b == "" || a == b {
+				return errors.New("two distinct artist ids required")
+			}
+			args = append(args, a, b)
+		}
+		q := fmt.Sprintf(refreshCollabsSQL, strings.TrimSuffix(strings.Repeat("(?,?),", len(batch)), ","))
+		if _, err := s.DB.ExecContext(ctx, q, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildCollaborations discards the collaborations table and derives it again
+// from every stored track. It returns the number of pairs written.
+func (s *Store) RebuildCollaborations(ctx context.Context) (int, error) {
+	q := `SELECT ta.artist_id, tb.artist_id, t.id, t.release_year
+		FROM track_artists ta
+		JOIN track_artists tb ON tb.track_id = ta.track_id AND ta.artist_id < tb.artist_id
+		JOIN tracks t ON t.id = ta.track_id
+		ORDER BY ta.artist_id, tb.artist_id, t.release_year DESC, t.id`
+	rows, err := s.DB.QueryContext(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	var out []DBCollaboration
+	for rows.Next() {
+		var a, b, id string
+		var year sql.NullInt64
+		if err := rows.Scan(&a, &b, &id, &year); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if n := len(out); n == 0 || out[n-1].ArtistA != a || out[n-1].ArtistB != b {
+			out = append(out, DBCollaboration{ArtistA: a, ArtistB: b})
+		}
+		out[len(out)-1].addSharedTrack(id, year)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collaborations`); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	for _, c := range out {
+		if err := upsertCollaboration(ctx, tx, c); err != nil {
+			_ = tx.Rollback()
+			return 0, fmt.Errorf("write %s/%s: %w", c.ArtistA, c.ArtistB, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(out), nil
+}
+
+// GetCollaboration returns the row for a pair in either order.
+func (s *Store) GetCollaboration(ctx context.Context, x, y string) (DBCollaboration, error) {
+	a, b := collabPair(x, y)
+	q := `SELECT artist_a, artist_b, shared_track_count, first_year, last_year, sample_track_ids
+		FROM collaborations WHERE artist_a=? AND artist_b=?`
+	return scanCollaboration(s.DB.QueryRowContext(ctx, q, a, b))
+}
+
+// ListCollaborators returns an artist's collaborations, strongest first.
+func (s *Store) ListCollaborators(ctx context.Context, artistID string, limit int) ([]DBCollaboration, error) {
+	if artistID == "" {
+		return nil, errors.New("artistID required")
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 50
+	}
+	q := `SELECT artist_a, artist_b, shared_track_count, first_year, last_year, sample_track_ids
+		FROM collaborations
+		WHERE artist_a = ? OR artist_b = ?
+		ORDER BY shared_track_count DESC, last_year DESC LIMIT ?`
+	rows, err := s.DB.QueryContext(ctx, q, artistID, artistID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DBCollaboration
+	for rows.Next() {
+		c, err := scanCollaboration(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCollaboration(r rowScanner) (DBCollaboration, error) {
+	var c DBCollaboration
+	var samples sql.NullString
+	if err := r.Scan(&c.ArtistA, &c.ArtistB, &c.SharedTrackCount, &c.FirstYear, &c.LastYear, &samples); err != nil {
+		return c, err
+	}
+	if samples.Valid && samples.String != "" {
+		_ = json.Unmarshal([]byte(samples.String), &c.SampleTrackIDs)
+	}
+	return c, nil
+}
+
+// collabLookupBatch is how many artists one CollabsAmong query asks about.
+const collabLookupBatch = 500
+
+// CollabsAmong returns the stored collaborations between any two of ids as
+// the edge metadata weight strategies read, with recency measured against
+// currentYear.
+func (s *Store) CollabsAmong(ctx context.Context, ids []string, currentYear int) (map[sixdegrees.PairKey]sixdegrees.EdgeContext, error) {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	out := make(map[sixdegrees.PairKey]sixdegrees.EdgeContext)
+	for start := 0; start < len(ids); start += collabLookupBatch {
+		batch := ids[start:min(start+collabLookupBatch, len(ids))]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		q := `SELECT artist_a, artist_b, shared_track_count, first_year, last_year, sample_track_ids
+			FROM collaborations WHERE artist_a IN (` + strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",") + `)`
+		rows, err := s.DB.QueryContext(ctx, q, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			c, err := scanCollaboration(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if want[c.ArtistB] {
+				out[sixdegrees.NewPairKey(c.ArtistA, c.ArtistB)] = c.EdgeContext(currentYear)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// SearchCollabs serves the collaborations table to weighted searches as
+// sixdegrees.SearchOptions.Collabs.
+type SearchCollabs struct {
+	Store   *Store
+	Timeout time.Duration // per lookup; 0 for none
+}
+
+func (c SearchCollabs) Collaborations(ids []string) (map[sixdegrees.PairKey]sixdegrees.EdgeContext, error) {
+	ctx := context.Background()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	return c.Store.CollabsAmong(ctx, ids, time.Now().Year())
+}
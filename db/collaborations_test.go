@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestCollabPair_OrdersIDs(t *testing.T) {
+	a, b := collabPair("zz", "aa")
+	if a != "aa" || b != "zz" {
+		t.Fatalf("expected (aa, zz), got (%s, %s)", a, b)
+	}
+	a, b = collabPair("aa", "zz")
+	if a != "aa" || b != "zz" {
+		t.Fatalf("expected (aa, zz), got (%s, %s)", a, b)
+	}
+}
+
+func TestAddSharedTrack_AggregatesYearsAndSamples(t *testing.T) {
+	c := DBCollaboration{ArtistA: "a", ArtistB: "b"}
+	years := []sql.NullInt64{
+		{Int64: 2020, Valid: true},
+		{},
+		{Int64: 2011, Valid: true},
+		{Int64: 2015, Valid: true},
+		{Int64: 2015, Valid: true},
+		{Int64: 2009, Valid: true},
+	}
+	ids := []string{"t1", "t2", "t3", "t4", "t5", "t6"}
+	for i := range ids {
+		c.addSharedTrack(ids[i], years[i])
+	}
+	if c.SharedTrackCount != 6 {
+		t.Fatalf("expected 6 shared tracks, got %d", c.SharedTrackCount)
+	}
+	if c.FirstYear.Int64 != 2009 || c.LastYear.Int64 != 2020 {
+		t.Fatalf("unexpected year span %v..%v", c.FirstYear, c.LastYear)
+	}
+	if len(c.SampleTrackIDs) != maxSampleTracks || c.SampleTrackIDs[0] != "t1" {
+		t.Fatalf("unexpected samples %v", c.SampleTrackIDs)
+	}
+}
+
+func TestDBCollaboration_EdgeContext(t *testing.T) {
+	c := DBCollaboration{ArtistA: "a", ArtistB: "b", SharedTrackCount: 3, LastYear: sql.NullInt64{Int64: 2024, Valid: true}}
+	ctx := c.EdgeContext(2024)
+	if ctx.SharedCount != 3 || ctx.RecencyScore != 1 {
+		t.Fatalf("unexpected edge context %+v", ctx)
+	}
+	if got := c.Other("a"); got != "b" {
+		t.Fatalf("expected other of a to be b, got %s", got)
+	}
+	if ctx := (DBCollaboration{SharedTrackCount: 1}).EdgeContext(2024); ctx.RecencyScore != 0 {
+		t.Fatalf("unknown year should score 0, got %v", ctx.RecencyScore)
+	}
+}
+
+func TestRefreshCollaborations_RejectsSelfPair(t *testing.T) {
+	var s Store
+	err := s.RefreshCollaborations(context.Background(), [][2]string{{"a", "a"}})
+	if err == nil || !strings.Contains(err.Error(), "distinct") {
+		t.Fatalf("expected an error for a pair of the same artist, got %v", err)
+	}
+	if err := s.RefreshCollaborations(context.Background(), nil); err != nil {
+		t.Fatalf("no pairs should need no statement, got %v", err)
+	}
+}
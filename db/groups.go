@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+// ArtistGroup is one row of the artist_groups table: the connected
+// component and the community an artist belongs to in the stored graph.
+// Groups are numbered from 0, largest first.
+type ArtistGroup struct {
+	ArtistID      string
+	Component     int
+	ComponentSize int
+	Community     int
+	CommunitySize int
+}
+
+// NewArtistGroups combines components and communities into rows, one per
+// artist in components.
+func NewArtistGroups(components, communities sixdegrees.Groups) []ArtistGroup {
+	rows := make([]ArtistGroup, 0, len(components.Of))
+	for id, c := range components.Of {
+		m := communities.Of[id]
+		rows = append(rows, ArtistGroup{ArtistID: id, Component: c, ComponentSize: components.Sizes[c],
+			Community: m, CommunitySize: communities.Sizes[m]})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ArtistID < rows[j].ArtistID })
+	return rows
+}
+
+// ReplaceArtistGroups discards the artist_groups table and writes rows in
+// its place.
+func (s *Store) ReplaceArtistGroups(ctx context.Context, rows []ArtistGroup) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM artist_groups`); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for start := 0; start < len(rows); start += metricsBatch {
+		end := start + metricsBatch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+		args := make([]interface{}, 0, 5*len(batch))
+		for _, g := range batch {
+			args = append(args, g.ArtistID, g.Component, g.ComponentSize, g.Community, g.CommunitySize)
+		}
+		q := `INSERT INTO artist_groups (artist_id, component, component_size, community, community_size)
+			VALUES ` + strings.TrimSuffix(strings.Repeat("(?,?,?,?,?),", len(batch)), ",")
+		if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("write groups: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetArtistGroup returns the groups of one artist. It returns
+// sql.ErrNoRows when the artist has no stored collaborations or groups
+// were never computed.
+func (s *Store) GetArtistGroup(ctx context.Context, artistID string) (ArtistGroup, error) {
+	var g ArtistGroup
+	q := `SELECT artist_id, component, component_size, community, community_size FROM artist_groups WHERE artist_id=?`
+	err := s.DB.QueryRowContext(ctx, q, artistID).Scan(&g.ArtistID, &g.Component, &g.ComponentSize, &g.Community, &g.CommunitySize)
+	return g, err
+}
+
+// Disconnected reports whether a and b are known to lie in different
+// components of the stored graph, returning both artists' groups. Artists
+// without stored groups are never reported as disconnected.
+func (s *Store) Disconnected(ctx context.Context, a, b string) (bool, ArtistGroup, ArtistGroup, error) {
+	ga, err := s.GetArtistGroup(ctx, a)
+	if err == sql.ErrNoRows {
+		return false, ga, ArtistGroup{}, nil
+	} else if err != nil {
+		return false, ga, ArtistGroup{}, err
+	}
+	gb, err := s.GetArtistGroup(ctx, b)
+	if err == sql.ErrNoRows {
+		return false, ga, gb, nil
+	} else if err != nil {
+		return false, ga, gb, err
+	}
+	return ga.Component != gb.Component, ga, gb, nil
+}
+
+// DisconnectedNote explains that the stored graph does not connect a and b
+// either, or returns "" when it may. The stored graph is a partial crawl that
+// Spotify may well connect, so this is only a hint for after a search fails,
+// never a reason not to search.
+func (s *Store) DisconnectedNote(ctx context.Context, a, b *sixdegrees.Artists) (string, error) {
+	apart, ga, gb, err := s.Disconnected(ctx, a.ID, b.ID)
+	if err != nil || !apart {
+		return "", err
+	}
+	return fmt.Sprintf("%s and %s are not connected in the stored graph either: they are in different components (%d and %d artists)",
+		a.Name, b.Name, ga.ComponentSize, gb.ComponentSize), nil
+}
+
+// Community summarizes one community of the stored graph.
+type Community struct {
+	ID        int          `json:"id"`
+	Size      int          `json:"size"`
+	Component int          `json:"component"`
+	Genres    []GenreCount `json:"genres"`  // most common first
+	Members   []string     `json:"members"` // most popular first
+}
+
+// GenreCount is how many artists of a community carry a genre.
+type GenreCount struct {
+	Genre   string `json:"genre"`
+	Artists int    `json:"artists"`
+}
+
+// ListCommunities returns the largest communities with their most common
+// genres and most popular members, up to top of each.
+func (s *Store) ListCommunities(ctx context.Context, limit, top int) ([]Community, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 25
+	}
+	q := `SELECT g.community, g.community_size, g.component, a.name, a.popularity, a.genres
+		FROM artist_groups g JOIN artists a ON a.id = g.artist_id
+		WHERE g.community IN (SELECT community FROM (
+			SELECT DISTINCT community FROM artist_groups ORDER BY community LIMIT ?) AS c)
+		ORDER BY g.community, a.popularity DESC, a.name`
+	rows, err := s.DB.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Community
+	counts := make(map[string]int)
+	flush := func() {
+		if len(out) > 0 {
+			out[len(out)-1].Genres = topGenres(counts, top)
+		}
+		counts = make(map[string]int)
+	}
+	for rows.Next() {
+		var id, size, component int
+		var name string
+		var pop sql.NullInt64
+		var genres sql.NullString
+		if err := rows.Scan(&id, &size, &component, &name, &pop, &genres); err != nil {
+			return nil, err
+		}
+		if n := len(out); n == 0 || out[n-1].ID != id {
+			flush()
+			out = append(out, Community{ID: id, Size: size, Component: component})
+		}
+		c := &out[len(out)-1]
+		if len(c.Members) < top {
+			c.Members = append(c.Members, name)
+		}
+		if genres.Valid && genres.String != "" {
+			var gs map[string]int
+			_ = json.Unmarshal([]byte(genres.String), &gs)
+			for g := range gs {
+				counts[g]++
+			}
+		}
+	}
+	flush()
+	return out, rows.Err()
+}
+
+// topGenres returns the n most common genres, ties by name.
+func topGenres(counts map[string]int, n int) []GenreCount {
+	out := make([]GenreCount, 0, len(counts))
+	for g, c := range counts {
+		out = append(out, GenreCount{Genre: g, Artists: c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Artists != out[j].Artists {
+			return out[i].Artists > out[j].Artists
+		}
+		return out[i].Genre < out[j].Genre
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
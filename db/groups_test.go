@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+func TestNewArtistGroups(t *testing.T) {
+	components := sixdegrees.Groups{Of: map[string]int{"a": 0, "b": 0, "c": 1}, Sizes: []int{2, 1}}
+	communities := sixdegrees.Groups{Of: map[string]int{"a": 1, "b": 0, "c": 2}, Sizes: []int{1, 1, 1}}
+	rows := NewArtistGroups(components, communities)
+	want := []ArtistGroup{
+		{ArtistID: "a", Component: 0, ComponentSize: 2, Community: 1, CommunitySize: 1},
+		{ArtistID: "b", Component: 0, ComponentSize: 2, Community: 0, CommunitySize: 1},
+		{ArtistID: "c", Component: 1, ComponentSize: 1, Community: 2, CommunitySize: 1},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %+v", len(want), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Fatalf("row %d: expected %+v, got %+v", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestTopGenres(t *testing.T) {
+	got := topGenres(map[string]int{"rap": 3, "pop": 1, "hip hop": 3, "trap": 2}, 3)
+	if len(got) != 3 || got[0].Genre != "hip hop" || got[1].Genre != "rap" || got[2] != (GenreCount{Genre: "trap", Artists: 2}) {
+		t.Fatalf("unexpected top genres %+v", got)
+	}
+}
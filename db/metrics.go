@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+// metricsBatch is how many artist_metrics rows one INSERT writes.
+const metricsBatch = 500
+
+// ArtistMetrics is one row of the artist_metrics table: an artist's
+// centrality in the stored collaboration graph, and its distance from the
+// center chosen when the metrics were computed.
+type ArtistMetrics struct {
+	ArtistID       string
+	Degree         int
+	Strength       int
+	Closeness      float64
+	Betweenness    float64
+	CenterID       sql.NullString
+	CenterDistance sql.NullInt64 // hops to CenterID; NULL when unreachable
+	SampledSources int           // sources searched; 0 when exact
+}
+
+// NewArtistMetrics converts a centrality into a row, with the distances
+// from center (as returned by CollabGraph.Distances) when one is chosen.
+func NewArtistMetrics(c sixdegrees.Centrality, center string, dist map[string]int, samples int) ArtistMetrics {
+	m := ArtistMetrics{ArtistID: c.ID, Degree: c.Degree, Strength: c.Strength, Closeness: c.Closeness,
+		Betweenness: c.Betweenness, SampledSources: samples}
+	if center != "" {
+		m.CenterID = sql.NullString{String: center, Valid: true}
+		if d, ok := dist[c.ID]; ok {
+			m.CenterDistance = sql.NullInt64{Int64: int64(d), Valid: true}
+		}
+	}
+	return m
+}
+
+// metricColumns maps the orders TopArtistMetrics accepts to columns.
+var metricColumns = map[string]string{
+	"degree":      "degree",
+	"strength":    "strength",
+	"closeness":   "closeness",
+	"betweenness": "betweenness",
+}
+
+// MetricNames lists the orders TopArtistMetrics accepts.
+func MetricNames() []string {
+	names := make([]string, 0, len(metricColumns))
+	for n := range metricColumns {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadCollabGraph reads the whole collaborations table into memory.
+func (s *Store) LoadCollabGraph(ctx context.Context) (*sixdegrees.CollabGraph, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT artist_a, artist_b, shared_track_count FROM collaborations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	g := sixdegrees.NewCollabGraph()
+	for rows.Next() {
+		var a, b string
+		var shared int
+		if err := rows.Scan(&a, &b, &shared); err != nil {
+			return nil, err
+		}
+		g.AddEdge(a, b, shared)
+	}
+	return g, rows.Err()
+}
+
+// ReplaceArtistMetrics discards the artist_metrics table and writes rows in
+// its place, so artists no longer in the graph do not keep stale metrics.
+func (s *Store) ReplaceArtistMetrics(ctx context.Context, rows []ArtistMetrics) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM artist_metrics`); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for start := 0; start < len(rows); start += metricsBatch {
+		end := start + metricsBatch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+		args := make([]interface{}, 0, 8*len(batch))
+		for _, m := range batch {
+			args = append(args, m.ArtistID, m.Degree, m.Strength, m.Closeness, m.Betweenness,
+				m.CenterID, nullInt(m.CenterDistance), m.SampledSources)
+		}
+		q := `INSERT INTO artist_metrics (artist_id, degree, strength, closeness, betweenness, center_id, center_distance, sampled_sources)
+			VALUES ` + strings.TrimSuffix(strings.Repeat("(?,?,?,?,?,?,?,?),", len(batch)), ",")
+		if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("write metrics: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+const metricsSelect = `SELECT artist_id, degree, strength, closeness, betweenness, center_id, center_distance, sampled_sources
+	FROM artist_metrics`
+
+// GetArtistMetrics returns one artist's stored metrics.
+func (s *Store) GetArtistMetrics(ctx context.Context, artistID string) (ArtistMetrics, error) {
+	return scanArtistMetrics(s.DB.QueryRowContext(ctx, metricsSelect+` WHERE artist_id=?`, artistID))
+}
+
+// TopArtistMetrics returns the artists ranking highest by one of
+// MetricNames.
+func (s *Store) TopArtistMetrics(ctx context.Context, by string, limit int) ([]ArtistMetrics, error) {
+	col, ok := metricColumns[by]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q (want one of %s)", by, strings.Join(MetricNames(), ", "))
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 25
+	}
+	rows, err := s.DB.QueryContext(ctx, metricsSelect+` ORDER BY `+col+` DESC, artist_id LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ArtistMetrics
+	for rows.Next() {
+		m, err := scanArtistMetrics(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// CenterHeuristic is the heuristic name that LoadCenterHeuristic serves, for
+// commands that offer it alongside sixdegrees.HeuristicNames.
+const CenterHeuristic = "center"
+
+// LoadCenterHeuristic builds an A* heuristic from every artist's stored
+// distance to the center, as written by the centrality command.
+func (s *Store) LoadCenterHeuristic(ctx context.Context, scale float64) (sixdegrees.CenterDistanceHeuristic, error) {
+	h := sixdegrees.CenterDistanceHeuristic{Distances: make(map[string]int), Scale: scale}
+	rows, err := s.DB.QueryContext(ctx, `SELECT artist_id, center_distance FROM artist_metrics WHERE center_distance IS NOT NULL`)
+	if err != nil {
+		return h, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var d int
+		if err := rows.Scan(&id, &d); err != nil {
+			return h, err
+		}
+		h.Distances[id] = d
+	}
+	if err := rows.Err(); err != nil {
+		return h, err
+	}
+	if len(h.Distances) == 0 {
+		return h, errors.New("no stored center distances; run dbtool centrality first")
+	}
+	return h, nil
+}
+
+func scanArtistMetrics(r rowScanner) (ArtistMetrics, error) {
+	var m ArtistMetrics
+	err := r.Scan(&m.ArtistID, &m.Degree, &m.Strength, &m.Closeness, &m.Betweenness, &m.CenterID, &m.CenterDistance, &m.SampledSources)
+	return m, err
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+func TestNewArtistMetrics_CenterDistance(t *testing.T) {
+	c := sixdegrees.Centrality{ID: "a", Degree: 2, Strength: 5, Closeness: 0.5, Betweenness: 3}
+	m := NewArtistMetrics(c, "hub", map[string]int{"a": 2}, 100)
+	if m.ArtistID != "a" || m.Degree != 2 || m.Strength != 5 || m.SampledSources != 100 {
+		t.Fatalf("unexpected row %+v", m)
+	}
+	if m.CenterID.String != "hub" || !m.CenterDistance.Valid || m.CenterDistance.Int64 != 2 {
+		t.Fatalf("expected distance 2 from hub, got %+v", m)
+	}
+	if m := NewArtistMetrics(c, "hub", map[string]int{}, 0); !m.CenterID.Valid || m.CenterDistance.Valid {
+		t.Fatalf("an unreachable artist should have a NULL distance, got %+v", m)
+	}
+	if m := NewArtistMetrics(c, "", nil, 0); m.CenterID.Valid {
+		t.Fatalf("no center should leave center_id NULL, got %+v", m)
+	}
+}
+
+func TestTopArtistMetrics_RejectsUnknownOrder(t *testing.T) {
+	var s Store
+	if _, err := s.TopArtistMetrics(context.Background(), "name; DROP TABLE artists", 10); err == nil || !strings.Contains(err.Error(), "betweenness") {
+		t.Fatalf("expected an unknown metric error listing the choices, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -42,6 +43,11 @@ func Open(dsn string) (*Store, error) {
 	return &Store{DB: db}, nil
 }
 
+// Ping checks that the database is reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
 func (s *Store) Close() error {
 	if s == nil || s.DB == nil {
 		return nil
@@ -79,6 +85,7 @@ func (s *Store) Migrate(ctx context.Context) error {
 			name VARCHAR(255) NOT NULL,
 			album_id VARCHAR(64) NULL,
 			primary_artist_id VARCHAR(64) NULL,
+			release_year SMALLINT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			INDEX idx_tracks_name (name),
@@ -96,13 +103,68 @@ func (s *Store) Migrate(ctx context.Context) error {
 			FOREIGN KEY (track_id) REFERENCES tracks(id) ON DELETE CASCADE ON UPDATE CASCADE,
 			FOREIGN KEY (artist_id) REFERENCES artists(id) ON DELETE CASCADE ON UPDATE CASCADE
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+		`CREATE TABLE IF NOT EXISTS collaborations (
+			artist_a VARCHAR(64) NOT NULL,
+			artist_b VARCHAR(64) NOT NULL,
+			shared_track_count INT NOT NULL DEFAULT 0,
+			first_year SMALLINT NULL,
+			last_year SMALLINT NULL,
+			sample_track_ids TEXT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (artist_a, artist_b),
+			INDEX idx_collaborations_b (artist_b),
+			FOREIGN KEY (artist_a) REFERENCES artists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			FOREIGN KEY (artist_b) REFERENCES artists(id) ON DELETE CASCADE ON UPDATE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+		`CREATE TABLE IF NOT EXISTS artist_metrics (
+			artist_id VARCHAR(64) PRIMARY KEY,
+			degree INT NOT NULL DEFAULT 0,
+			strength INT NOT NULL DEFAULT 0,
+			closeness DOUBLE NOT NULL DEFAULT 0,
+			betweenness DOUBLE NOT NULL DEFAULT 0,
+			center_id VARCHAR(64) NULL,
+			center_distance INT NULL,
+			sampled_sources INT NOT NULL DEFAULT 0,
+			computed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_artist_metrics_closeness (closeness),
+			INDEX idx_artist_metrics_betweenness (betweenness),
+			FOREIGN KEY (artist_id) REFERENCES artists(id) ON DELETE CASCADE ON UPDATE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+		`CREATE TABLE IF NOT EXISTS artist_groups (
+			artist_id VARCHAR(64) PRIMARY KEY,
+			component INT NOT NULL,
+			component_size INT NOT NULL,
+			community INT NOT NULL,
+			community_size INT NOT NULL,
+			computed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_artist_groups_component (component),
+			INDEX idx_artist_groups_community (community),
+			FOREIGN KEY (artist_id) REFERENCES artists(id) ON DELETE CASCADE ON UPDATE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 	}
 	for _, q := range stmts {
 		if _, err := s.DB.ExecContext(ctx, q); err != nil {
 			return err
 		}
 	}
-	return nil
+	// Columns added after the first release; CREATE TABLE IF NOT EXISTS does not
+	// touch tables that already exist.
+	return s.ensureColumn(ctx, "tracks", "release_year", "SMALLINT NULL")
+}
+
+// ensureColumn adds a column to an existing table when it is missing.
+func (s *Store) ensureColumn(ctx context.Context, table, column, ddl string) error {
+	var n int
+	q := `SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`
+	if err := s.DB.QueryRowContext(ctx, q, table, column).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := s.DB.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, ddl))
+	return err
 }
 
 // =============================== Data Models =============================== //
@@ -125,6 +187,7 @@ type DBTrack struct {
 	Name            string
 	AlbumID         sql.NullString
 	PrimaryArtistID sql.NullString
+	ReleaseYear     sql.NullInt64
 }
 
 // =============================== Upserts ================================== //
@@ -161,10 +224,11 @@ func (s *Store) UpsertTrack(ctx context.Context, t DBTrack) error {
 	if t.ID == "" || t.Name == "" {
 		return errors.New("track id and name required")
 	}
-	q := `INSERT INTO tracks (id, name, album_id, primary_artist_id)
-		VALUES (?,?,?,?)
-		ON DUPLICATE KEY UPDATE name=VALUES(name), album_id=VALUES(album_id), primary_artist_id=VALUES(primary_artist_id)`
-	_, err := s.DB.ExecContext(ctx, q, t.ID, t.Name, t.AlbumID, t.PrimaryArtistID)
+	q := `INSERT INTO tracks (id, name, album_id, primary_artist_id, release_year)
+		VALUES (?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE name=VALUES(name), album_id=VALUES(album_id), primary_artist_id=VALUES(primary_artist_id),
+			release_year=COALESCE(VALUES(release_year), release_year)`
+	_, err := s.DB.ExecContext(ctx, q, t.ID, t.Name, t.AlbumID, t.PrimaryArtistID, nullInt(t.ReleaseYear))
 	return err
 }
 
@@ -191,15 +255,13 @@ func (s *Store) AddTrackArtist(ctx context.Context, trackID, artistID, role stri
 // - Upserts the primary artist with popularity and genres.
 // - Upserts each track and creates track_artists relations for primary and features.
 // - Upserts any discovered featured artists by their ID/Name if known (ID may be empty if not looked up yet).
+// - Refreshes the collaborations rows for every artist pair credited together on those tracks.
 func (s *Store) SaveArtistWithTracks(ctx context.Context, a *sixdegrees.Artists) error {
 	if a == nil || a.Name == "" {
 		return errors.New("artist required")
 	}
 	// If ID is missing we still allow storing by name (but recommend having Spotify ID)
-	artistID := a.ID
-	if artistID == "" {
-		artistID = strings.ToLower(strings.ReplaceAll(a.Name, " ", "_")) // fallback deterministic key
-	}
+	artistID := fallbackArtistID(a)
 	pop := sql.NullInt64{}
 	if a.Popularity > 0 {
 		pop = sql.NullInt64{Int64: int64(a.Popularity), Valid: true}
@@ -207,44 +269,89 @@ func (s *Store) SaveArtistWithTracks(ctx context.Context, a *sixdegrees.Artists)
 	if err := s.UpsertArtist(ctx, DBArtist{ID: artistID, Name: a.Name, Popularity: pop, Genres: a.Genres}); err != nil {
 		return fmt.Errorf("upsert artist: %w", err)
 	}
+	pairs := make(map[[2]string]bool)
 	for _, t := range a.Tracks {
 		trackID := t.ID
 		if trackID == "" {
 			// Some flows might not include IDs; derive a stable key on name + primary artist
 			trackID = strings.ToLower(fmt.Sprintf("%s::%s", a.Name, t.Name))
 		}
+		// Guest appearances credit the release's own artist first
+		primaryID, role := artistID, "primary"
+		if t.Guest && len(t.Featured) > 0 && t.Featured[0] != nil && t.Featured[0].Name != "" {
+			host := t.Featured[0]
+			primaryID, role = fallbackArtistID(host), "featured"
+			if err := s.UpsertArtist(ctx, DBArtist{ID: primaryID, Name: host.Name}); err != nil {
+				return fmt.Errorf("upsert primary artist: %w", err)
+			}
+		}
 		if err := s.UpsertTrack(ctx, DBTrack{
 			ID:   trackID,
 			Name: t.Name,
 			// album unknown here (nullable)
-			PrimaryArtistID: sql.NullString{String: artistID, Valid: artistID != ""},
+			PrimaryArtistID: sql.NullString{String: primaryID, Valid: primaryID != ""},
+			ReleaseYear:     sql.NullInt64{Int64: int64(t.Year), Valid: t.Year > 0},
 		}); err != nil {
 			return fmt.Errorf("upsert track: %w", err)
 		}
-		// primary relation
-		if err := s.AddTrackArtist(ctx, trackID, artistID, "primary"); err != nil {
+		// own relation: primary, or featured on a guest appearance
+		if err := s.AddTrackArtist(ctx, trackID, artistID, role); err != nil {
 			return fmt.Errorf("link primary artist: %w", err)
 		}
 		// featured relations
+		credited := []string{artistID}
 		for _, f := range t.Featured {
 			if f == nil || f.Name == "" {
 				continue
 			}
-			fid := f.ID
-			if fid == "" {
-				fid = strings.ToLower(strings.ReplaceAll(f.Name, " ", "_"))
+			fid := fallbackArtistID(f)
+			if fid == artistID {
+				continue
 			}
 			if err := s.UpsertArtist(ctx, DBArtist{ID: fid, Name: f.Name}); err != nil {
 				return fmt.Errorf("upsert featured artist: %w", err)
 			}
-			if err := s.AddTrackArtist(ctx, trackID, fid, "featured"); err != nil {
+			fRole := "featured"
+			if fid == primaryID {
+				fRole = "primary"
+			}
+			if err := s.AddTrackArtist(ctx, trackID, fid, fRole); err != nil {
 				return fmt.Errorf("link featured artist: %w", err)
 			}
+			credited = append(credited, fid)
+		}
+		for i := 0; i < len(credited); i++ {
+			for j := i + 1; j < len(credited); j++ {
+				x, y := collabPair(credited[i], credited[j])
+				pairs[[2]string{x, y}] = true
+			}
 		}
 	}
+	touched := make([][2]string, 0, len(pairs))
+	for p := range pairs {
+		touched = append(touched, p)
+	}
+	sort.Slice(touched, func(i, j int) bool {
+		if touched[i][0] != touched[j][0] {
+			return touched[i][0] < touched[j][0]
+		}
+		return touched[i][1] < touched[j][1]
+	})
+	if err := s.RefreshCollaborations(ctx, touched); err != nil {
+		return fmt.Errorf("refresh collaborations: %w", err)
+	}
 	return nil
 }
 
+// fallbackArtistID returns a's Spotify ID, or a deterministic key derived
+// from its name when the ID is unknown.
+func fallbackArtistID(a *sixdegrees.Artists) string {
+	if a.ID != "" {
+		return a.ID
+	}
+	return strings.ToLower(strings.ReplaceAll(a.Name, " ", "_"))
+}
+
 // ================================ Reads =================================== //
 
 func (s *Store) GetArtistByID(ctx context.Context, id string) (DBArtist, error) {
@@ -283,6 +390,28 @@ func (s *Store) FindArtistByName(ctx context.Context, name string) (DBArtist, er
 	return row, nil
 }
 
+// ListArtists returns every stored artist.
+func (s *Store) ListArtists(ctx context.Context) ([]DBArtist, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, name, popularity, genres FROM artists ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DBArtist
+	for rows.Next() {
+		var a DBArtist
+		var genres sql.NullString
+		if err := rows.Scan(&a.ID, &a.Name, &a.Popularity, &genres); err != nil {
+			return nil, err
+		}
+		if genres.Valid && genres.String != "" {
+			_ = json.Unmarshal([]byte(genres.String), &a.Genres)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
 func (s *Store) SearchArtistsByName(ctx context.Context, qstr string, limit int) ([]DBArtist, error) {
 	if limit <= 0 || limit > 1000 {
 		limit = 25
@@ -348,8 +477,8 @@ func (s *Store) GetTrackByID(ctx context.Context, id string) (DBTrack, error) {
 	if id == "" {
 		return row, errors.New("id required")
 	}
-	q := `SELECT id, name, album_id, primary_artist_id FROM tracks WHERE id=?`
-	if err := s.DB.QueryRowContext(ctx, q, id).Scan(&row.ID, &row.Name, &row.AlbumID, &row.PrimaryArtistID); err != nil {
+	q := `SELECT id, name, album_id, primary_artist_id, release_year FROM tracks WHERE id=?`
+	if err := s.DB.QueryRowContext(ctx, q, id).Scan(&row.ID, &row.Name, &row.AlbumID, &row.PrimaryArtistID, &row.ReleaseYear); err != nil {
 		return row, err
 	}
 	return row, nil
@@ -359,7 +488,7 @@ func (s *Store) SearchTracksByName(ctx context.Context, qstr string, limit int)
 	if limit <= 0 || limit > 1000 {
 		limit = 25
 	}
-	q := `SELECT id, name, album_id, primary_artist_id FROM tracks WHERE name LIKE ? ORDER BY name LIMIT ?`
+	q := `SELECT id, name, album_id, primary_artist_id, release_year FROM tracks WHERE name LIKE ? ORDER BY name LIMIT ?`
 	rows, err := s.DB.QueryContext(ctx, q, like(qstr), limit)
 	if err != nil {
 		return nil, err
@@ -368,7 +497,7 @@ func (s *Store) SearchTracksByName(ctx context.Context, qstr string, limit int)
 	var out []DBTrack
 	for rows.Next() {
 		var t DBTrack
-		if err := rows.Scan(&t.ID, &t.Name, &t.AlbumID, &t.PrimaryArtistID); err != nil {
+		if err := rows.Scan(&t.ID, &t.Name, &t.AlbumID, &t.PrimaryArtistID, &t.ReleaseYear); err != nil {
 			return nil, err
 		}
 		out = append(out, t)
@@ -383,7 +512,7 @@ func (s *Store) ListTracksByArtistID(ctx context.Context, artistID string, limit
 	if limit <= 0 || limit > 1000 {
 		limit = 50
 	}
-	q := `SELECT t.id, t.name, t.album_id, t.primary_artist_id
+	q := `SELECT t.id, t.name, t.album_id, t.primary_artist_id, t.release_year
 		FROM tracks t
 		JOIN track_artists ta ON ta.track_id = t.id
 		WHERE ta.artist_id = ?
@@ -396,7 +525,7 @@ func (s *Store) ListTracksByArtistID(ctx context.Context, artistID string, limit
 	var out []DBTrack
 	for rows.Next() {
 		var t DBTrack
-		if err := rows.Scan(&t.ID, &t.Name, &t.AlbumID, &t.PrimaryArtistID); err != nil {
+		if err := rows.Scan(&t.ID, &t.Name, &t.AlbumID, &t.PrimaryArtistID, &t.ReleaseYear); err != nil {
 			return nil, err
 		}
 		out = append(out, t)
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"sort"
 
 	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
 )
@@ -39,8 +40,12 @@ var auth *spotify.Auth
 
 // ===== Main =====
 
-func Gather() {
+// Gather samples artists from random genre recommendations and searches
+// between them in pairs. The same seed picks the same genres and audio
+// feature targets, so a run can be repeated.
+func Gather(seed int64) {
 	var name1, name2 string
+	rng := rand.New(rand.NewSource(seed))
 	// Ensure Spotify authorization before making any API calls
 	if err := ensureSpotifyAuth(); err != nil {
 		log.Fatalf("Spotify authorization failed: %v", err)
@@ -61,8 +66,8 @@ func Gather() {
 
 	for len(unique) < want && safety < 10 {
 		safety++
-		genre := genres[rand.Intn(len(genres))]
-		names, err := getArtistsFromRecommendations(auth.AccessToken, genre, 100)
+		genre := genres[rng.Intn(len(genres))]
+		names, err := getArtistsFromRecommendations(auth.AccessToken, genre, 100, rng)
 		if err != nil {
 			log.Printf("recommendations error for genre %q: %v", genre, err)
 			continue
@@ -78,13 +83,14 @@ func Gather() {
 	if len(unique) == 0 {
 		log.Fatal("Failed to retrieve any artists from recommendations.")
 	}
-	// Convert map to slice for indexed access
+	// Convert map to slice for indexed access, sorted so pairs repeat
 	artists := make([]string, 0, len(unique))
 	for name := range unique {
 		artists = append(artists, name)
-		if len(artists) >= want {
-			break
-		}
+	}
+	sort.Strings(artists)
+	if len(artists) > want {
+		artists = artists[:want]
 	}
 
 	// Print results
@@ -135,7 +141,7 @@ func getGenreSeeds(token string) ([]string, error) {
 	return gr.Genres, nil
 }
 
-func getArtistsFromRecommendations(token, genre string, limit int) ([]string, error) {
+func getArtistsFromRecommendations(token, genre string, limit int, rng *rand.Rand) ([]string, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
@@ -143,8 +149,8 @@ func getArtistsFromRecommendations(token, genre string, limit int) ([]string, er
 	params := url.Values{}
 	params.Set("limit", fmt.Sprint(limit))
 	params.Set("seed_genres", genre)
-	params.Set("min_popularity", fmt.Sprint(rand.Intn(50))) // 0..49
-	params.Set("target_energy", fmt.Sprintf("%.2f", rand.Float64()))
+	params.Set("min_popularity", fmt.Sprint(rng.Intn(50))) // 0..49
+	params.Set("target_energy", fmt.Sprintf("%.2f", rng.Float64()))
 	params.Set("market", "US") // helps ensure playable tracks
 
 	endpoint := recommendationsURL + "?" + params.Encode()
@@ -1,11 +1,8 @@
 module github.com/Jonnymurillo288/SixDegreesSpotify
 
-go 1.13
+go 1.21
 
 require (
-	github.com/Jonnymurillo288/GoUtils v0.0.0-20210919214837-823d36c368b5
-	github.com/Jonnymurillo288/go-runner v0.0.0-20210919215732-05da596653e3 // indirect
-	github.com/emirpasic/gods v1.18.1 // indirect
 	github.com/go-sql-driver/mysql v1.7.0
 	github.com/gorilla/mux v1.8.0
 	golang.org/x/oauth2 v0.7.0
@@ -1,133 +1,673 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Jonnymurillo288/SixDegreesSpotify/db"
 	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
 	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "recommend":
+			runRecommend(os.Args[2:])
+			return
+		case "explore":
+			runExplore(os.Args[2:])
+			return
+		}
+	}
 	startTime := time.Now().UTC().Unix()
 	var start, find string
 	var depth int
 	var verbose bool
-	var limit int
-	var startID string
+	var logLevel, logFormat string
+	var limit, trackLimit int
+	var includeGroups, market string
+	var dsn string
+	var snapshotPath string
+	var mode, strategyName, strategyFile string
+	var heuristicName string
+	var heuristicScale float64
+	var jsonOut bool
+	var avoidNames, viaNames listFlag
+	var allowGenres, denyGenres string
+	var minPopularity, maxPopularity float64
+	var fromYear, toYear int
+	var tracePath string
+	var playlist bool
+	var startCredit int
+	var budget sixdegrees.Budget
+	var recordDir, replayDir string
 	var switchingArtist bool
 	switchingArtist = false
 
-	flag.StringVar(&start, "start", "", "Starting artist name")
+	flag.StringVar(&start, "start", "", `Starting artist name, or "current" for the artist of the track playing on Spotify`)
+	flag.IntVar(&startCredit, "start-credit", 1, `With -start current, which credited artist of the playing track to start from (1 is the primary artist)`)
 	flag.StringVar(&find, "find", "", "Target artist name to find connection to")
 	flag.IntVar(&depth, "depth", -1, "Maximum BFS depth in hops (-1 for unlimited)")
-	flag.BoolVar(&verbose, "verbose", false, "Enable verbose logging")
-	flag.IntVar(&limit, "limit", 5, "Max Limit of albums to parse through")
+	flag.BoolVar(&verbose, "verbose", false, "Print search stats and log at debug level unless -log-level is given")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	flag.StringVar(&logFormat, "log-format", "text", "Log format: text or json")
+	flag.IntVar(&limit, "limit", spotify.DefaultAlbumLimit, "Albums to fetch per artist (-1 for all)")
+	flag.IntVar(&trackLimit, "track-limit", 0, "Tracks to fetch per album (0 for all)")
+	flag.StringVar(&includeGroups, "include-groups", "album,single", "Comma-separated album groups to fetch: "+strings.Join(spotify.AlbumGroups, ", "))
+	flag.StringVar(&market, "market", "US", "Market (ISO country code) albums and tracks are fetched for")
+	flag.StringVar(&dsn, "dsn", "", "MySQL DSN; when set, explored artists and their collaborations are saved, and weighted searches count stored collaborations")
+	flag.StringVar(&snapshotPath, "snapshot", "", "Graph snapshot file to load at startup and append explored artists to")
+	flag.StringVar(&mode, "mode", "bfs", "Search mode: bfs (fewest hops), weighted (cheapest path under -strategy), or astar (weighted, expanding lazily)")
+	flag.StringVar(&strategyName, "strategy", "collab", "Weight strategy for -mode weighted/astar: one of "+strings.Join(sixdegrees.StrategyNames(), ", ")+", or a mix such as 0.7*collab+0.3*recency")
+	flag.StringVar(&strategyFile, "strategy-file", "", `JSON file mapping strategy names to coefficients, e.g. {"collab": 0.7, "recency": 0.3}; overrides -strategy`)
+	flag.StringVar(&heuristicName, "heuristic", "genre", "A* heuristic for -mode astar: "+strings.Join(sixdegrees.HeuristicNames(), ", ")+", or "+db.CenterHeuristic+" (with -dsn)")
+	flag.Float64Var(&heuristicScale, "heuristic-scale", 0.1, "Largest A* heuristic estimate; keep it at or below the cheapest edge cost to guarantee the cheapest path")
+	flag.BoolVar(&jsonOut, "json", false, "Print the result as JSON")
+	flag.Var(&avoidNames, "avoid", "Artist the path must not pass through (repeatable)")
+	flag.Var(&viaNames, "via", "Artist the path must pass through, in order (repeatable)")
+	flag.StringVar(&allowGenres, "genres", "", "Comma-separated genres; every intermediate artist must match one (e.g. \"hip hop,rap\")")
+	flag.StringVar(&denyGenres, "exclude-genres", "", "Comma-separated genres no intermediate artist may match")
+	flag.Float64Var(&minPopularity, "min-popularity", 0, "Minimum popularity (0-100) of intermediate artists")
+	flag.Float64Var(&maxPopularity, "max-popularity", 0, "Maximum popularity (0-100) of intermediate artists; 0 for no limit")
+	flag.IntVar(&fromYear, "from-year", 0, "Only connect artists through tracks released in or after this year")
+	flag.IntVar(&toYear, "to-year", 0, "Only connect artists through tracks released in or before this year")
+	flag.StringVar(&tracePath, "trace", "", "File to write the search's expansion order to, one JSON line per artist")
+	flag.BoolVar(&playlist, "playlist", false, "Save the found path as a private playlist on your Spotify account, one connecting track per hop")
+	flag.IntVar(&budget.APICalls, "max-api-calls", 0, "Stop the search after this many Spotify requests (0 for no limit)")
+	flag.IntVar(&budget.Expanded, "max-expanded", 0, "Stop the search after fetching this many artists' tracks (0 for no limit)")
+	flag.DurationVar(&budget.Duration, "timeout", 0, "Stop the search after this long, e.g. 2m (0 for no limit)")
+	flag.StringVar(&recordDir, "record", "", "Directory to save every Spotify response to, for replaying later with -replay")
+	flag.StringVar(&replayDir, "replay", "", "Directory of responses saved with -record to answer Spotify requests from, offline")
 	flag.Parse()
 
 	if start == "" || find == "" {
 		fmt.Println("Missing required flags: -start and/or -find.")
-		fmt.Println(`Usage: go run main.go -start "Artist A" -find "Artist B" [-depth N] [-verbose]`)
+		fmt.Println(`Usage: go run main.go -start "Artist A" -find "Artist B" [-depth N] [-mode bfs|weighted|astar] [-strategy EXPR | -strategy-file FILE] [-heuristic NAME] [-avoid NAME] [-via NAME] [-genres LIST] [-json] [-playlist] [-verbose] [-log-level LEVEL] [-log-format text|json]`)
+		os.Exit(1)
+	}
+
+	if verbose && !flagSet("log-level") {
+		logLevel = "debug"
+	}
+	jobLog := setupLogging(logLevel, logFormat)
+	log := jobLog.With("component", "cli")
+
+	var strategy sixdegrees.WeightStrategy
+	var heuristic sixdegrees.Heuristic
+	switch mode {
+	case "bfs":
+	case "weighted", "astar":
+		var s sixdegrees.WeightStrategy
+		var err error
+		if strategyFile != "" {
+			s, err = sixdegrees.LoadStrategyFile(strategyFile)
+		} else {
+			s, err = sixdegrees.ParseStrategy(strategyName)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		strategy = s
+		if c, ok := s.(sixdegrees.CompositeStrategy); ok {
+			strategyName = c.String()
+		}
+		if mode == "astar" {
+			if strings.EqualFold(strings.TrimSpace(heuristicName), db.CenterHeuristic) {
+				heuristic, err = storedCenterHeuristic(dsn, heuristicScale)
+			} else {
+				heuristic, err = sixdegrees.HeuristicByName(heuristicName, heuristicScale)
+			}
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+	default:
+		fmt.Printf("Unknown -mode %q (want bfs, weighted or astar)\n", mode)
 		os.Exit(1)
 	}
 
+	groups, err := spotify.ParseIncludeGroups(includeGroups)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fetch := spotify.FetchOptions{IncludeGroups: groups, Market: market, AlbumLimit: limit, TrackLimit: trackLimit}
+
 	// Ensure Spotify authorization before making any API calls
-	if err := ensureSpotifyAuth(); err != nil {
-		log.Fatalf("Spotify authorization failed: %v", err)
+	if !useFixtures(log, recordDir, replayDir) {
+		if err := ensureSpotifyAuth(); err != nil {
+			fatal(log, "Spotify authorization failed", "err", err)
+		}
+	}
+
+	h := sixdegrees.NewHelper()
+
+	// Start warm from a previously explored graph
+	var snap *sixdegrees.Snapshot
+	if snapshotPath != "" {
+		loadStart := time.Now()
+		s, err := sixdegrees.OpenSnapshot(snapshotPath, h)
+		if err != nil {
+			fatal(log, "failed to load snapshot", "path", snapshotPath, "err", err)
+		}
+		snap = s
+		log.Debug("loaded snapshot", "path", snapshotPath, "artists", snap.Len(), "took", time.Since(loadStart).Round(time.Millisecond))
 	}
 
 	// Look up start and target artists
-	startArtist := sixdegrees.InputArtist(start)
+	var startArtist *sixdegrees.Artists
+	if strings.EqualFold(start, "current") {
+		startArtist, err = nowPlayingArtist(log, startCredit)
+		if err != nil {
+			fatal(log, "cannot start from the playing track", "err", err)
+		}
+	} else {
+		startArtist = sixdegrees.InputArtist(start)
+	}
 	if startArtist == nil || startArtist.ID == "" {
-		log.Fatalf("Start artist %q not found on Spotify.", start)
+		fatal(log, "start artist not found on Spotify", "artist", start)
 	}
 
 	targetArtist := sixdegrees.InputArtist(find)
 	if targetArtist == nil || targetArtist.ID == "" {
-		log.Fatalf("Target artist %q not found on Spotify.", find)
+		fatal(log, "target artist not found on Spotify", "artist", find)
+	}
+
+	// Reuse artists the snapshot already expanded
+	startArtist, targetArtist = warmArtist(h, startArtist), warmArtist(h, targetArtist)
+	result := searchResult{Start: startArtist.Name, Target: targetArtist.Name, Mode: mode, Depth: depth}
+	if strategy != nil {
+		result.Strategy = strategyName
+	}
+	if heuristic != nil {
+		result.Heuristic = heuristicName
+	}
+
+	constraints, err := buildConstraints(h, avoidNames, viaNames, allowGenres, denyGenres, minPopularity, maxPopularity, fromYear, toYear)
+	if err != nil {
+		fatal(log, "invalid constraints", "err", err)
 	}
 
 	// Ensure startArtist is the *less popular* one
 	if startArtist.Popularity > targetArtist.Popularity {
 		switchingArtist = true
 		startArtist, targetArtist = targetArtist, startArtist
+		if constraints != nil {
+			reverseArtists(constraints.Waypoints)
+		}
 	}
 
-	// Retrieve albums for the starting artist
-	albums, err := spotify.ArtistAlbums(startID, 15)
-	if err != nil {
-		log.Fatalf("Error fetching albums for %s: %v", startArtist.Name, err)
+	// Run the connection search; it fetches the start artist's tracks itself
+	// so that its stats cover every request
+	opts := sixdegrees.SearchOptions{
+		MaxDepth:    depth,
+		Logger:      jobLog,
+		Fetch:       fetch,
+		Known:       h,
+		Constraints: constraints,
+		Budget:      budget,
+	}
+	if dsn != "" && mode == "weighted" {
+		store, err := db.Open(dsn)
+		if err != nil {
+			log.Warn("failed to open database for stored collaborations", "err", err)
+		} else {
+			defer store.Close()
+			opts.Collabs = db.SearchCollabs{Store: store, Timeout: collabsTimeout}
+		}
+	}
+	if tracePath != "" {
+		f, err := os.Create(tracePath)
+		if err != nil {
+			fatal(log, "failed to create trace file", "path", tracePath, "err", err)
+		}
+		defer f.Close()
+		opts.Trace = f
+	}
+	searchMode := strings.Join(strings.Fields(mode+" "+result.Strategy+" "+result.Heuristic), " ")
+	if heuristic != nil {
+		searchMode += " " + strconv.FormatFloat(heuristicScale, 'g', -1, 64)
 	}
+	fp := sixdegrees.NewFingerprint(startArtist, targetArtist, searchMode, opts, snap.Version())
+	result.Fingerprint = &fp
 
-	h := sixdegrees.NewHelper()
+	var helper *sixdegrees.Helper
+	var steps []sixdegrees.PathStep
+	var ok bool
+	log.Info("searching", "start", startArtist.Name, "target", targetArtist.Name, "mode", mode, "fingerprint", fp.Hash)
+	switch {
+	case heuristic != nil:
+		helper, steps, ok = sixdegrees.RunAStarSearch(startArtist, targetArtist, strategy, heuristic, opts)
+	case strategy != nil:
+		helper, steps, ok = sixdegrees.RunWeightedSearch(startArtist, targetArtist, strategy, opts)
+	default:
+		var path []string
+		helper, path, ok = sixdegrees.RunSearchOpts(startArtist, targetArtist, opts)
+		steps = helper.Steps(path)
+	}
+	if snap != nil {
+		h.ArtistMap[targetArtist.Name] = targetArtist
+		if n, err := snap.Append(h); err != nil {
+			log.Warn("failed to update snapshot", "path", snapshotPath, "err", err)
+		} else {
+			log.Debug("appended to snapshot", "path", snapshotPath, "artists", n)
+		}
+	}
+	if dsn != "" {
+		if err := saveExplored(dsn, helper, startArtist, targetArtist); err != nil {
+			log.Warn("failed to save explored artists", "err", err)
+		}
+	}
 
-	// The first layer of the queue will be the startArtist features
-	for _, album := range startArtist.ParseAlbums(albums) {
-		tracks, err := spotify.GetAlbumTracks(album)
-		for _, track := range tracks {
-			db.UpsertTrack(track)
+	if switchingArtist {
+		sixdegrees.ReverseSteps(steps)
+	}
+	result.Found = ok && len(steps) > 0
+	result.Hops = len(steps)
+	result.Steps = steps
+	result.TotalCost = sixdegrees.TotalCost(steps)
+	result.Seconds = time.Now().UTC().Unix() - startTime
+	result.Stats = helper.Stats
+	result.Diagnosis = helper.Diagnosis
+	if !result.Found && dsn != "" {
+		a, b := startArtist, targetArtist
+		if switchingArtist {
+			a, b = b, a
 		}
+		if note, err := storedDisconnect(dsn, a, b); err != nil {
+			log.Warn("failed to check stored components", "err", err)
+		} else {
+			result.StoredGraph = note
+		}
+	}
+	if playlist && result.Found {
+		pl, err := sixdegrees.CreatePathPlaylist(steps, false)
 		if err != nil {
-			log.Printf("Warning: failed to fetch tracks for album %s: %v", album, err)
-			continue
+			log.Warn("failed to create playlist", "err", err)
+		} else {
+			result.Playlist = &pl
 		}
-		t, _ := startArtist.CreateTracks(tracks, h)
-		startArtist.Tracks = append(startArtist.Tracks, t...)
 	}
 
-	// The second layer of the queue will be the targetArtist features
-	for _, album := range targetArtist.ParseAlbums(albums) {
-		tracks, err := spotify.GetAlbumTracks(album)
-		if err != nil {
-			log.Printf("Warning: failed to fetch tracks for album %s: %v", album, err)
-			continue
+	emitResult(log, result, jsonOut, verbose)
+}
+
+// emitResult prints a search result as JSON or text, with the search stats
+// when verbose.
+func emitResult(log *slog.Logger, r searchResult, jsonOut, verbose bool) {
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			fatal(log, "failed to encode result", "err", err)
 		}
-		t, _ := targetArtist.CreateTracks(tracks, h)
-		targetArtist.Tracks = append(targetArtist.Tracks, t...)
+		return
+	}
+	printResult(r)
+	if verbose {
+		printStats(r.Stats)
 	}
+}
 
-	// Run the connection search
-	helper, path, ok := sixdegrees.RunSearchOpts(startArtist, targetArtist, depth, verbose, &limit)
-	if !ok || len(path) == 0 {
-		if depth >= 0 {
-			fmt.Printf("No path found between %q and %q within depth %d\n", startArtist.Name, targetArtist.Name, depth)
-		} else {
-			fmt.Printf("No path found between %q and %q\n", startArtist.Name, targetArtist.Name)
+// collabsTimeout bounds loading stored collaborations for a weighted search,
+// or stored center distances for an A* one.
+const collabsTimeout = 30 * time.Second
+
+// storedCenterHeuristic loads the A* heuristic for -heuristic center from the
+// distances `dbtool centrality` stored.
+func storedCenterHeuristic(dsn string, scale float64) (sixdegrees.Heuristic, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("-heuristic %s needs -dsn", db.CenterHeuristic)
+	}
+	store, err := db.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), collabsTimeout)
+	defer cancel()
+	return store.LoadCenterHeuristic(ctx, scale)
+}
+
+// storedDisconnect notes that the stored graph does not connect a and b
+// either, or returns "" when it may. Components come from `dbtool components`.
+func storedDisconnect(dsn string, a, b *sixdegrees.Artists) (string, error) {
+	store, err := db.Open(dsn)
+	if err != nil {
+		return "", err
+	}
+	defer store.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return store.DisconnectedNote(ctx, a, b)
+}
+
+// setupLogging makes the logger every package logs to and returns it tagged
+// with a fresh job ID, exiting on a bad level or format.
+func setupLogging(level, format string) *slog.Logger {
+	logger, err := sixdegrees.NewLogger(os.Stderr, level, format)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	spotify.SetLogger(logger)
+	sixdegrees.SetLogger(logger)
+	return logger.With("job_id", sixdegrees.NewLogID())
+}
+
+// nowPlayingArtist picks the n-th credited artist (from 1) of the track
+// playing on Spotify.
+func nowPlayingArtist(log *slog.Logger, n int) (*sixdegrees.Artists, error) {
+	track, credits, err := sixdegrees.NowPlaying()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(credits))
+	for i, a := range credits {
+		names[i] = fmt.Sprintf("%d. %s", i+1, a.Name)
+	}
+	if n < 1 || n > len(credits) {
+		return nil, fmt.Errorf("-start-credit %d: %q credits %s", n, track, strings.Join(names, ", "))
+	}
+	log.Info("starting from the playing track", "track", track, "artist", credits[n-1].Name, "credits", strings.Join(names, ", "))
+	return credits[n-1], nil
+}
+
+// fatal logs msg at error level and exits.
+func fatal(log *slog.Logger, msg string, args ...any) {
+	log.Error(msg, args...)
+	os.Exit(1)
+}
+
+// useFixtures sends Spotify requests through the responses in the -record or
+// -replay directory, if either is set. It reports whether requests are
+// replayed, which needs no authorization.
+func useFixtures(log *slog.Logger, record, replay string) bool {
+	switch {
+	case record != "" && replay != "":
+		fatal(log, "-record and -replay cannot be used together")
+	case record != "":
+		if _, err := spotify.UseFixtures(record, spotify.Record); err != nil {
+			fatal(log, "cannot record Spotify responses", "dir", record, "err", err)
+		}
+		log.Info("recording Spotify responses", "dir", record)
+	case replay != "":
+		if _, err := spotify.UseFixtures(replay, spotify.Replay); err != nil {
+			fatal(log, "cannot replay Spotify responses", "dir", replay, "err", err)
 		}
-		os.Exit(0)
+		log.Info("replaying Spotify responses", "dir", replay)
+		return true
 	}
+	return false
+}
 
-	// Display the found path
-	fmt.Printf("Path found between %q and %q (%d hops):\n\n", startArtist.Name, targetArtist.Name, len(path)-1)
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
 
-	if switchingArtist {
-		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
-			path[i], path[j] = path[j], path[i]
+// searchResult is the outcome of one CLI search, printed as text or JSON.
+type searchResult struct {
+	Start     string                `json:"start"`
+	Target    string                `json:"target"`
+	Mode      string                `json:"mode"`
+	Strategy  string                `json:"strategy,omitempty"`
+	Heuristic string                `json:"heuristic,omitempty"`
+	Depth     int                   `json:"depth"`
+	Found     bool                  `json:"found"`
+	Hops      int                   `json:"hops"`
+	TotalCost float64               `json:"total_cost"`
+	Steps     []sixdegrees.PathStep `json:"steps"`
+	Seconds   int64                 `json:"elapsed_seconds"`
+
+	Diagnosis   *sixdegrees.Diagnosis `json:"diagnosis,omitempty"`    // why the search found nothing
+	StoredGraph string                `json:"stored_graph,omitempty"` // set when the stored graph does not connect them either
+
+	// Fingerprint records the search's parameters and starting graph, to
+	// reproduce it or diff its result across versions.
+	Fingerprint *sixdegrees.Fingerprint `json:"fingerprint,omitempty"`
+	Playlist    *spotify.Playlist       `json:"playlist,omitempty"` // set by -playlist
+	Stats       *sixdegrees.SearchStats `json:"stats,omitempty"`
+}
+
+func printResult(r searchResult) {
+	if !r.Found {
+		if r.Depth >= 0 {
+			fmt.Printf("No path found between %q and %q within depth %d\n", r.Start, r.Target, r.Depth)
+		} else {
+			fmt.Printf("No path found between %q and %q\n", r.Start, r.Target)
+		}
+		if r.Diagnosis != nil {
+			printDiagnosis(r.Diagnosis)
+		}
+		if r.StoredGraph != "" {
+			fmt.Printf("%s.\n", r.StoredGraph)
 		}
+		printFingerprint(r.Fingerprint)
+		return
 	}
 
-	for i := 1; i < len(path); i++ {
-		from := path[i-1]
-		to := path[i]
-		track := helper.Evidence[from]
+	// Display the found path
+	fmt.Printf("Path found between %q and %q (%d hops):\n\n", r.Start, r.Target, r.Hops)
+	for i, st := range r.Steps {
+		cost := ""
+		if r.Strategy != "" {
+			cost = fmt.Sprintf("  (cost %.3f)", st.Cost)
+		}
+		track := st.Track
+		var notes []string
+		if st.Year > 0 {
+			notes = append(notes, strconv.Itoa(st.Year))
+		}
+		if st.Guest {
+			notes = append(notes, "guest appearance")
+		}
+		if track != "" && len(notes) > 0 {
+			track = fmt.Sprintf("%s (%s)", track, strings.Join(notes, ", "))
+		}
 		if track != "" {
-			fmt.Printf("%d. %s —[%s]→ %s\n", i, from, track, to)
+			fmt.Printf("%d. %s —[%s]→ %s%s\n", i+1, st.From, track, st.To, cost)
 		} else {
-			fmt.Printf("%d. %s → %s\n", i, from, to)
+			fmt.Printf("%d. %s → %s%s\n", i+1, st.From, st.To, cost)
 		}
 	}
-	endTime := time.Now().UTC().Unix()
-	fmt.Printf("Analysis took %s seconds", strconv.FormatInt(endTime-startTime, 10))
+	if r.Strategy != "" {
+		fmt.Printf("\nTotal cost (%s strategy): %.3f\n", r.Strategy, r.TotalCost)
+	}
+	if r.Playlist != nil {
+		fmt.Printf("\nPlaylist: %s\n", r.Playlist.URL)
+	}
+	printFingerprint(r.Fingerprint)
+	fmt.Printf("Analysis took %s seconds", strconv.FormatInt(r.Seconds, 10))
 	fmt.Println("\nDone.")
 }
 
+// printFingerprint shows the hash to compare reruns of the same search by;
+// -json output has its parameters.
+func printFingerprint(fp *sixdegrees.Fingerprint) {
+	if fp == nil {
+		return
+	}
+	snapshot := fp.Snapshot
+	if snapshot == "" {
+		snapshot = "none"
+	}
+	fmt.Printf("Search fingerprint: %s (snapshot %s, %d artists known)\n", fp.Hash, snapshot, fp.Known)
+}
+
+// printDiagnosis explains a failed search: why it stopped, how far it got
+// and which of the artists it reached look most like the target.
+func printDiagnosis(d *sixdegrees.Diagnosis) {
+	fmt.Printf("The search from %q to %q %s.\n", d.From, d.To, d.Summary())
+	counts := make([]string, len(d.ByDepth))
+	for hops, n := range d.ByDepth {
+		counts[hops] = fmt.Sprintf("%d: %d", hops, n)
+	}
+	fmt.Printf("Artists reached by depth: %s\n", strings.Join(counts, ", "))
+	if len(d.Closest) > 0 {
+		fmt.Printf("\nClosest artists to %q:\n", d.To)
+		for i, m := range d.Closest {
+			route := []string{d.From}
+			for _, st := range m.Path {
+				route = append(route, st.To)
+			}
+			fmt.Printf("%d. %s (%d hops, score %.2f: genre %.2f, popularity %.2f) via %s\n",
+				i+1, m.Name, m.Hops, m.Score, m.Genre, m.PopularityFit, strings.Join(route, " → "))
+		}
+	}
+	fmt.Printf("\nTo find a path, %s.\n", d.Advice())
+}
+
+// printStats shows what the search did, to explain slow searches.
+func printStats(s *sixdegrees.SearchStats) {
+	if s == nil {
+		return
+	}
+	fmt.Printf("\nSearch stats:\n")
+	fmt.Printf("  artists dequeued %d, expanded %d\n", s.Dequeued, s.Expanded)
+	fmt.Printf("  albums fetched %d (cache hits %d, misses %d), tracks %d\n", s.AlbumsFetched, s.CacheHits, s.CacheMisses, s.TracksFetched)
+	fmt.Printf("  API calls %d", s.TotalAPICalls())
+	endpoints := make([]string, 0, len(s.APICalls))
+	for e := range s.APICalls {
+		endpoints = append(endpoints, e)
+	}
+	sort.Strings(endpoints)
+	for _, e := range endpoints {
+		fmt.Printf(", %s %d", e, s.APICalls[e])
+	}
+	fmt.Printf("\n  throttle waits %d (%.0f ms)\n", s.ThrottleWaits, s.ThrottleMS)
+	phases := make([]string, 0, len(s.PhaseMS))
+	for p := range s.PhaseMS {
+		phases = append(phases, p)
+	}
+	sort.Strings(phases)
+	for _, p := range phases {
+		fmt.Printf("  %s: %.0f ms\n", p, s.PhaseMS[p])
+	}
+}
+
+// listFlag collects a flag that may be given several times.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ", ") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// buildConstraints looks up the named artists and returns the path
+// constraints, or nil when none were requested.
+func buildConstraints(h *sixdegrees.Helper, avoid, via []string, allow, deny string, minPop, maxPop float64, fromYear, toYear int) (*sixdegrees.Constraints, error) {
+	c := &sixdegrees.Constraints{
+		AllowGenres:   splitList(allow),
+		DenyGenres:    splitList(deny),
+		MinPopularity: minPop,
+		MaxPopularity: maxPop,
+		FromYear:      fromYear,
+		ToYear:        toYear,
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	for _, name := range avoid {
+		a := sixdegrees.InputArtist(name)
+		if a == nil || a.ID == "" {
+			return nil, fmt.Errorf("artist to avoid %q not found on Spotify", name)
+		}
+		c.ExcludeIDs = append(c.ExcludeIDs, a.ID)
+	}
+	for _, name := range via {
+		a := sixdegrees.InputArtist(name)
+		if a == nil || a.ID == "" {
+			return nil, fmt.Errorf("waypoint %q not found on Spotify", name)
+		}
+		c.Waypoints = append(c.Waypoints, warmArtist(h, a))
+	}
+	if len(c.ExcludeIDs) == 0 && len(c.Waypoints) == 0 && len(c.AllowGenres) == 0 &&
+		len(c.DenyGenres) == 0 && minPop == 0 && maxPop == 0 && fromYear == 0 && toYear == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+func reverseArtists(a []*sixdegrees.Artists) {
+	for i, j := 0, len(a)-1; i < j; i, j = i+1, j-1 {
+		a[i], a[j] = a[j], a[i]
+	}
+}
+
+// warmArtist returns the already-expanded copy of a from h when there is one,
+// refreshed with the metadata just looked up; otherwise it returns a.
+func warmArtist(h *sixdegrees.Helper, a *sixdegrees.Artists) *sixdegrees.Artists {
+	known, ok := h.ArtistMap[a.Name]
+	if !ok || len(known.Tracks) == 0 {
+		return a
+	}
+	known.ID, known.Popularity, known.Genres = a.ID, a.Popularity, a.Genres
+	return known
+}
+
+// saveExplored persists every expanded artist (with tracks) so the collaborations
+// table grows with each search.
+func saveExplored(dsn string, h *sixdegrees.Helper, extra ...*sixdegrees.Artists) error {
+	store, err := db.Open(dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := store.Migrate(ctx); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	artists := make(map[string]*sixdegrees.Artists, len(h.ArtistMap)+len(extra))
+	for name, a := range h.ArtistMap {
+		artists[name] = a
+	}
+	for _, a := range extra {
+		artists[a.Name] = a
+	}
+	for _, a := range artists {
+		if len(a.Tracks) == 0 {
+			continue
+		}
+		if err := store.SaveArtistWithTracks(ctx, a); err != nil {
+			return fmt.Errorf("save %s: %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
 // ensureSpotifyAuth verifies valid token exists or triggers auth flow.
 func ensureSpotifyAuth() error {
 	// Ensure auth configuration exists (bootstrap from sample if needed)
@@ -221,3 +761,313 @@ func tokenValid(path string) (*spotify.Auth, bool) {
 	}
 	return &t, true
 }
+
+// seedCommand holds the flags shared by the subcommands that explore the
+// graph around one seed artist rather than search between two.
+type seedCommand struct {
+	fs *flag.FlagSet
+
+	seed                                *string
+	seedCredit                          *int
+	verbose, jsonOut                    *bool
+	logLevel, logFormat                 *string
+	limit, trackLimit                   *int
+	includeGroups, market, snapshotPath *string
+	recordDir, replayDir                *string
+	avoidNames                          listFlag
+	allowGenres, denyGenres             *string
+	minPopularity, maxPopularity        *float64
+	fromYear, toYear                    *int
+
+	log, jobLog *slog.Logger
+	known       *sixdegrees.Helper
+	snap        *sixdegrees.Snapshot
+}
+
+// newSeedCommand registers the shared flags on a flag set for the named
+// subcommand; the caller adds its own before parsing.
+func newSeedCommand(name string) *seedCommand {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	c := &seedCommand{fs: fs}
+	c.seed = fs.String("seed", "", `Artist to start from, or "current" for the artist of the track playing on Spotify`)
+	c.seedCredit = fs.Int("seed-credit", 1, `With -seed current, which credited artist of the playing track to use (1 is the primary artist)`)
+	c.verbose = fs.Bool("verbose", false, "Print search stats and log at debug level unless -log-level is given")
+	c.jsonOut = fs.Bool("json", false, "Print the result as JSON")
+	c.logLevel = fs.String("log-level", "info", "Log level: debug, info, warn or error")
+	c.logFormat = fs.String("log-format", "text", "Log format: text or json")
+	c.limit = fs.Int("limit", spotify.DefaultAlbumLimit, "Albums to fetch per artist (-1 for all)")
+	c.trackLimit = fs.Int("track-limit", 0, "Tracks to fetch per album (0 for all)")
+	c.includeGroups = fs.String("include-groups", "album,single", "Comma-separated album groups to fetch: "+strings.Join(spotify.AlbumGroups, ", "))
+	c.market = fs.String("market", "US", "Market (ISO country code) albums and tracks are fetched for")
+	c.snapshotPath = fs.String("snapshot", "", "Graph snapshot file to load at startup and append explored artists to")
+	c.recordDir = fs.String("record", "", "Directory to save every Spotify response to, for replaying later with -replay")
+	c.replayDir = fs.String("replay", "", "Directory of responses saved with -record to answer Spotify requests from, offline")
+	fs.Var(&c.avoidNames, "avoid", "Artist to leave out of the explored graph (repeatable)")
+	c.allowGenres = fs.String("genres", "", "Comma-separated genres; every explored artist must match one")
+	c.denyGenres = fs.String("exclude-genres", "", "Comma-separated genres no explored artist may match")
+	c.minPopularity = fs.Float64("min-popularity", 0, "Minimum popularity (0-100) of explored artists")
+	c.maxPopularity = fs.Float64("max-popularity", 0, "Maximum popularity (0-100) of explored artists; 0 for no limit")
+	c.fromYear = fs.Int("from-year", 0, "Only follow collaborations on tracks released in or after this year")
+	c.toYear = fs.Int("to-year", 0, "Only follow collaborations on tracks released in or before this year")
+	return c
+}
+
+// setup parses args, printing usage and exiting without -seed, then sets up
+// logging, Spotify auth and the snapshot, and looks up the seed artist.
+func (c *seedCommand) setup(args []string, usage string) (*sixdegrees.Artists, sixdegrees.SearchOptions) {
+	c.fs.Parse(args)
+	if *c.seed == "" {
+		fmt.Println("Missing required flag: -seed.")
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	levelSet := false
+	c.fs.Visit(func(f *flag.Flag) { levelSet = levelSet || f.Name == "log-level" })
+	if *c.verbose && !levelSet {
+		*c.logLevel = "debug"
+	}
+	c.jobLog = setupLogging(*c.logLevel, *c.logFormat)
+	c.log = c.jobLog.With("component", "cli")
+	log := c.log
+
+	groups, err := spotify.ParseIncludeGroups(*c.includeGroups)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !useFixtures(log, *c.recordDir, *c.replayDir) {
+		if err := ensureSpotifyAuth(); err != nil {
+			fatal(log, "Spotify authorization failed", "err", err)
+		}
+	}
+
+	c.known = sixdegrees.NewHelper()
+	if *c.snapshotPath != "" {
+		if c.snap, err = sixdegrees.OpenSnapshot(*c.snapshotPath, c.known); err != nil {
+			fatal(log, "failed to load snapshot", "path", *c.snapshotPath, "err", err)
+		}
+	}
+
+	var seed *sixdegrees.Artists
+	if strings.EqualFold(*c.seed, "current") {
+		if seed, err = nowPlayingArtist(log, *c.seedCredit); err != nil {
+			fatal(log, "cannot start from the playing track", "err", err)
+		}
+	} else {
+		seed = sixdegrees.InputArtist(*c.seed)
+	}
+	if seed == nil || seed.ID == "" {
+		fatal(log, "seed artist not found on Spotify", "artist", *c.seed)
+	}
+	seed = warmArtist(c.known, seed)
+
+	constraints, err := buildConstraints(c.known, c.avoidNames, nil, *c.allowGenres, *c.denyGenres, *c.minPopularity, *c.maxPopularity, *c.fromYear, *c.toYear)
+	if err != nil {
+		fatal(log, "invalid constraints", "err", err)
+	}
+	return seed, sixdegrees.SearchOptions{
+		Logger:      c.jobLog,
+		Fetch:       spotify.FetchOptions{IncludeGroups: groups, Market: *c.market, AlbumLimit: *c.limit, TrackLimit: *c.trackLimit},
+		Known:       c.known,
+		Constraints: constraints,
+	}
+}
+
+// finish appends what was explored to the snapshot, then prints result as
+// JSON or with print, followed by stats under -verbose.
+func (c *seedCommand) finish(result interface{}, stats *sixdegrees.SearchStats, print func()) {
+	if c.snap != nil {
+		if added, err := c.snap.Append(c.known); err != nil {
+			c.log.Warn("failed to update snapshot", "path", *c.snapshotPath, "err", err)
+		} else {
+			c.log.Debug("appended to snapshot", "path", *c.snapshotPath, "artists", added)
+		}
+	}
+	if *c.jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fatal(c.log, "failed to encode result", "err", err)
+		}
+		return
+	}
+	print()
+	if *c.verbose {
+		printStats(stats)
+	}
+}
+
+// recommendResult is the outcome of the recommend subcommand, printed as
+// text or JSON.
+type recommendResult struct {
+	Seed            string                      `json:"seed"`
+	MinHops         int                         `json:"min_hops"`
+	MaxHops         int                         `json:"max_hops"`
+	Reached         int                         `json:"artists_reached"`
+	Recommendations []sixdegrees.Recommendation `json:"recommendations"`
+	Seconds         int64                       `json:"elapsed_seconds"`
+
+	Stats *sixdegrees.SearchStats `json:"stats,omitempty"`
+}
+
+// runRecommend implements `main.go recommend`: artists a few hops from a
+// seed, ranked by how well they fit it.
+func runRecommend(args []string) {
+	startTime := time.Now().UTC().Unix()
+	c := newSeedCommand("recommend")
+	minHops := c.fs.Int("min-hops", 2, "Fewest hops from the seed a recommended artist may be")
+	maxHops := c.fs.Int("max-hops", 2, "Most hops from the seed a recommended artist may be; every artist closer than this is fetched")
+	count := c.fs.Int("count", sixdegrees.DefaultRecommendations, "Recommendations to show (-1 for all)")
+	seed, opts := c.setup(args, `Usage: go run main.go recommend -seed "Artist" [-min-hops N] [-max-hops N] [-count N] [-genres LIST] [-min-popularity N] [-max-popularity N] [-json] [-verbose]`)
+
+	c.log.Info("recommending", "seed", seed.Name, "min_hops", *minHops, "max_hops", *maxHops)
+	n, recs, err := sixdegrees.Recommend(seed, *minHops, *maxHops, *count, opts)
+	if err != nil {
+		fatal(c.log, "cannot recommend", "err", err)
+	}
+	result := recommendResult{
+		Seed:            seed.Name,
+		MinHops:         *minHops,
+		MaxHops:         *maxHops,
+		Reached:         len(n.Helper.DistTo) - 1,
+		Recommendations: recs,
+		Seconds:         time.Now().UTC().Unix() - startTime,
+		Stats:           n.Helper.Stats,
+	}
+	c.finish(result, result.Stats, func() { printRecommendations(result) })
+}
+
+func printRecommendations(r recommendResult) {
+	hops := fmt.Sprintf("%d", r.MinHops)
+	if r.MaxHops != r.MinHops {
+		hops = fmt.Sprintf("%d-%d", r.MinHops, r.MaxHops)
+	}
+	if len(r.Recommendations) == 0 {
+		fmt.Printf("No artists found %s hops from %q (%d reached)\n", hops, r.Seed, r.Reached)
+		return
+	}
+	fmt.Printf("Artists %s hops from %q (%d reached):\n\n", hops, r.Seed, r.Reached)
+	for i, rec := range r.Recommendations {
+		fmt.Printf("%d. %s  (score %.2f: collab %.2f over %d tracks, genre %.2f, popularity %.2f)\n",
+			i+1, rec.Name, rec.Score, rec.Collab, rec.SharedTracks, rec.Genre, rec.PopularityFit)
+		names := []string{r.Seed}
+		for _, st := range rec.Path {
+			if st.Track != "" {
+				names = append(names, fmt.Sprintf("—[%s]→ %s", st.Track, st.To))
+			} else {
+				names = append(names, "→ "+st.To)
+			}
+		}
+		fmt.Printf("   %s\n", strings.Join(names, " "))
+	}
+	fmt.Printf("\nAnalysis took %d seconds\n", r.Seconds)
+}
+
+// exploreResult is the outcome of the explore subcommand, printed as text
+// or JSON.
+type exploreResult struct {
+	sixdegrees.Exploration
+	Export  string `json:"export,omitempty"` // file the ego network was written to
+	Seconds int64  `json:"elapsed_seconds"`
+
+	Stats *sixdegrees.SearchStats `json:"stats,omitempty"`
+}
+
+// runExplore implements `main.go explore`: the collaboration graph within a
+// few hops of a seed, with no target.
+func runExplore(args []string) {
+	startTime := time.Now().UTC().Unix()
+	c := newSeedCommand("explore")
+	depth := c.fs.Int("depth", 2, "Hops to explore from the seed; every artist closer than this is fetched")
+	top := c.fs.Int("top", 10, "Collaborators, bridges and genres per hop to show (-1 for all)")
+	exportPath := c.fs.String("export", "", "File to write the explored ego network to")
+	exportFormat := c.fs.String("export-format", "", "Export format: "+strings.Join(sixdegrees.ExportFormats, " or ")+"; guessed from the -export extension when empty")
+	seed, opts := c.setup(args, `Usage: go run main.go explore -seed "Artist" [-depth N] [-top N] [-export FILE] [-export-format json|dot] [-genres LIST] [-json] [-verbose]`)
+	if *depth < 1 {
+		fatal(c.log, "-depth must be at least 1", "depth", *depth)
+	}
+
+	c.log.Info("exploring", "seed", seed.Name, "depth", *depth)
+	n := sixdegrees.ExploreNeighborhood(seed, *depth, opts)
+	result := exploreResult{Exploration: n.Explore(*top), Stats: n.Helper.Stats}
+	if *exportPath != "" {
+		format := *exportFormat
+		if format == "" {
+			format = sixdegrees.ExportFormat(*exportPath)
+		}
+		if err := exportNetwork(n, *exportPath, format); err != nil {
+			fatal(c.log, "failed to export the ego network", "path", *exportPath, "err", err)
+		}
+		result.Export = *exportPath
+	}
+	result.Seconds = time.Now().UTC().Unix() - startTime
+	c.finish(result, result.Stats, func() { printExploration(result, *top) })
+}
+
+func exportNetwork(n *sixdegrees.Neighborhood, path, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := n.WriteNetwork(f, format); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+func printExploration(r exploreResult, top int) {
+	fmt.Printf("Artists by distance from %q:\n", r.Seed)
+	widest := 0
+	for _, n := range r.Histogram {
+		if n > widest {
+			widest = n
+		}
+	}
+	for d, n := range r.Histogram {
+		bar := ""
+		if widest > 0 {
+			bar = strings.Repeat("#", (n*40+widest-1)/widest)
+		}
+		fmt.Printf("  %d hops  %6d  %s\n", d, n, bar)
+	}
+
+	fmt.Printf("\nTop collaborators:\n")
+	for i, c := range r.TopCollaborators {
+		fmt.Printf("  %d. %s  (%d shared tracks, e.g. %q)\n", i+1, c.Name, c.SharedTracks, c.Track)
+	}
+
+	fmt.Printf("\nGenres reachable per hop:\n")
+	for d := 1; d < len(r.GenresByHop); d++ {
+		genres := make([]string, 0, len(r.GenresByHop[d]))
+		for g := range r.GenresByHop[d] {
+			genres = append(genres, g)
+		}
+		sort.Slice(genres, func(i, j int) bool {
+			a, b := r.GenresByHop[d][genres[i]], r.GenresByHop[d][genres[j]]
+			if a != b {
+				return a > b
+			}
+			return genres[i] < genres[j]
+		})
+		if top >= 0 && len(genres) > top {
+			genres = genres[:top]
+		}
+		for i, g := range genres {
+			genres[i] = fmt.Sprintf("%s (%d)", g, r.GenresByHop[d][g])
+		}
+		fmt.Printf("  %d hops: %d genres  %s\n", d, len(r.GenresByHop[d]), strings.Join(genres, ", "))
+	}
+
+	if len(r.Bridges) > 0 {
+		fmt.Printf("\nBridging neighbors (artists two hops out they lead to, and how many only through them):\n")
+		for i, b := range r.Bridges {
+			fmt.Printf("  %d. %s  leads to %d, only path to %d\n", i+1, b.Name, b.Reaches, b.Only)
+		}
+	}
+	if r.Export != "" {
+		fmt.Printf("\nEgo network written to %s\n", r.Export)
+	}
+	fmt.Printf("\nAnalysis took %d seconds\n", r.Seconds)
+}
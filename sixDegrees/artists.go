@@ -2,7 +2,7 @@ package sixdegrees
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 
 	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
 )
@@ -17,18 +17,32 @@ type Artists struct {
 	Genres                      map[string]int
 }
 
+// artistObject is Spotify's full artist object.
+type artistObject struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Popularity float64  `json:"popularity"`
+	Genres     []string `json:"genres"`
+}
+
 // searchResponse matches Spotify /v1/search
 type searchResponse struct {
 	Artists struct {
-		Items []struct {
-			ID         string   `json:"id"`
-			Name       string   `json:"name"`
-			Popularity float64  `json:"popularity"`
-			Genres     []string `json:"genres"`
-		} `json:"items"`
+		Items []artistObject `json:"items"`
 	} `json:"artists"`
 }
 
+// severalArtistsResponse matches Spotify /v1/artists?ids=...
+type severalArtistsResponse struct {
+	Artists []*artistObject `json:"artists"`
+}
+
+// fetchArtists fetches artist objects by ID; tests replace it.
+var fetchArtists = spotify.GetArtists
+
+// currentPlayback reads the user's now-playing track; tests replace it.
+var currentPlayback = spotify.GetPlayback
+
 // InputArtist queries Spotify and returns an initialized Artists struct.
 // It returns a placeholder with Name set if lookup fails (so callers can continue gracefully).
 func InputArtist(name string) *Artists {
@@ -42,22 +56,22 @@ func InputArtist(name string) *Artists {
 
 	body, err := spotify.SearchArtist(name)
 	if err != nil {
-		log.Printf("SearchArtist error for %q: %v", name, err)
+		logger().Warn("artist search failed", "query", name, "err", err)
 		return a
 	}
 	if !json.Valid(body) {
-		log.Printf("Spotify returned invalid JSON for %q", name)
+		logger().Warn("artist search returned invalid JSON", "query", name)
 		return a
 	}
 
 	var resp searchResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
-		log.Printf("JSON unmarshal error for %q: %v", name, err)
+		logger().Warn("cannot parse artist search", "query", name, "err", err)
 		return a
 	}
 
 	if len(resp.Artists.Items) == 0 {
-		log.Printf("No artist found for query %q", name)
+		logger().Info("no artist found", "query", name)
 		return a
 	}
 
@@ -69,10 +83,73 @@ func InputArtist(name string) *Artists {
 		a.Genres[g]++
 	}
 
-	log.Printf("Loaded artist: %s (ID %s)", a.Name, a.ID)
+	logger().Debug("loaded artist", "artist", a.Name, "artist_id", a.ID)
 	return a
 }
 
+// LookupArtists fetches the artists with the given Spotify IDs in batches,
+// keyed by ID. IDs Spotify does not know are left out.
+func LookupArtists(ids []string) (map[string]*Artists, error) {
+	found := make(map[string]*Artists, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+	body, err := fetchArtists(ids)
+	if err != nil {
+		return found, err
+	}
+	var resp severalArtistsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return found, err
+	}
+	for _, item := range resp.Artists {
+		if item == nil || item.ID == "" {
+			continue
+		}
+		a := CreateArtists(item.Name, item.ID)
+		a.Popularity = item.Popularity
+		for _, g := range item.Genres {
+			a.Genres[g]++
+		}
+		found[item.ID] = a
+	}
+	return found, nil
+}
+
+// NowPlaying returns the name of the track the user is listening to and
+// its credited artists, primary artist first. Artists Spotify cannot look up
+// are still returned with their name and ID; credits without an ID (local
+// files) are searched by name.
+func NowPlaying() (string, []*Artists, error) {
+	q := currentPlayback()
+	// Local files have no track ID, so go by the name and credits.
+	if q.TrackName == "" || len(q.Artists) == 0 {
+		return "", nil, errors.New("nothing is playing on Spotify")
+	}
+	ids := make([]string, 0, len(q.Artists))
+	for _, c := range q.Artists {
+		if c.ID != "" {
+			ids = append(ids, c.ID)
+		}
+	}
+	hydrated, err := LookupArtists(ids)
+	if err != nil {
+		logger().Warn("credited artist lookup failed", "track", q.TrackName, "err", err)
+	}
+	credits := make([]*Artists, 0, len(q.Artists))
+	for _, c := range q.Artists {
+		switch {
+		case hydrated[c.ID] != nil:
+			credits = append(credits, hydrated[c.ID])
+		case c.ID != "":
+			credits = append(credits, CreateArtists(c.Name, c.ID))
+		default:
+			credits = append(credits, InputArtist(c.Name))
+		}
+	}
+	return q.TrackName, credits, nil
+}
+
 // CreateArtists creates a lightweight Artists struct manually.
 func CreateArtists(name, id string) *Artists {
 	return &Artists{
@@ -11,10 +11,10 @@ import (
 
 func TestAppendArtistTracks(t *testing.T) {
 	art := InputArtist("Eminem")
-	albums,_ := spotify.ArtistAlbums(art.ID,1)
+	albums,_ := spotify.ArtistAlbums(art.ID,spotify.FetchOptions{AlbumLimit: 1})
 	h := NewHelper()
 	for _,al := range art.ParseAlbums(albums) {
-		tr, _ := spotify.GetAlbumTracks(al)
+		tr, _ := spotify.GetAlbumTracks(al,spotify.FetchOptions{})
 		T,_ := art.CreateTracks(tr,h)
 		art.Tracks = append(art.Tracks,T...)
 	}
@@ -0,0 +1,363 @@
+package sixdegrees
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ================================ Heuristics =================================
+
+// Heuristic estimates the remaining cost from an artist to the target.
+//
+// The built-in heuristics return 0 at the target and at most Scale anywhere
+// else. Any path to the target that is not already there crosses at least one
+// edge, so they are admissible (and consistent) whenever Scale does not exceed
+// the cheapest edge the WeightStrategy can produce. Larger scales trade
+// optimality for fewer expansions. CenterDistanceHeuristic, loaded from stored
+// metrics rather than by name, counts Scale per hop instead.
+type Heuristic interface {
+	Estimate(from, target *Artists) float64
+}
+
+// ZeroHeuristic turns A* into a lazily expanding Dijkstra.
+type ZeroHeuristic struct{}
+
+func (ZeroHeuristic) Estimate(_, _ *Artists) float64 { return 0 }
+
+// GenreOverlapHeuristic scores artists that share fewer genres with the
+// target (by Jaccard similarity) as farther away. Artists without genres are
+// treated optimistically.
+type GenreOverlapHeuristic struct{ Scale float64 }
+
+func (gh GenreOverlapHeuristic) Estimate(from, target *Artists) float64 {
+	if from == nil || target == nil || from.Name == target.Name {
+		return 0
+	}
+	if len(from.Genres) == 0 || len(target.Genres) == 0 {
+		return 0
+	}
+	return gh.Scale * (1 - genreSimilarity(from.Genres, target.Genres))
+}
+
+// PopularityHeuristic scores artists by how far their popularity (0..100) is
+// from the target's.
+type PopularityHeuristic struct{ Scale float64 }
+
+func (ph PopularityHeuristic) Estimate(from, target *Artists) float64 {
+	if from == nil || target == nil || from.Name == target.Name {
+		return 0
+	}
+	return ph.Scale * math.Min(1, math.Abs(target.Popularity-from.Popularity)/100)
+}
+
+// CenterDistanceHeuristic estimates from stored hop distances to one center
+// artist, keyed by artist ID (see CollabGraph.Distances). Any path between
+// two artists crosses at least as many edges as their distances to the
+// center differ, so it estimates Scale per hop of difference. That stays
+// admissible while Scale does not exceed the cheapest edge and the stored
+// graph holds the collaborations the search crosses. Artists without a
+// stored distance are treated optimistically.
+type CenterDistanceHeuristic struct {
+	Distances map[string]int
+	Scale     float64
+}
+
+func (ch CenterDistanceHeuristic) Estimate(from, target *Artists) float64 {
+	if from == nil || target == nil || from.ID == target.ID {
+		return 0
+	}
+	df, ok1 := ch.Distances[from.ID]
+	dt, ok2 := ch.Distances[target.ID]
+	if !ok1 || !ok2 {
+		return 0
+	}
+	return ch.Scale * math.Abs(float64(df-dt))
+}
+
+// heuristics maps the names accepted by HeuristicByName to constructors.
+var heuristics = map[string]func(scale float64) Heuristic{
+	"none":       func(float64) Heuristic { return ZeroHeuristic{} },
+	"genre":      func(s float64) Heuristic { return GenreOverlapHeuristic{Scale: s} },
+	"popularity": func(s float64) Heuristic { return PopularityHeuristic{Scale: s} },
+}
+
+// HeuristicNames lists the built-in heuristic names in sorted order.
+func HeuristicNames() []string {
+	names := make([]string, 0, len(heuristics))
+	for name := range heuristics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HeuristicByName resolves a built-in heuristic such as "genre" or
+// "popularity" with the given scale.
+func HeuristicByName(name string, scale float64) (Heuristic, error) {
+	if f, ok := heuristics[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return f(scale), nil
+	}
+	return nil, fmt.Errorf("unknown heuristic %q (want one of %s)", name, strings.Join(HeuristicNames(), ", "))
+}
+
+// ============================== Neighborhoods ================================
+
+// collaborator is an artist credited alongside another, with the context of
+// the edge between them.
+type collaborator struct {
+	artist *Artists
+	ctx    EdgeContext
+}
+
+// collaborators lists everyone credited with a on the tracks of a's that c
+// allows, in order of first appearance, with the context of each edge.
+func collaborators(a *Artists, c *Constraints) []collaborator {
+	var out []collaborator
+	var accs []edgeAcc
+	pos := make(map[string]int)
+	for _, t := range a.Tracks {
+		if !c.AllowsTrack(t) {
+			continue
+		}
+		for _, c := range trackCredits(a, t)[1:] {
+			i, ok := pos[c.Name]
+			if !ok {
+				i = len(out)
+				pos[c.Name] = i
+				out = append(out, collaborator{artist: c})
+				accs = append(accs, edgeAcc{})
+			}
+			accs[i].add(t)
+		}
+	}
+	for i := range out {
+		out[i].ctx = accs[i].context()
+	}
+	return out
+}
+
+// ================================= A* search =================================
+
+// astarState is an artist reached in a number of hops. Under a depth limit
+// the same artist reached in fewer hops may still lead somewhere its cheaper
+// but deeper state cannot, so the two are searched separately; without one
+// hops is always 0 and each artist has a single state.
+type astarState struct {
+	name string
+	hops int
+}
+
+// astarEdge is how a state was reached.
+type astarEdge struct {
+	from astarState
+	ctx  EdgeContext
+	cost float64
+}
+
+type astarItem struct {
+	astarState
+	g, f float64
+}
+
+// astarQueue orders by estimated total cost, preferring deeper entries (larger
+// g) and then names and hops on ties so runs are repeatable.
+type astarQueue []astarItem
+
+func (q astarQueue) Len() int { return len(q) }
+func (q astarQueue) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+	if q[i].g != q[j].g {
+		return q[i].g > q[j].g
+	}
+	if q[i].name != q[j].name {
+		return q[i].name < q[j].name
+	}
+	return q[i].hops < q[j].hops
+}
+func (q astarQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *astarQueue) Push(x interface{}) { *q = append(*q, x.(astarItem)) }
+
+func (q *astarQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+// RunAStarSearch finds the cheapest path from start to target under strat,
+// guided by heur. Unlike RunWeightedSearch it expands artists through the
+// catalog only when they are popped from the queue, so it usually fetches far
+// fewer artists. Edge context comes from the expanded artist's own tracks.
+// A nil strat uses CollabStrengthStrategy; a nil heur uses ZeroHeuristic.
+// Disallowed artists under opts.Constraints are never entered. Under a
+// depth limit the path is the cheapest within opts.MaxDepth hops.
+func RunAStarSearch(start, target *Artists, strat WeightStrategy, heur Heuristic, opts SearchOptions) (*Helper, []PathStep, bool) {
+	if strat == nil {
+		strat = CollabStrengthStrategy{}
+	}
+	if heur == nil {
+		heur = ZeroHeuristic{}
+	}
+	if opts.hasWaypoints() {
+		return searchViaWaypoints(start, target, opts, func(from, to *Artists, o SearchOptions) (*Helper, []PathStep, bool) {
+			return RunAStarSearch(from, to, strat, heur, o)
+		})
+	}
+	h := newSearchHelper(opts)
+	log := h.logger()
+	defer h.Stats.measure("astar")()
+	if opts.Known != nil {
+		defer opts.Known.MergeArtists(h)
+	}
+	h.ArtistMap[start.Name] = start
+	h.DistTo[start.Name] = 0
+
+	bounded := opts.MaxDepth >= 0
+	origin := astarState{name: start.Name}
+	best := map[astarState]float64{origin: 0} // cheapest known cost from start
+	depth := map[astarState]int{origin: 0}    // hops from start, also when unbounded
+	via := make(map[astarState]astarEdge)
+	closed := make(map[astarState]bool)
+	// owner is the state whose route h's Prev and DistTo describe for each
+	// artist. It only changes while that state is open, so the routes of
+	// artists linked from it never change under them.
+	owner := map[string]astarState{start.Name: origin}
+	queue := &astarQueue{}
+
+	// relax records a cheaper way to reach to through from, returning the
+	// state it reached.
+	relax := func(from astarState, to *Artists, ctx EdgeContext) (astarState, bool) {
+		d := depth[from] + 1
+		next := astarState{name: to.Name}
+		if bounded {
+			if depth[from] >= opts.MaxDepth {
+				return next, false
+			}
+			next.hops = d
+		}
+		if closed[next] || !opts.Constraints.Allows(to, target) {
+			return next, false
+		}
+		w := strat.Weight(target, h.ArtistMap[from.name], to, ctx)
+		if math.IsNaN(w) || math.IsInf(w, 0) || w < 0 {
+			return next, false
+		}
+		g := best[from] + w
+		if old, ok := best[next]; ok && g >= old {
+			return next, false
+		}
+		// A state reached in fewer hops for no more cost can do all this
+		// one can.
+		for k := 0; bounded && k < d; k++ {
+			if old, ok := best[astarState{to.Name, k}]; ok && old <= g {
+				return next, false
+			}
+		}
+		best[next] = g
+		depth[next] = d
+		via[next] = astarEdge{from: from, ctx: ctx, cost: w}
+		o, owned := owner[to.Name]
+		if owner[from.name] == from && (!owned || o == next || !closed[o] && g < best[o]) {
+			owner[to.Name] = next
+			h.linkEdge(from.name, to.Name, ctx)
+			h.DistTo[to.Name] = d
+		}
+		heap.Push(queue, astarItem{astarState: next, g: g, f: g + heur.Estimate(to, target)})
+		return next, true
+	}
+
+	// The target's own tracks reveal which artists connect to it, so those
+	// artists can reach the target without being fetched themselves.
+	noTarget := false
+	if err := opts.expand(target, h, "", &noTarget); err != nil {
+		log.Warn("expansion failed", "artist", target.Name, "err", err)
+	}
+	intoTarget := make(map[string]EdgeContext)
+	for _, c := range collaborators(target, opts.Constraints) {
+		intoTarget[c.artist.Name] = c.ctx
+	}
+	reached := func(st astarState) {
+		if ctx, ok := intoTarget[st.name]; ok {
+			relax(st, target, ctx)
+		}
+	}
+
+	heap.Push(queue, astarItem{astarState: origin, f: heur.Estimate(start, target)})
+	reached(origin)
+	var found *astarState
+
+	for queue.Len() > 0 && !h.outOfBudget() {
+		item := heap.Pop(queue).(astarItem)
+		st := item.astarState
+		if closed[st] || item.g > best[st] {
+			continue // stale queue entry
+		}
+		h.Stats.Dequeued++
+		closed[st] = true
+		if st.name == target.Name {
+			found = &st
+			break
+		}
+
+		current := h.ArtistMap[st.name]
+		if bounded && depth[st] >= opts.MaxDepth {
+			continue
+		}
+		log.Debug("expanding", "artist", current.Name, "cost", item.g, "estimate", item.f)
+		if err := opts.expand(current, h, "", &noTarget); err != nil {
+			log.Warn("expansion failed", "artist", current.Name, "err", err)
+		}
+
+		for _, c := range collaborators(current, opts.Constraints) {
+			next, ok := h.ArtistMap[c.artist.Name]
+			if !ok {
+				next = c.artist
+				h.ArtistMap[next.Name] = next
+			}
+			if next.Name == target.Name {
+				next = target
+			}
+			if ns, ok := relax(st, next, c.ctx); ok {
+				reached(ns)
+			}
+		}
+	}
+
+	if found == nil {
+		var frontier []string
+		queued := make(map[string]bool)
+		for _, item := range *queue {
+			if !closed[item.astarState] && item.g <= best[item.astarState] && !queued[item.name] {
+				queued[item.name] = true
+				frontier = append(frontier, item.name)
+			}
+		}
+		h.Diagnosis = diagnose(h, start, target, opts.MaxDepth, frontier)
+		return h, nil, false
+	}
+
+	// Lay the found route into h, whatever other routes it recorded.
+	var route []astarState
+	for st := *found; st != origin; st = via[st].from {
+		route = append(route, st)
+	}
+	path := []string{start.Name}
+	for i := len(route) - 1; i >= 0; i-- {
+		st, e := route[i], via[route[i]]
+		h.linkEdge(e.from.name, st.name, e.ctx)
+		h.DistTo[st.name] = depth[st]
+		path = append(path, st.name)
+	}
+	steps := h.Steps(path)
+	for i := range steps {
+		steps[i].Cost = via[route[len(route)-1-i]].cost
+	}
+	return h, steps, true
+}
@@ -0,0 +1,337 @@
+package sixdegrees
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// fixtureCatalog serves tracks from memory and counts how many artists were
+// fetched, standing in for the Spotify API.
+type fixtureCatalog struct {
+	tracks  map[string][]Track
+	fetched map[string]bool
+}
+
+func newFixtureCatalog() *fixtureCatalog {
+	return &fixtureCatalog{tracks: make(map[string][]Track), fetched: make(map[string]bool)}
+}
+
+// collab records a shared track in both artists' catalogs.
+func (c *fixtureCatalog) collab(a, b *Artists, name string) {
+	t := Track{Artist: a, Name: name, ID: name, Featured: []*Artists{b}}
+	c.tracks[a.Name] = append(c.tracks[a.Name], t)
+	c.tracks[b.Name] = append(c.tracks[b.Name], t)
+}
+
+func (c *fixtureCatalog) Expand(a *Artists, h *Helper) error {
+	if len(a.Tracks) > 0 {
+		return nil
+	}
+	c.fetched[a.Name] = true
+	a.Tracks = c.tracks[a.Name]
+	for _, t := range a.Tracks {
+		for _, x := range trackCredits(a, t) {
+			if _, ok := h.ArtistMap[x.Name]; !ok {
+				h.ArtistMap[x.Name] = x
+			}
+		}
+	}
+	return nil
+}
+
+// reset forgets every fetched track so the fixture can be searched again.
+func (c *fixtureCatalog) reset(artists []*Artists) {
+	for _, a := range artists {
+		a.Tracks = nil
+	}
+	c.fetched = make(map[string]bool)
+}
+
+// fixtureGraph builds n artists in genre clusters of popularity-skewed
+// artists, each collaborating mostly inside its cluster.
+func fixtureGraph(n, degree int, seed int64) ([]*Artists, *fixtureCatalog) {
+	const clusters = 10
+	rng := rand.New(rand.NewSource(seed))
+	artists := make([]*Artists, n)
+	for i := range artists {
+		a := CreateArtists(fmt.Sprintf("artist-%d", i), fmt.Sprintf("id-%d", i))
+		a.Popularity = math.Floor(100 * rng.Float64() * rng.Float64())
+		a.Genres[fmt.Sprintf("genre-%d", i%clusters)] = 1
+		if rng.Intn(4) == 0 {
+			a.Genres[fmt.Sprintf("genre-%d", rng.Intn(clusters))] = 1
+		}
+		artists[i] = a
+	}
+	cat := newFixtureCatalog()
+	for i, a := range artists {
+		for d := 0; d < degree; d++ {
+			j := rng.Intn(n)
+			if rng.Intn(5) != 0 {
+				j = (j/clusters)*clusters + i%clusters // same cluster
+			}
+			if j == i || j >= n {
+				continue
+			}
+			cat.collab(a, artists[j], fmt.Sprintf("t-%d-%d", i, d))
+		}
+	}
+	return artists, cat
+}
+
+func TestCollaborators_CountsDistinctSharedTracks(t *testing.T) {
+	A := &Artists{Name: "A"}
+	B := &Artists{Name: "B"}
+	C := &Artists{Name: "C"}
+	A.Tracks = []Track{
+		{Artist: A, Name: "t1", ID: "1", Featured: []*Artists{B}},
+		{Artist: A, Name: "t2", ID: "2", Featured: []*Artists{B, C}},
+		{Artist: A, Name: "t2", ID: "2", Featured: []*Artists{B, C}}, // same track on another album
+	}
+	got := collaborators(A, nil)
+	if len(got) != 2 || got[0].artist != B || got[1].artist != C {
+		t.Fatalf("unexpected collaborators %+v", got)
+	}
+	if got[0].ctx.SharedCount != 2 || got[0].ctx.Evidence != "t1" || got[1].ctx.SharedCount != 1 {
+		t.Fatalf("unexpected contexts %+v %+v", got[0].ctx, got[1].ctx)
+	}
+}
+
+func TestRunAStarSearch_FindsCheapestPathLazily(t *testing.T) {
+	A, B, C, D := CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c"), CreateArtists("D", "d")
+	X := CreateArtists("X", "x")
+	cat := newFixtureCatalog()
+	for i := 0; i < 3; i++ {
+		cat.collab(A, B, fmt.Sprintf("ab%d", i))
+	}
+	cat.collab(B, D, "bd")
+	cat.collab(A, C, "ac")
+	cat.collab(C, D, "cd")
+	cat.collab(C, X, "cx")
+
+	_, steps, ok := RunAStarSearch(A, D, CollabStrengthStrategy{}, nil, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok {
+		t.Fatalf("expected a path")
+	}
+	if len(steps) != 2 || steps[0].To != "B" || steps[1].Track != "bd" {
+		t.Fatalf("expected A-B-D via bd, got %+v", steps)
+	}
+	if want := 1.0/3 + 1; math.Abs(TotalCost(steps)-want) > 1e-9 {
+		t.Fatalf("expected cost %v, got %v", want, TotalCost(steps))
+	}
+	if cat.fetched["X"] {
+		t.Fatalf("expanded artists beyond the answer: %v", cat.fetched)
+	}
+}
+
+func TestRunAStarSearch_RespectsMaxDepth(t *testing.T) {
+	A, B, C := CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c")
+	cat := newFixtureCatalog()
+	cat.collab(A, B, "ab")
+	cat.collab(B, C, "bc")
+
+	if _, _, ok := RunAStarSearch(A, C, nil, nil, SearchOptions{MaxDepth: 1, Catalog: cat}); ok {
+		t.Fatalf("expected no path within depth 1")
+	}
+	cat.reset([]*Artists{A, B, C})
+	if _, steps, ok := RunAStarSearch(A, C, nil, nil, SearchOptions{MaxDepth: 2, Catalog: cat}); !ok || len(steps) != 2 {
+		t.Fatalf("expected a 2-hop path within depth 2, got %+v", steps)
+	}
+}
+
+// depthTrapGraph has a cheap 3-hop route A-C-B-T and a dearer 2-hop route
+// A-B-T, so a depth limit of 2 must not end up on the cheap one.
+func depthTrapGraph() (A, T *Artists, cat *fixtureCatalog, all []*Artists) {
+	A, B, C, T := CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c"), CreateArtists("T", "t")
+	cat = newFixtureCatalog()
+	cat.collab(A, B, "ab")
+	for i := 0; i < 5; i++ {
+		cat.collab(A, C, fmt.Sprintf("ac%d", i))
+		cat.collab(C, B, fmt.Sprintf("cb%d", i))
+	}
+	for i := 0; i < 3; i++ {
+		cat.collab(B, T, fmt.Sprintf("bt%d", i))
+	}
+	return A, T, cat, []*Artists{A, B, C, T}
+}
+
+func TestRunAStarSearch_CheaperDeeperRouteKeepsDepthLimit(t *testing.T) {
+	A, T, cat, _ := depthTrapGraph()
+	_, steps, ok := RunAStarSearch(A, T, CollabStrengthStrategy{}, nil, SearchOptions{MaxDepth: 2, Catalog: cat})
+	if !ok {
+		t.Fatalf("expected the 2-hop path")
+	}
+	if len(steps) != 2 || steps[0].To != "B" || steps[1].To != "T" {
+		t.Fatalf("expected A-B-T within depth 2, got %+v", steps)
+	}
+	if want := 1 + 1.0/3; math.Abs(TotalCost(steps)-want) > 1e-9 {
+		t.Fatalf("expected cost %v, got %v", want, TotalCost(steps))
+	}
+}
+
+func TestRunAStarSearch_HeuristicKeepsCostAndExpandsLess(t *testing.T) {
+	artists, cat := fixtureGraph(3000, 3, 7)
+	start, target := artists[1], artists[2502]
+
+	_, base, ok := RunAStarSearch(start, target, CollabStrengthStrategy{}, ZeroHeuristic{}, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok {
+		t.Fatalf("expected fixture endpoints to be connected")
+	}
+	baseFetched := len(cat.fetched)
+
+	cat.reset(artists)
+	heur := GenreOverlapHeuristic{Scale: 0.25}
+	_, guided, ok := RunAStarSearch(start, target, CollabStrengthStrategy{}, heur, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok {
+		t.Fatalf("expected guided search to find a path")
+	}
+	if math.Abs(TotalCost(base)-TotalCost(guided)) > 1e-9 {
+		t.Fatalf("admissible heuristic changed the cost: %v vs %v", TotalCost(base), TotalCost(guided))
+	}
+	if len(cat.fetched) > baseFetched {
+		t.Fatalf("heuristic expanded more artists (%d) than none (%d)", len(cat.fetched), baseFetched)
+	}
+}
+
+func TestRunAStarSearch_CenterDistanceKeepsCostAndExpandsLess(t *testing.T) {
+	artists, cat := fixtureGraph(3000, 3, 7)
+	start, target := artists[1], artists[2502]
+	stored := NewCollabGraph()
+	for name, tracks := range cat.tracks {
+		for _, tr := range tracks {
+			if tr.Artist.Name == name {
+				stored.AddEdge(tr.Artist.ID, tr.Featured[0].ID, 1)
+			}
+		}
+	}
+
+	_, base, ok := RunAStarSearch(start, target, CollabStrengthStrategy{}, ZeroHeuristic{}, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok {
+		t.Fatalf("expected fixture endpoints to be connected")
+	}
+	baseFetched := len(cat.fetched)
+
+	cat.reset(artists)
+	heur := CenterDistanceHeuristic{Distances: stored.Distances(artists[0].ID), Scale: 0.25}
+	_, guided, ok := RunAStarSearch(start, target, CollabStrengthStrategy{}, heur, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok {
+		t.Fatalf("expected guided search to find a path")
+	}
+	if math.Abs(TotalCost(base)-TotalCost(guided)) > 1e-9 {
+		t.Fatalf("admissible heuristic changed the cost: %v vs %v", TotalCost(base), TotalCost(guided))
+	}
+	if len(cat.fetched) >= baseFetched {
+		t.Fatalf("heuristic expanded %d artists, no fewer than none (%d)", len(cat.fetched), baseFetched)
+	}
+}
+
+func TestHeuristics(t *testing.T) {
+	target := &Artists{Name: "T", Popularity: 80, Genres: map[string]int{"rap": 1, "trap": 1}}
+	a := &Artists{Name: "A", Popularity: 30, Genres: map[string]int{"rap": 1, "pop": 1}}
+
+	if got := (GenreOverlapHeuristic{Scale: 1}).Estimate(a, target); math.Abs(got-2.0/3) > 1e-9 {
+		t.Fatalf("genre estimate = %v, want 2/3", got)
+	}
+	if got := (PopularityHeuristic{Scale: 2}).Estimate(a, target); math.Abs(got-1) > 1e-9 {
+		t.Fatalf("popularity estimate = %v, want 1", got)
+	}
+	if got := (GenreOverlapHeuristic{Scale: 1}).Estimate(target, target); got != 0 {
+		t.Fatalf("estimate at target = %v, want 0", got)
+	}
+	center := CenterDistanceHeuristic{Distances: map[string]int{"a": 1, "t": 4}, Scale: 0.5}
+	a.ID, target.ID = "a", "t"
+	if got := center.Estimate(a, target); math.Abs(got-1.5) > 1e-9 {
+		t.Fatalf("center estimate = %v, want 1.5", got)
+	}
+	if got := center.Estimate(&Artists{ID: "x"}, target); got != 0 {
+		t.Fatalf("estimate without a stored distance = %v, want 0", got)
+	}
+	if _, err := HeuristicByName("Genre", 1); err != nil {
+		t.Fatalf("HeuristicByName: %v", err)
+	}
+	if _, err := HeuristicByName("nope", 1); err == nil {
+		t.Fatalf("expected error for unknown heuristic")
+	}
+}
+
+// Benchmarks compare how many artists each search fetches through the
+// catalog (fetches/search) on the same clustered fixture graph, averaged over
+// a fixed set of endpoint pairs. BFS stops at the first connection it sees;
+// Dijkstra and A* return the cheapest path under CollabStrengthStrategy, with
+// Dijkstra fetching the whole reachable graph first.
+
+const fixturePairs = 8
+
+func benchmarkFixtureSearch(b *testing.B, n int, search func(start, target *Artists, cat Catalog) bool) {
+	artists, cat := fixtureGraph(n, 6, 1)
+	rng := rand.New(rand.NewSource(2))
+	pairs := make([][2]*Artists, fixturePairs)
+	for i := range pairs {
+		pairs[i] = [2]*Artists{artists[rng.Intn(n)], artists[rng.Intn(n)]}
+	}
+	fetched := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range pairs {
+			b.StopTimer()
+			cat.reset(artists)
+			b.StartTimer()
+			if !search(p[0], p[1], cat) {
+				b.Fatalf("no path between %s and %s", p[0].Name, p[1].Name)
+			}
+			fetched += len(cat.fetched)
+		}
+	}
+	b.ReportMetric(float64(fetched)/float64(b.N*fixturePairs), "fetches/search")
+}
+
+func runFixtureBFS(start, target *Artists, cat Catalog) bool {
+	_, _, ok := RunSearchOpts(start, target, SearchOptions{MaxDepth: -1, Catalog: cat})
+	return ok
+}
+
+func runFixtureDijkstra(start, target *Artists, cat Catalog) bool {
+	h := NewHelper()
+	h.ArtistMap[start.Name] = start
+	queue := []*Artists{start}
+	seen := map[string]bool{start.Name: true}
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		cat.Expand(a, h)
+		for _, c := range collaborators(a, nil) {
+			if !seen[c.artist.Name] {
+				seen[c.artist.Name] = true
+				queue = append(queue, c.artist)
+			}
+		}
+	}
+	g, meta := BuildGraph(h, target)
+	d := NewDijkstrasToTarget(g, start, target, CollabStrengthStrategy{}, meta)
+	_, ok := d.PathTo(g, target.Name)
+	return ok
+}
+
+func runFixtureAStarZero(start, target *Artists, cat Catalog) bool {
+	_, _, ok := RunAStarSearch(start, target, CollabStrengthStrategy{}, ZeroHeuristic{}, SearchOptions{MaxDepth: -1, Catalog: cat})
+	return ok
+}
+
+// Fixture collaborations rarely repeat, so edges cost 1 and a genre scale of
+// 1 stays (almost always) admissible.
+func runFixtureAStar(start, target *Artists, cat Catalog) bool {
+	_, _, ok := RunAStarSearch(start, target, CollabStrengthStrategy{}, GenreOverlapHeuristic{Scale: 1}, SearchOptions{MaxDepth: -1, Catalog: cat})
+	return ok
+}
+
+func BenchmarkFixtureBFS_2k(b *testing.B)       { benchmarkFixtureSearch(b, 2000, runFixtureBFS) }
+func BenchmarkFixtureDijkstra_2k(b *testing.B)  { benchmarkFixtureSearch(b, 2000, runFixtureDijkstra) }
+func BenchmarkFixtureAStarZero_2k(b *testing.B) { benchmarkFixtureSearch(b, 2000, runFixtureAStarZero) }
+func BenchmarkFixtureAStar_2k(b *testing.B)     { benchmarkFixtureSearch(b, 2000, runFixtureAStar) }
+func BenchmarkFixtureBFS_20k(b *testing.B)      { benchmarkFixtureSearch(b, 20000, runFixtureBFS) }
+func BenchmarkFixtureDijkstra_20k(b *testing.B) { benchmarkFixtureSearch(b, 20000, runFixtureDijkstra) }
+func BenchmarkFixtureAStarZero_20k(b *testing.B) {
+	benchmarkFixtureSearch(b, 20000, runFixtureAStarZero)
+}
+func BenchmarkFixtureAStar_20k(b *testing.B) { benchmarkFixtureSearch(b, 20000, runFixtureAStar) }
@@ -4,7 +4,8 @@ import (
 	"container/heap"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"time"
 
 	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
@@ -16,7 +17,15 @@ type ArtistQueue []*Artists
 func (aq ArtistQueue) Len() int { return len(aq) }
 func (aq ArtistQueue) Less(i, j int) bool {
 	// Change < to > if you want more popular first
-	return aq[i].Popularity > aq[j].Popularity // More popular first
+	if aq[i].Popularity != aq[j].Popularity {
+		return aq[i].Popularity > aq[j].Popularity // More popular first
+	}
+	// Break ties by ID, then name, so the same query expands artists in the
+	// same order every time.
+	if aq[i].ID != aq[j].ID {
+		return aq[i].ID < aq[j].ID
+	}
+	return aq[i].Name < aq[j].Name
 }
 func (aq ArtistQueue) Swap(i, j int) { aq[i], aq[j] = aq[j], aq[i] }
 
@@ -38,6 +47,17 @@ type Helper struct {
 	DistTo    map[string]int      // distance (hops)
 	Prev      map[string]string   // predecessor chain
 	Evidence  map[string]string   // track name connecting Prev[x] -> x
+
+	EvidenceID    map[string]string // Spotify ID of Evidence[x]; empty when unknown
+	EvidenceYear  map[string]int    // release year of Evidence[x]; 0 when unknown
+	EvidenceGuest map[string]bool   // Evidence[x] is a guest appearance
+
+	Stats *SearchStats // work done by the search that filled this helper
+
+	Diagnosis *Diagnosis // why the search found no path; nil when it found one
+
+	log    *slog.Logger // the search's logger; nil uses the package logger
+	budget *budgetMeter // what the search may still spend; nil is unlimited
 }
 
 // NewHelper initializes an empty BFS helper
@@ -47,36 +67,222 @@ func NewHelper() *Helper {
 		DistTo:    make(map[string]int),
 		Prev:      make(map[string]string),
 		Evidence:  make(map[string]string),
+
+		EvidenceID:    make(map[string]string),
+		EvidenceYear:  make(map[string]int),
+		EvidenceGuest: make(map[string]bool),
+
+		Stats: newSearchStats(),
 	}
 }
 
+// link records that t connects from -> to on the current path.
+func (h *Helper) link(from, to string, t Track) {
+	h.Prev[to] = from
+	h.Evidence[to] = t.Name
+	h.EvidenceID[to] = t.ID
+	h.EvidenceYear[to] = t.Year
+	h.EvidenceGuest[to] = t.Guest
+}
+
+// linkEdge is link for an edge summarized by its context.
+func (h *Helper) linkEdge(from, to string, ctx EdgeContext) {
+	h.Prev[to] = from
+	h.Evidence[to] = ctx.Evidence
+	h.EvidenceID[to] = ctx.EvidenceID
+	h.EvidenceYear[to] = ctx.Year
+	h.EvidenceGuest[to] = ctx.Guest
+}
+
 var albumCache = make(map[string][]byte)
 
+// albumCacheKey keys albumCache; truncated track lists are cached separately.
+func albumCacheKey(albumID string, fetch spotify.FetchOptions) string {
+	if fetch.TrackLimit > 0 {
+		return fmt.Sprintf("%s/%d", albumID, fetch.TrackLimit)
+	}
+	return albumID
+}
+
+// severalAlbumsResponse matches Spotify /v1/albums?ids=...
+type severalAlbumsResponse struct {
+	Albums []*struct {
+		ID     string `json:"id"`
+		Tracks struct {
+			Items []json.RawMessage `json:"items"`
+			Next  *string           `json:"next"`
+		} `json:"tracks"`
+	} `json:"albums"`
+}
+
+// fetchAlbums fetches album objects by ID; tests replace it.
+var fetchAlbums = spotify.GetAlbums
+
+// fetchArtistAlbums lists an artist's albums; tests replace it.
+var fetchArtistAlbums = spotify.ArtistAlbums
+
+// prefetchAlbumTracks caches the tracks of every uncached album in ids using
+// batched album lookups, which carry each album's first page of tracks.
+// Albums with more tracks than that are left to fetchAlbumTracksCached.
+func prefetchAlbumTracks(ids []string, fetch spotify.FetchOptions) error {
+	var missing []string
+	for _, id := range ids {
+		if _, ok := albumCache[albumCacheKey(id, fetch)]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	body, err := fetchAlbums(missing, fetch)
+	if err != nil {
+		return err
+	}
+	var resp severalAlbumsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	for _, al := range resp.Albums {
+		if al == nil || al.ID == "" {
+			continue
+		}
+		items := al.Tracks.Items
+		if fetch.TrackLimit > 0 && len(items) >= fetch.TrackLimit {
+			items = items[:fetch.TrackLimit]
+		} else if al.Tracks.Next != nil && *al.Tracks.Next != "" {
+			continue
+		}
+		if data, err := json.Marshal(map[string][]json.RawMessage{"items": items}); err == nil {
+			albumCache[albumCacheKey(al.ID, fetch)] = data
+		}
+	}
+	return nil
+}
+
 // This function checks if we have any cached albums and their respective tracks
-func fetchAlbumTracksCached(a *Artists, h *Helper, albumID string) ([]byte, error) {
+func fetchAlbumTracksCached(a *Artists, h *Helper, albumID string, fetch spotify.FetchOptions) ([]byte, error) {
 	// 1. check memory cache
-	if data, ok := albumCache[albumID]; ok {
-		fmt.Println("Got a cached Album for %s", a.Name)
+	key := albumCacheKey(albumID, fetch)
+	if data, ok := albumCache[key]; ok {
+		h.logger().Debug("album cache hit", "artist", a.Name, "album_id", albumID)
 		return data, nil
 	}
 
 	// 2. call API
-	tracks, err := spotify.GetAlbumTracks(albumID)
+	tracks, err := spotify.GetAlbumTracks(albumID, fetch)
 	if err != nil {
 		return nil, err
 	}
 
 	// 3. store to cache as bytes (for reuse)
 	if data, err := json.Marshal(tracks); err == nil {
-		albumCache[albumID] = data
+		albumCache[key] = data
 	}
 
 	return tracks, nil
 }
 
-// RunSearchOpts performs a bounded/unbounded BFS search between artists.
-func RunSearchOpts(start, target *Artists, maxDepth int, verbose bool, limit *int) (*Helper, []string, bool) {
+// SearchOptions tunes a search run.
+type SearchOptions struct {
+	MaxDepth int // maximum hops; -1 for unlimited
+
+	// Logger receives the search's log records; expansion progress is
+	// logged at debug level. nil uses the package logger (see SetLogger).
+	Logger *slog.Logger
+
+	// Fetch controls how much of each artist's catalog the built-in Spotify
+	// expansion fetches.
+	Fetch spotify.FetchOptions
+
+	// Constraints restricts the artists a path may pass through. nil allows
+	// any artist.
+	Constraints *Constraints
+
+	// Catalog expands artists as the search reaches them. nil fetches from
+	// Spotify.
+	Catalog Catalog
+
+	// Trace, when set, receives one JSON line per expanded artist, in
+	// expansion order.
+	Trace io.Writer
+
+	// Known holds artists loaded before the search (e.g. from a snapshot).
+	// They are reused instead of being looked up again, and every artist the
+	// search discovers is added back to it. Known is not safe for concurrent
+	// searches.
+	Known *Helper
+
+	// Budget caps the Spotify requests, artist expansions and wall time the
+	// search may spend. The zero Budget is unlimited.
+	Budget Budget
+
+	// Collabs supplies collaborations saved from earlier crawls. Weighted
+	// searches count them towards the shared tracks and recency of the
+	// edges they build, so tracks they did not fetch still strengthen an
+	// edge. nil uses only the fetched tracks.
+	Collabs StoredCollabs
+
+	meter *budgetMeter // shared by the legs of a waypoint search
+}
+
+// newSearchHelper returns fresh per-search state that already knows every
+// artist in opts.Known and logs through opts.Logger.
+func newSearchHelper(opts SearchOptions) *Helper {
 	h := NewHelper()
+	if opts.Logger != nil {
+		h.log = opts.Logger.With("component", "search")
+	}
+	h.budget = opts.meter
+	if h.budget == nil {
+		h.budget = newBudgetMeter(opts.Budget)
+	}
+	if known := opts.Known; known != nil {
+		for name, a := range known.ArtistMap {
+			h.ArtistMap[name] = a
+		}
+	}
+	return h
+}
+
+// MergeArtists adds every artist from other that h does not know yet.
+func (h *Helper) MergeArtists(other *Helper) int {
+	if other == nil {
+		return 0
+	}
+	added := 0
+	for name, a := range other.ArtistMap {
+		if _, ok := h.ArtistMap[name]; !ok {
+			h.ArtistMap[name] = a
+			added++
+		}
+	}
+	return added
+}
+
+// RunSearchOpts performs a bounded/unbounded BFS search between artists.
+func RunSearchOpts(start, target *Artists, opts SearchOptions) (*Helper, []string, bool) {
+	if opts.hasWaypoints() {
+		h, steps, ok := searchViaWaypoints(start, target, opts, func(from, to *Artists, o SearchOptions) (*Helper, []PathStep, bool) {
+			h, path, ok := RunSearchOpts(from, to, o)
+			return h, h.Steps(path), ok
+		})
+		if !ok {
+			return h, nil, false
+		}
+		path := []string{start.Name}
+		for _, st := range steps {
+			path = append(path, st.To)
+		}
+		return h, path, true
+	}
+
+	maxDepth := opts.MaxDepth
+	h := newSearchHelper(opts)
+	log := h.logger()
+	defer h.Stats.measure("bfs")()
+	if opts.Known != nil {
+		defer opts.Known.MergeArtists(h)
+	}
 	h.ArtistMap[start.Name] = start
 	h.DistTo[start.Name] = 0
 
@@ -86,14 +292,21 @@ func RunSearchOpts(start, target *Artists, maxDepth int, verbose bool, limit *in
 	visited := map[string]bool{start.Name: true}
 	found := false
 
+	// Callers usually fetch the start's tracks up front; fill them in if not
+	if err := opts.expand(start, h, target.Name, &found); err != nil {
+		log.Warn("expansion failed", "artist", start.Name, "err", err)
+	}
+
 	// Functions for adding
 	// UpsertArtist, UpsertAlbum, UpsertTrack, AddTrackArtist, SaveArtistWithTracks
 	for queue.Len() > 0 && !found {
+		if h.outOfBudget() {
+			break
+		}
 		current := heap.Pop(queue).(*Artists)
+		h.Stats.Dequeued++
 
-		if verbose {
-			log.Printf("[Depth %d] Exploring %s (%d tracks)", h.DistTo[current.Name], current.Name, len(current.Tracks))
-		}
+		log.Debug("exploring", "artist", current.Name, "depth", h.DistTo[current.Name], "tracks", len(current.Tracks))
 
 		// Depth guard
 		if maxDepth >= 0 && h.DistTo[current.Name] >= maxDepth {
@@ -101,9 +314,11 @@ func RunSearchOpts(start, target *Artists, maxDepth int, verbose bool, limit *in
 		}
 
 		for _, tr := range current.Tracks {
+			if !opts.Constraints.AllowsTrack(tr) {
+				continue
+			}
 			if tr.Artist.Name == target.Name {
-				h.Prev[target.Name] = current.Name
-				h.Evidence[target.Name] = tr.Name
+				h.link(current.Name, target.Name, tr)
 				found = true
 				break
 			}
@@ -115,30 +330,34 @@ func RunSearchOpts(start, target *Artists, maxDepth int, verbose bool, limit *in
 				if visited[feat.Name] {
 					continue
 				}
+				if !opts.Constraints.Allows(feat, target) {
+					continue
+				}
 				visited[feat.Name] = true
 
-				h.Prev[feat.Name] = current.Name
-				h.Evidence[feat.Name] = tr.Name
+				h.link(current.Name, feat.Name, tr)
 				h.DistTo[feat.Name] = h.DistTo[current.Name] + 1
 				h.ArtistMap[feat.Name] = feat
 
-				if verbose {
-					log.Printf("  ↳ Found feature: %s (via %s)", feat.Name, tr.Name)
+				log.Debug("found collaborator", "artist", feat.Name, "via", current.Name, "track", tr.Name)
+				if feat.Name == target.Name {
+					found = true
+					break
 				}
 
 				// Fetch this feature’s albums/tracks only once
-				if err := enrichArtist(feat, h, target.Name, &found, verbose, limit); err != nil && verbose {
-					log.Printf("    (warning: %v)", err)
-				}
-				if found {
-					break
+				if err := opts.expand(feat, h, target.Name, &found); err != nil {
+					log.Warn("expansion failed", "artist", feat.Name, "err", err)
 				}
 
-				// Check if target found among features’ tracks
-				if hasTarget(feat, target.Name) {
-					h.Prev[target.Name] = feat.Name
-					found = true
-					break
+				// Check if target found among features’ tracks, as long as
+				// the extra hop still fits within the depth limit
+				if maxDepth < 0 || h.DistTo[feat.Name] < maxDepth {
+					if track, ok := targetTrack(feat, target.Name, opts.Constraints); ok {
+						h.link(feat.Name, target.Name, track)
+						found = true
+						break
+					}
 				}
 
 				heap.Push(queue, feat)
@@ -152,57 +371,87 @@ func RunSearchOpts(start, target *Artists, maxDepth int, verbose bool, limit *in
 	if found {
 		return h, h.ReconstructPath(start.Name, target.Name), true
 	}
+	frontier := make([]string, 0, queue.Len())
+	for _, a := range *queue {
+		frontier = append(frontier, a.Name)
+	}
+	h.Diagnosis = diagnose(h, start, target, maxDepth, frontier)
 	return h, nil, false
 }
 
 // Functions for adding
 // UpsertArtist, UpsertAlbum, UpsertTrack, AddTrackArtist, SaveArtistWithTracks
 // Enrich artist data by fetching albums and tracks if not already populated.
-func enrichArtist(a *Artists, h *Helper, target string, found *bool, verbose bool, limit *int) error {
+func enrichArtist(a *Artists, h *Helper, target string, found *bool, fetch spotify.FetchOptions) error {
 	if len(a.Tracks) > 0 || *found {
 		return nil
 	}
-	if verbose {
-		log.Printf("    Fetching albums/tracks for %s...", a.Name)
-	}
-	body, err := spotify.ArtistAlbums(a.ID, 5)
+	log := h.logger()
+	log.Debug("fetching albums", "artist", a.Name, "artist_id", a.ID)
+	body, err := fetchArtistAlbums(a.ID, fetch)
 	if err != nil {
 		return fmt.Errorf("albums fetch failed for %s: %w", a.Name, err)
 	}
-	for i, al := range a.ParseAlbums(body) {
-		if i > 5 {
-			return nil
+	albums := ParseAlbumInfo(body)
+	ids := a.ParseAlbums(body)
+	log.Debug("parsed albums", "artist", a.Name, "albums", len(ids))
+	requested := make(map[string]bool)
+	for _, al := range ids {
+		if _, ok := albumCache[albumCacheKey(al, fetch)]; ok {
+			h.Stats.CacheHits++
+		} else {
+			h.Stats.CacheMisses++
+			requested[al] = true
 		}
-		tracks, err := fetchAlbumTracksCached(a, h, al)
+	}
+	if err := prefetchAlbumTracks(ids, fetch); err != nil {
+		log.Warn("batched album fetch failed", "artist", a.Name, "err", err)
+	}
+	for _, al := range ids {
+		tracks, err := fetchAlbumTracksCached(a, h, al, fetch)
 		if err != nil {
 			continue
 		}
-		T, _ := a.CreateTracks(tracks, h)
-		a.Tracks = append(a.Tracks, T...)
+		if requested[al] {
+			h.Stats.AlbumsFetched++
+		}
+		a.Tracks = append(a.Tracks, a.AlbumTracks(tracks, albums[al], h)...)
 
-		// check if any of these tracks hit the target mid-fetch
+		// stop fetching once these tracks reach the target; the caller
+		// records the connecting hop
 		if hasTarget(a, target) {
-			*found = true
 			return nil
 		}
 	}
-	time.Sleep(300 * time.Millisecond) // small delay to respect API rate limits
+	// small delay to respect API rate limits; only Spotify's own 429 waits
+	// count as throttling
+	time.Sleep(300 * time.Millisecond)
 	return nil
 }
 
 // Utility to check if any track by this artist matches the target
 func hasTarget(a *Artists, target string) bool {
+	_, ok := targetTrack(a, target, nil)
+	return ok
+}
+
+// targetTrack returns the first track by this artist that credits the target
+// and that c allows.
+func targetTrack(a *Artists, target string, c *Constraints) (Track, bool) {
 	for _, t := range a.Tracks {
-		if t.Artist.Name == target {
-			return true
+		if !c.AllowsTrack(t) {
+			continue
+		}
+		if t.Artist != nil && t.Artist.Name == target {
+			return t, true
 		}
 		for _, f := range t.Featured {
-			if f.Name == target {
-				return true
+			if f != nil && f.Name == target {
+				return t, true
 			}
 		}
 	}
-	return false
+	return Track{}, false
 }
 
 func (h *Helper) ReconstructPath(start, target string) []string {
@@ -217,8 +466,8 @@ func (h *Helper) ReconstructPath(start, target string) []string {
 			break
 		}
 		cur = h.Prev[cur]
-		if cur == "" {
-			return nil
+		if cur == "" || len(path) > len(h.Prev) {
+			return nil // broken or cyclic chain
 		}
 	}
 	// reverse
@@ -227,3 +476,54 @@ func (h *Helper) ReconstructPath(start, target string) []string {
 	}
 	return path
 }
+
+// PathStep is one hop of a found path with the track that connects it.
+type PathStep struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Track   string  `json:"track,omitempty"`
+	TrackID string  `json:"track_id,omitempty"` // Spotify ID of Track
+	Year    int     `json:"year,omitempty"`     // release year of Track
+	Guest   bool    `json:"guest,omitempty"`    // Track is from another artist's release
+	Cost    float64 `json:"cost"`               // edge cost; BFS counts every hop as 1
+}
+
+// Steps converts a reconstructed BFS path into hops with their evidence tracks.
+func (h *Helper) Steps(path []string) []PathStep {
+	if len(path) < 2 {
+		return nil
+	}
+	steps := make([]PathStep, 0, len(path)-1)
+	for i := 1; i < len(path); i++ {
+		steps = append(steps, PathStep{
+			From:    path[i-1],
+			To:      path[i],
+			Track:   h.Evidence[path[i]],
+			TrackID: h.EvidenceID[path[i]],
+			Year:    h.EvidenceYear[path[i]],
+			Guest:   h.EvidenceGuest[path[i]],
+			Cost:    1,
+		})
+	}
+	return steps
+}
+
+// ReverseSteps flips a path in place so it reads from the other end.
+func ReverseSteps(steps []PathStep) []PathStep {
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	for i := range steps {
+		steps[i].From, steps[i].To = steps[i].To, steps[i].From
+	}
+	return steps
+}
+
+// TotalCost sums the cost of every hop.
+func TotalCost(steps []PathStep) float64 {
+	total := 0.0
+	for _, s := range steps {
+		total += s.Cost
+	}
+	return total
+}
@@ -1,6 +1,10 @@
 package sixdegrees
 
-import "testing"
+import (
+	"container/heap"
+	"strings"
+	"testing"
+)
 
 // BFS unit tests using a small synthetic graph without hitting Spotify API.
 func TestBFS_PathReconstruction(t *testing.T) {
@@ -16,7 +20,7 @@ func TestBFS_PathReconstruction(t *testing.T) {
 	C.Tracks = []Track{{Artist: C, Name: "t3", Featured: []*Artists{D}}}
 
 	// Use RunSearchOpts with no depth limit on the synthetic graph
-	helper, path, found := RunSearchOpts(A, D, -1, false, nil)
+	_, path, found := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1})
 	if !found {
 		t.Fatalf("expected to find path from A to D")
 	}
@@ -40,8 +44,37 @@ func TestBFS_DepthLimitStopsExpansion(t *testing.T) {
 	B.Tracks = []Track{{Artist: B, Name: "t2", Featured: []*Artists{C}}}
 
 	// Depth limit of 1 allows A->B but not B->C expansion
-	_, _, found := RunSearchOpts(A, C, 1, false, nil)
+	_, _, found := RunSearchOpts(A, C, SearchOptions{MaxDepth: 1})
 	if found {
 		t.Fatalf("did not expect to find C within depth 1")
 	}
 }
+
+func TestSteps_AndReverse(t *testing.T) {
+	h := NewHelper()
+	h.Evidence["B"] = "t1"
+	h.Evidence["C"] = "t2"
+	steps := h.Steps([]string{"A", "B", "C"})
+	if len(steps) != 2 || steps[0].Track != "t1" || steps[1].From != "B" || TotalCost(steps) != 2 {
+		t.Fatalf("unexpected steps %+v", steps)
+	}
+	ReverseSteps(steps)
+	if steps[0].From != "C" || steps[0].To != "B" || steps[0].Track != "t2" || steps[1].To != "A" {
+		t.Fatalf("unexpected reversed steps %+v", steps)
+	}
+}
+
+func TestArtistQueue_BreaksTiesByID(t *testing.T) {
+	q := &ArtistQueue{}
+	for _, id := range []string{"c", "a", "d", "b"} {
+		heap.Push(q, &Artists{Name: "same", ID: id, Popularity: 50})
+	}
+	heap.Push(q, &Artists{Name: "popular", ID: "z", Popularity: 90})
+	var got []string
+	for q.Len() > 0 {
+		got = append(got, heap.Pop(q).(*Artists).ID)
+	}
+	if want := "z a b c d"; strings.Join(got, " ") != want {
+		t.Fatalf("popped %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package sixdegrees
+
+// RunBidirectionalSearch finds a path with the fewest hops by searching from
+// both ends at once, one level at a time, always growing the side with the
+// smaller frontier. The two searches meet in the middle, so a long path costs
+// about as many expansions as two searches of half its length. Unlike
+// RunSearchOpts, which explores popular artists first and stops at the first
+// path it sees, the path is always among the shortest; ties go to the meeting
+// artist with the smallest name, so runs are repeatable. Waypoints are not
+// supported; use RunSearchOpts for them.
+func RunBidirectionalSearch(start, target *Artists, opts SearchOptions) (*Helper, []string, bool) {
+	h := newSearchHelper(opts)
+	log := h.logger()
+	defer h.Stats.measure("bidirectional")()
+	if opts.Known != nil {
+		defer opts.Known.MergeArtists(h)
+	}
+	h.ArtistMap[start.Name] = start
+	h.ArtistMap[target.Name] = target
+	h.DistTo[start.Name] = 0
+	if start.Name == target.Name {
+		return h, []string{start.Name}, true
+	}
+
+	// back searches from the target with h's artists, stats and budget, so
+	// its Prev points towards the target.
+	back := NewHelper()
+	back.ArtistMap, back.Stats, back.log, back.budget = h.ArtistMap, h.Stats, h.log, h.budget
+	back.DistTo[target.Name] = 0
+
+	fwd, bwd := []*Artists{start}, []*Artists{target}
+	depthF, depthB := 0, 0
+	noTarget := false
+	meet := ""
+
+	// grow expands every artist on one side's frontier and returns the next
+	// frontier, noting the best artist the other side has already reached.
+	grow := func(side, other *Helper, frontier []*Artists) []*Artists {
+		var next []*Artists
+		best := -1
+		for _, current := range frontier {
+			if h.outOfBudget() {
+				return nil
+			}
+			h.Stats.Dequeued++
+			log.Debug("exploring", "artist", current.Name, "depth", side.DistTo[current.Name], "tracks", len(current.Tracks))
+			if err := opts.expand(current, side, "", &noTarget); err != nil {
+				log.Warn("expansion failed", "artist", current.Name, "err", err)
+			}
+			for _, c := range collaborators(current, opts.Constraints) {
+				a, ok := h.ArtistMap[c.artist.Name]
+				if !ok {
+					a = c.artist
+					h.ArtistMap[a.Name] = a
+				}
+				if _, seen := side.DistTo[a.Name]; seen || !opts.Constraints.Allows(a, target) {
+					continue
+				}
+				side.linkEdge(current.Name, a.Name, c.ctx)
+				side.DistTo[a.Name] = side.DistTo[current.Name] + 1
+				next = append(next, a)
+				if d, ok := other.DistTo[a.Name]; ok && (best < 0 || d < best || d == best && a.Name < meet) {
+					best, meet = d, a.Name
+				}
+			}
+		}
+		return next
+	}
+
+	for meet == "" && len(fwd) > 0 && len(bwd) > 0 && !h.outOfBudget() {
+		if opts.MaxDepth >= 0 && depthF+depthB >= opts.MaxDepth {
+			break
+		}
+		if len(fwd) <= len(bwd) {
+			fwd = grow(h, back, fwd)
+			depthF++
+		} else {
+			bwd = grow(back, h, bwd)
+			depthB++
+		}
+	}
+
+	if meet == "" {
+		var frontier []string
+		for _, a := range fwd {
+			frontier = append(frontier, a.Name)
+		}
+		h.Diagnosis = diagnose(h, start, target, opts.MaxDepth, frontier)
+		if h.Diagnosis.Reason != StopBudget && opts.MaxDepth >= 0 && depthF+depthB >= opts.MaxDepth {
+			h.Diagnosis.Reason = StopDepthLimit
+			h.Diagnosis.Frontier = len(fwd) + len(bwd)
+		}
+		return h, nil, false
+	}
+
+	// Hang the backward half of the path off the forward one.
+	for cur := meet; cur != target.Name; {
+		next := back.Prev[cur]
+		h.Prev[next] = cur
+		h.Evidence[next] = back.Evidence[cur]
+		h.EvidenceID[next] = back.EvidenceID[cur]
+		h.EvidenceYear[next] = back.EvidenceYear[cur]
+		h.EvidenceGuest[next] = back.EvidenceGuest[cur]
+		h.DistTo[next] = h.DistTo[cur] + 1
+		cur = next
+	}
+	return h, h.ReconstructPath(start.Name, target.Name), true
+}
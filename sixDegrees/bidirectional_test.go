@@ -0,0 +1,73 @@
+package sixdegrees
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRunBidirectionalSearch_StitchesBothHalves(t *testing.T) {
+	a, b, c, d := CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c"), CreateArtists("D", "d")
+	cat := newFixtureCatalog()
+	cat.collab(a, b, "ab")
+	cat.collab(b, c, "bc")
+	cat.collab(c, d, "cd")
+
+	h, path, ok := RunBidirectionalSearch(a, d, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok {
+		t.Fatalf("expected a path, diagnosis %+v", h.Diagnosis)
+	}
+	want := []string{"A", "B", "C", "D"}
+	if len(path) != len(want) {
+		t.Fatalf("path = %v, want %v", path, want)
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Fatalf("path = %v, want %v", path, want)
+		}
+	}
+	for i, st := range h.Steps(path) {
+		if wantTrack := []string{"ab", "bc", "cd"}[i]; st.Track != wantTrack {
+			t.Errorf("step %d track = %q, want %q", i, st.Track, wantTrack)
+		}
+	}
+}
+
+func TestRunBidirectionalSearch_MatchesBFSHops(t *testing.T) {
+	artists, cat := fixtureGraph(3000, 3, 7)
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 10; i++ {
+		start, target := artists[rng.Intn(len(artists))], artists[rng.Intn(len(artists))]
+
+		cat.reset(artists)
+		_, bfs, okBFS := RunSearchOpts(start, target, SearchOptions{MaxDepth: -1, Catalog: cat})
+		cat.reset(artists)
+		_, bi, okBi := RunBidirectionalSearch(start, target, SearchOptions{MaxDepth: -1, Catalog: cat})
+
+		if okBFS != okBi {
+			t.Fatalf("%s -> %s: bfs found %v, bidirectional %v", start.Name, target.Name, okBFS, okBi)
+		}
+		// BFS orders its queue by popularity, so it is not always shortest.
+		if len(bi) > len(bfs) {
+			t.Errorf("%s -> %s: bidirectional path %v longer than bfs %v", start.Name, target.Name, bi, bfs)
+		}
+	}
+}
+
+func TestRunBidirectionalSearch_DepthLimit(t *testing.T) {
+	a, b, c, d := CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c"), CreateArtists("D", "d")
+	cat := newFixtureCatalog()
+	cat.collab(a, b, "ab")
+	cat.collab(b, c, "bc")
+	cat.collab(c, d, "cd")
+
+	h, _, ok := RunBidirectionalSearch(a, d, SearchOptions{MaxDepth: 2, Catalog: cat})
+	if ok {
+		t.Fatalf("expected no path within 2 hops")
+	}
+	if h.Diagnosis == nil || h.Diagnosis.Reason != StopDepthLimit {
+		t.Fatalf("diagnosis = %+v, want %s", h.Diagnosis, StopDepthLimit)
+	}
+	if _, _, ok := RunBidirectionalSearch(a, d, SearchOptions{MaxDepth: 3, Catalog: cat}); !ok {
+		t.Fatalf("expected a path within 3 hops")
+	}
+}
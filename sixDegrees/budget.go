@@ -0,0 +1,94 @@
+package sixdegrees
+
+import (
+	"time"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+// Budget caps the work one search may do, so that unbounded searches between
+// obscure artists end in reasonable time without burning the rate limit.
+// Zero fields are unlimited. A search that runs out stops where it is and
+// reports StopBudget in its Diagnosis, with its stats so far.
+type Budget struct {
+	APICalls int           // Spotify requests
+	Expanded int           // artists whose tracks are fetched
+	Duration time.Duration // wall time
+}
+
+// Limits of a Budget, as named in Diagnosis.Budget.
+const (
+	BudgetAPICalls = "api_calls"
+	BudgetExpanded = "expanded"
+	BudgetDuration = "duration"
+)
+
+// IsZero reports whether b sets no limit.
+func (b Budget) IsZero() bool { return b == Budget{} }
+
+// budgetMeter tracks what a search has spent against its Budget. The legs of
+// a waypoint search share one meter.
+type budgetMeter struct {
+	Budget
+	began    time.Time
+	calls    spotify.CallStats // process-wide counters when the search began
+	expanded int
+	spent    string // the limit that ran out; empty while within budget
+}
+
+// newBudgetMeter starts metering b, or returns nil when b sets no limit.
+func newBudgetMeter(b Budget) *budgetMeter {
+	if b.IsZero() {
+		return nil
+	}
+	return &budgetMeter{Budget: b, began: time.Now(), calls: spotify.Stats()}
+}
+
+// exhausted returns the limit that ran out, or "" while within budget. Once
+// a limit runs out it stays out. Spotify requests are counted process-wide,
+// so searches running at the same time spend each other's call budgets.
+func (m *budgetMeter) exhausted() string {
+	if m == nil || m.spent != "" {
+		return m.limit()
+	}
+	switch {
+	case m.Expanded > 0 && m.expanded >= m.Expanded:
+		m.spent = BudgetExpanded
+	case m.Duration > 0 && time.Since(m.began) >= m.Duration:
+		m.spent = BudgetDuration
+	case m.APICalls > 0 && totalCalls(spotify.Stats().Since(m.calls)) >= m.APICalls:
+		m.spent = BudgetAPICalls
+	}
+	return m.spent
+}
+
+// limit returns the limit that has run out without checking again.
+func (m *budgetMeter) limit() string {
+	if m == nil {
+		return ""
+	}
+	return m.spent
+}
+
+func totalCalls(c spotify.CallStats) int {
+	n := 0
+	for _, v := range c.Calls {
+		n += v
+	}
+	return n
+}
+
+// outOfBudget reports whether the search filling h has run out of budget,
+// logging the limit the first time it does.
+func (h *Helper) outOfBudget() bool {
+	if h.budget == nil {
+		return false
+	}
+	was := h.budget.spent
+	limit := h.budget.exhausted()
+	if limit != "" && was == "" {
+		h.logger().Info("search budget exhausted", "limit", limit,
+			"expanded", h.budget.expanded, "elapsed", time.Since(h.budget.began).Round(time.Millisecond))
+	}
+	return limit != ""
+}
@@ -0,0 +1,53 @@
+package sixdegrees
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunSearchOpts_StopsAtExpansionBudget(t *testing.T) {
+	artists, cat := fixtureGraph(200, 4, 1)
+	nowhere := CreateArtists("nowhere", "nowhere")
+	h, _, ok := RunSearchOpts(artists[0], nowhere, SearchOptions{MaxDepth: -1, Catalog: cat, Budget: Budget{Expanded: 10}})
+	if ok {
+		t.Fatal("expected no path to an unconnected artist")
+	}
+	if h.Stats.Expanded != 10 || len(cat.fetched) != 10 {
+		t.Fatalf("expected 10 expansions, got %d (%d fetched)", h.Stats.Expanded, len(cat.fetched))
+	}
+	d := h.Diagnosis
+	if d == nil || d.Reason != StopBudget || d.Budget != BudgetExpanded || d.Frontier == 0 {
+		t.Fatalf("expected an expansion budget diagnosis with a frontier, got %+v", d)
+	}
+	for _, m := range d.Closest {
+		if len(m.Path) != m.Hops {
+			t.Fatalf("near miss %s has %d hops but a %d-step path", m.Name, m.Hops, len(m.Path))
+		}
+	}
+}
+
+func TestRunSearchOpts_StopsAtTimeBudget(t *testing.T) {
+	A, _, _, D, cat := diamond()
+	h, _, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Budget: Budget{Duration: time.Nanosecond}})
+	if ok || h.Diagnosis == nil || h.Diagnosis.Budget != BudgetDuration || len(cat.fetched) != 0 {
+		t.Fatalf("expected the search to stop before fetching, got ok=%v %+v fetched %v", ok, h.Diagnosis, cat.fetched)
+	}
+}
+
+func TestRunAStarSearch_StopsAtExpansionBudget(t *testing.T) {
+	artists, cat := fixtureGraph(200, 4, 1)
+	nowhere := CreateArtists("nowhere", "nowhere")
+	h, _, ok := RunAStarSearch(artists[0], nowhere, nil, nil, SearchOptions{MaxDepth: -1, Catalog: cat, Budget: Budget{Expanded: 5}})
+	if ok || h.Stats.Expanded != 5 || h.Diagnosis == nil || h.Diagnosis.Reason != StopBudget {
+		t.Fatalf("expected a search stopped after 5 expansions, got ok=%v expanded %d %+v", ok, h.Stats.Expanded, h.Diagnosis)
+	}
+}
+
+func TestRunSearchOpts_WaypointLegsShareBudget(t *testing.T) {
+	A, B, _, D, cat := diamond()
+	c := &Constraints{Waypoints: []*Artists{B}}
+	h, _, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c, Budget: Budget{Expanded: 1}})
+	if ok || h.Stats.Expanded != 1 || h.Diagnosis == nil || h.Diagnosis.Reason != StopBudget {
+		t.Fatalf("expected one expansion across both legs, got ok=%v expanded %d %+v", ok, h.Stats.Expanded, h.Diagnosis)
+	}
+}
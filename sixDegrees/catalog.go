@@ -0,0 +1,55 @@
+package sixdegrees
+
+import (
+	"time"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+// Catalog supplies an artist's tracks on demand. Searches call Expand only for
+// artists they actually explore, so a lazy search fetches no more than it
+// needs. Implementations must leave artists that already have tracks alone.
+type Catalog interface {
+	Expand(a *Artists, h *Helper) error
+}
+
+// SpotifyCatalog expands artists by fetching their albums and album tracks
+// from the Spotify API. Collaborators it discovers are registered in h, and
+// each fetch is logged at debug level through h's search logger.
+type SpotifyCatalog struct {
+	Fetch spotify.FetchOptions
+}
+
+func (c SpotifyCatalog) Expand(a *Artists, h *Helper) error {
+	found := false
+	return enrichArtist(a, h, "", &found, c.Fetch)
+}
+
+// expand fills in a's tracks through opts.Catalog, or through the built-in
+// Spotify fetch when no catalog is set. The built-in fetch stops early once
+// it reaches target.
+// Each expansion is counted in h.Stats and written to opts.Trace. Once the
+// search is out of budget, artists are left unexpanded.
+func (opts SearchOptions) expand(a *Artists, h *Helper, target string, found *bool) error {
+	if len(a.Tracks) > 0 || *found || h.outOfBudget() {
+		return nil
+	}
+	began := time.Now()
+	var err error
+	if opts.Catalog != nil {
+		err = opts.Catalog.Expand(a, h)
+	} else {
+		err = enrichArtist(a, h, target, found, opts.Fetch)
+	}
+	took := time.Since(began)
+	h.Stats.Expanded++
+	if h.budget != nil {
+		h.budget.expanded++
+	}
+	h.Stats.TracksFetched += len(a.Tracks)
+	h.Stats.addPhase("expand", took)
+	if opts.Trace != nil {
+		trace(opts.Trace, h.Stats.Expanded, a, h.DistTo[a.Name], took, err)
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package sixdegrees
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// maxPropagationRounds bounds label propagation, which usually settles in a
+// handful of rounds but may oscillate on some graphs.
+const maxPropagationRounds = 50
+
+// Groups assigns every artist of a CollabGraph to one group, numbered from
+// 0 in order of decreasing size (ties by the lowest artist ID in them).
+type Groups struct {
+	Of    map[string]int // group of each artist ID
+	Sizes []int          // artists in each group
+}
+
+// Same reports whether a and b are both grouped and in the same group, and
+// whether both were grouped at all.
+func (gr Groups) Same(a, b string) (same, known bool) {
+	x, okA := gr.Of[a]
+	y, okB := gr.Of[b]
+	return okA && okB && x == y, okA && okB
+}
+
+// unionFind is a disjoint-set forest with union by size and path halving.
+type unionFind struct {
+	parent, size []int
+}
+
+func newUnionFind(n int) *unionFind {
+	u := &unionFind{parent: make([]int, n), size: make([]int, n)}
+	for i := range u.parent {
+		u.parent[i], u.size[i] = i, 1
+	}
+	return u
+}
+
+func (u *unionFind) find(x int) int {
+	for u.parent[x] != x {
+		u.parent[x] = u.parent[u.parent[x]]
+		x = u.parent[x]
+	}
+	return x
+}
+
+func (u *unionFind) union(x, y int) {
+	x, y = u.find(x), u.find(y)
+	if x == y {
+		return
+	}
+	if u.size[x] < u.size[y] {
+		x, y = y, x
+	}
+	u.parent[y] = x
+	u.size[x] += u.size[y]
+}
+
+// Components groups artists connected by any chain of collaborations.
+func (g *CollabGraph) Components() Groups {
+	u := newUnionFind(len(g.ids))
+	for v, ws := range g.adj {
+		for _, w := range ws {
+			u.union(v, w)
+		}
+	}
+	labels := make([]int, len(g.ids))
+	for v := range labels {
+		labels[v] = u.find(v)
+	}
+	return g.groups(labels)
+}
+
+// Communities groups artists into densely collaborating communities by
+// weighted label propagation: every artist repeatedly adopts the label its
+// collaborators share the most tracks under, until no label changes. seed
+// fixes the visiting order, so the same graph and seed give the same
+// communities. A community never spans two components.
+func (g *CollabGraph) Communities(seed int64) Groups {
+	n := len(g.ids)
+	labels := make([]int, n)
+	for v := range labels {
+		labels[v] = v
+	}
+	rng := rand.New(rand.NewSource(seed))
+	weight := make(map[int]int)
+	for round := 0; round < maxPropagationRounds; round++ {
+		changed := false
+		for _, v := range rng.Perm(n) {
+			if len(g.adj[v]) == 0 {
+				continue
+			}
+			for k := range weight {
+				delete(weight, k)
+			}
+			for i, w := range g.adj[v] {
+				weight[labels[w]] += g.shared[v][i]
+			}
+			best, bestWeight := labels[v], weight[labels[v]]
+			for label, wt := range weight {
+				if wt > bestWeight || (wt == bestWeight && label < best && best != labels[v]) {
+					best, bestWeight = label, wt
+				}
+			}
+			if best != labels[v] {
+				labels[v] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return g.groups(labels)
+}
+
+// groups renumbers arbitrary per-node labels into Groups.
+func (g *CollabGraph) groups(labels []int) Groups {
+	members := make(map[int][]int)
+	for v, l := range labels {
+		members[l] = append(members[l], v)
+	}
+	type group struct {
+		first string
+		nodes []int
+	}
+	list := make([]group, 0, len(members))
+	for _, nodes := range members {
+		first := g.ids[nodes[0]]
+		for _, v := range nodes[1:] {
+			if g.ids[v] < first {
+				first = g.ids[v]
+			}
+		}
+		list = append(list, group{first, nodes})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].nodes) != len(list[j].nodes) {
+			return len(list[i].nodes) > len(list[j].nodes)
+		}
+		return list[i].first < list[j].first
+	})
+	out := Groups{Of: make(map[string]int, len(labels)), Sizes: make([]int, len(list))}
+	for i, gr := range list {
+		out.Sizes[i] = len(gr.nodes)
+		for _, v := range gr.nodes {
+			out.Of[g.ids[v]] = i
+		}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package sixdegrees
+
+import "testing"
+
+// cliques builds two tight trios joined by one weak collaboration, and a
+// separate pair.
+func cliques() *CollabGraph {
+	g := NewCollabGraph()
+	for _, trio := range [][3]string{{"a1", "a2", "a3"}, {"b1", "b2", "b3"}} {
+		g.AddEdge(trio[0], trio[1], 5)
+		g.AddEdge(trio[1], trio[2], 5)
+		g.AddEdge(trio[0], trio[2], 5)
+	}
+	g.AddEdge("a3", "b1", 1)
+	g.AddEdge("x", "y", 1)
+	return g
+}
+
+func TestCollabGraph_Components(t *testing.T) {
+	c := cliques().Components()
+	if len(c.Sizes) != 2 || c.Sizes[0] != 6 || c.Sizes[1] != 2 {
+		t.Fatalf("expected components of 6 and 2, got %v", c.Sizes)
+	}
+	if same, known := c.Same("a1", "b3"); !same || !known {
+		t.Fatalf("a1 and b3 should share a component")
+	}
+	if same, known := c.Same("a1", "x"); same || !known {
+		t.Fatalf("a1 and x should be in different components")
+	}
+	if _, known := c.Same("a1", "nobody"); known {
+		t.Fatalf("an artist outside the graph should be unknown")
+	}
+}
+
+func TestCollabGraph_Communities(t *testing.T) {
+	g := cliques()
+	c := g.Communities(1)
+	if len(c.Sizes) != 3 || c.Sizes[0] != 3 || c.Sizes[1] != 3 || c.Sizes[2] != 2 {
+		t.Fatalf("expected communities of 3, 3 and 2, got %v (%v)", c.Sizes, c.Of)
+	}
+	if same, _ := c.Same("a1", "a3"); !same {
+		t.Fatalf("a1 and a3 should share a community: %v", c.Of)
+	}
+	if same, _ := c.Same("a3", "b1"); same {
+		t.Fatalf("the weak link should not merge the trios: %v", c.Of)
+	}
+	again := g.Communities(1)
+	for id, l := range c.Of {
+		if again.Of[id] != l {
+			t.Fatalf("the same seed should give the same communities: %v vs %v", c.Of, again.Of)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package sixdegrees
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StrategyTerm is one weighted component of a CompositeStrategy.
+type StrategyTerm struct {
+	Coef     float64
+	Name     string // used when printing the composite
+	Strategy WeightStrategy
+}
+
+// CompositeStrategy weighs an edge as the coefficient-weighted sum of its
+// terms, e.g. 0.7*collab + 0.3*recency.
+type CompositeStrategy struct {
+	Terms []StrategyTerm
+}
+
+func (c CompositeStrategy) Weight(target, from, to *Artists, ctx EdgeContext) float64 {
+	total := 0.0
+	for _, t := range c.Terms {
+		total += t.Coef * t.Strategy.Weight(target, from, to, ctx)
+	}
+	return total
+}
+
+// String renders the composite in the syntax ParseStrategy accepts.
+func (c CompositeStrategy) String() string {
+	parts := make([]string, len(c.Terms))
+	for i, t := range c.Terms {
+		parts[i] = strconv.FormatFloat(t.Coef, 'g', -1, 64) + "*" + t.Name
+	}
+	return strings.Join(parts, "+")
+}
+
+// ParseStrategy builds a strategy from an expression such as "collab" or
+// "0.7*collab+0.3*recency". Terms are built-in strategy names with an
+// optional non-negative coefficient; a lone name returns that strategy as is.
+func ParseStrategy(expr string) (WeightStrategy, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("empty strategy expression")
+	}
+	var c CompositeStrategy
+	for _, raw := range strings.Split(expr, "+") {
+		term := strings.TrimSpace(raw)
+		coef, name := 1.0, term
+		if i := strings.Index(term, "*"); i >= 0 {
+			v, err := strconv.ParseFloat(strings.TrimSpace(term[:i]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("strategy term %q: bad coefficient", term)
+			}
+			coef, name = v, strings.TrimSpace(term[i+1:])
+		}
+		t, err := newStrategyTerm(name, coef)
+		if err != nil {
+			return nil, err
+		}
+		c.Terms = append(c.Terms, t)
+	}
+	if len(c.Terms) == 1 && c.Terms[0].Coef == 1 {
+		return c.Terms[0].Strategy, nil
+	}
+	return c, nil
+}
+
+// LoadStrategyFile reads a composite strategy from a JSON file mapping
+// built-in strategy names to coefficients, e.g. {"collab": 0.7, "recency": 0.3}.
+func LoadStrategyFile(path string) (WeightStrategy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var coefs map[string]float64
+	if err := json.Unmarshal(b, &coefs); err != nil {
+		return nil, fmt.Errorf("strategy file %s: %w", path, err)
+	}
+	if len(coefs) == 0 {
+		return nil, fmt.Errorf("strategy file %s: no strategies", path)
+	}
+	names := make([]string, 0, len(coefs))
+	for name := range coefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var c CompositeStrategy
+	for _, name := range names {
+		t, err := newStrategyTerm(name, coefs[name])
+		if err != nil {
+			return nil, fmt.Errorf("strategy file %s: %w", path, err)
+		}
+		c.Terms = append(c.Terms, t)
+	}
+	return c, nil
+}
+
+func newStrategyTerm(name string, coef float64) (StrategyTerm, error) {
+	if coef < 0 || math.IsNaN(coef) || math.IsInf(coef, 0) {
+		return StrategyTerm{}, fmt.Errorf("strategy %q: coefficient must be a non-negative number", name)
+	}
+	s, err := StrategyByName(name)
+	if err != nil {
+		return StrategyTerm{}, err
+	}
+	return StrategyTerm{Coef: coef, Name: strings.ToLower(strings.TrimSpace(name)), Strategy: s}, nil
+}
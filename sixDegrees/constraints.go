@@ -0,0 +1,171 @@
+package sixdegrees
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Constraints restricts which artists a path may pass through. They apply to
+// intermediate artists only; the start, the target and the waypoints are
+// always allowed.
+type Constraints struct {
+	ExcludeIDs []string   // Spotify IDs of artists the path must avoid
+	Waypoints  []*Artists // artists the path must visit, in order
+
+	// AllowGenres, when set, requires at least one genre containing one of
+	// these (case-insensitive), so "hip hop" also admits "southern hip hop".
+	// Artists without genres are rejected. DenyGenres rejects any artist
+	// with a matching genre.
+	AllowGenres []string
+	DenyGenres  []string
+
+	MinPopularity float64 // 0 for no lower bound
+	MaxPopularity float64 // 0 for no upper bound
+
+	// FromYear and ToYear limit connections to tracks released in that
+	// range (inclusive; 0 leaves a side open). Undated tracks are not used
+	// once either bound is set.
+	FromYear, ToYear int
+
+	avoid map[string]bool // artist names already used by earlier waypoint legs
+}
+
+// Validate reports a popularity or year range whose upper bound is below its
+// lower bound, which no artist or track could satisfy.
+func (c *Constraints) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.MaxPopularity > 0 && c.MaxPopularity < c.MinPopularity {
+		return fmt.Errorf("max popularity %.0f is below min popularity %.0f", c.MaxPopularity, c.MinPopularity)
+	}
+	if c.ToYear > 0 && c.ToYear < c.FromYear {
+		return fmt.Errorf("to year %d is before from year %d", c.ToYear, c.FromYear)
+	}
+	return nil
+}
+
+// Allows reports whether a may appear inside a path to target.
+func (c *Constraints) Allows(a, target *Artists) bool {
+	if c == nil || a == nil || (target != nil && a.Name == target.Name) {
+		return true
+	}
+	if c.avoid[a.Name] {
+		return false
+	}
+	for _, id := range c.ExcludeIDs {
+		if id != "" && a.ID == id {
+			return false
+		}
+	}
+	if a.Popularity < c.MinPopularity || (c.MaxPopularity > 0 && a.Popularity > c.MaxPopularity) {
+		return false
+	}
+	if len(c.DenyGenres) > 0 && hasGenre(a, c.DenyGenres) {
+		return false
+	}
+	if len(c.AllowGenres) > 0 && !hasGenre(a, c.AllowGenres) {
+		return false
+	}
+	return true
+}
+
+// AllowsTrack reports whether t may connect two artists on a path.
+func (c *Constraints) AllowsTrack(t Track) bool {
+	if c == nil || (c.FromYear == 0 && c.ToYear == 0) {
+		return true
+	}
+	if t.Year == 0 {
+		return false
+	}
+	return (c.FromYear == 0 || t.Year >= c.FromYear) && (c.ToYear == 0 || t.Year <= c.ToYear)
+}
+
+// hasGenre reports whether any of a's genres contains one of wanted.
+func hasGenre(a *Artists, wanted []string) bool {
+	for g := range a.Genres {
+		g = strings.ToLower(g)
+		for _, w := range wanted {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" && strings.Contains(g, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// constrainedStrategy makes edges into disallowed artists unusable.
+type constrainedStrategy struct {
+	WeightStrategy
+	c *Constraints
+}
+
+func (s constrainedStrategy) Weight(target, from, to *Artists, ctx EdgeContext) float64 {
+	if !s.c.Allows(to, target) {
+		return math.Inf(1)
+	}
+	return s.WeightStrategy.Weight(target, from, to, ctx)
+}
+
+// legSearch finds a path between two artists under opts.
+type legSearch func(from, to *Artists, opts SearchOptions) (*Helper, []PathStep, bool)
+
+// searchViaWaypoints solves start -> waypoints... -> target as a chain of
+// sub-searches, one per leg. Legs never share artists other than the stops
+// joining them, and MaxDepth applies to each leg separately.
+func searchViaWaypoints(start, target *Artists, opts SearchOptions, leg legSearch) (*Helper, []PathStep, bool) {
+	if opts.meter == nil {
+		opts.meter = newBudgetMeter(opts.Budget)
+	}
+	c := *opts.Constraints
+	stops := append([]*Artists{start}, c.Waypoints...)
+	stops = append(stops, target)
+	c.Waypoints = nil
+	avoid := make(map[string]bool, len(c.avoid))
+	for name := range c.avoid {
+		avoid[name] = true
+	}
+
+	combined := NewHelper()
+	combined.DistTo[start.Name] = 0
+	var all []PathStep
+	for i := 0; i+1 < len(stops); i++ {
+		// A leg may use neither earlier legs' artists nor later stops.
+		legAvoid := make(map[string]bool, len(avoid)+len(stops))
+		for name := range avoid {
+			legAvoid[name] = true
+		}
+		for _, later := range stops[i+2:] {
+			legAvoid[later.Name] = true
+		}
+		legC := c
+		legC.avoid = legAvoid
+		legOpts := opts
+		legOpts.Constraints = &legC
+
+		h, steps, ok := leg(stops[i], stops[i+1], legOpts)
+		combined.MergeArtists(h)
+		combined.Stats.Add(h.Stats)
+		if !ok {
+			combined.Diagnosis = h.Diagnosis
+			return combined, nil, false
+		}
+		for _, st := range steps {
+			combined.Prev[st.To] = st.From
+			combined.Evidence[st.To] = st.Track
+			combined.EvidenceID[st.To] = st.TrackID
+			combined.EvidenceYear[st.To] = st.Year
+			combined.EvidenceGuest[st.To] = st.Guest
+			combined.DistTo[st.To] = combined.DistTo[st.From] + 1
+			avoid[st.From] = true
+		}
+		all = append(all, steps...)
+	}
+	return combined, all, true
+}
+
+// hasWaypoints reports whether opts asks for a path through waypoints.
+func (opts SearchOptions) hasWaypoints() bool {
+	return opts.Constraints != nil && len(opts.Constraints.Waypoints) > 0
+}
@@ -0,0 +1,164 @@
+package sixdegrees
+
+import "testing"
+
+func TestConstraints_Allows(t *testing.T) {
+	target := &Artists{Name: "T", ID: "t"}
+	c := &Constraints{
+		ExcludeIDs:    []string{"drake"},
+		AllowGenres:   []string{"Hip Hop"},
+		DenyGenres:    []string{"pop"},
+		MinPopularity: 10,
+		MaxPopularity: 90,
+	}
+	cases := []struct {
+		a    *Artists
+		want bool
+	}{
+		{&Artists{Name: "ok", Popularity: 50, Genres: map[string]int{"southern hip hop": 1}}, true},
+		{&Artists{Name: "Drake", ID: "drake", Popularity: 50, Genres: map[string]int{"hip hop": 1}}, false},
+		{&Artists{Name: "jazz", Popularity: 50, Genres: map[string]int{"jazz": 1}}, false},
+		{&Artists{Name: "nogenre", Popularity: 50}, false},
+		{&Artists{Name: "poprap", Popularity: 50, Genres: map[string]int{"pop rap": 1, "hip hop": 1}}, false},
+		{&Artists{Name: "tiny", Popularity: 5, Genres: map[string]int{"hip hop": 1}}, false},
+		{&Artists{Name: "huge", Popularity: 95, Genres: map[string]int{"hip hop": 1}}, false},
+		{&Artists{Name: "T", Popularity: 99}, true}, // the target is always allowed
+	}
+	for _, tc := range cases {
+		if got := c.Allows(tc.a, target); got != tc.want {
+			t.Errorf("Allows(%s) = %v, want %v", tc.a.Name, got, tc.want)
+		}
+	}
+	var none *Constraints
+	if !none.Allows(cases[1].a, target) {
+		t.Fatalf("nil constraints should allow everything")
+	}
+}
+
+// diamond builds A-B-D and A-C-D, where A-B is the stronger tie.
+func diamond() (A, B, C, D *Artists, cat *fixtureCatalog) {
+	A, B, C, D = CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c"), CreateArtists("D", "d")
+	cat = newFixtureCatalog()
+	cat.collab(A, B, "ab1")
+	cat.collab(A, B, "ab2")
+	cat.collab(B, D, "bd")
+	cat.collab(A, C, "ac")
+	cat.collab(C, D, "cd")
+	return
+}
+
+func TestRunSearchOpts_AvoidsExcludedArtists(t *testing.T) {
+	A, _, _, D, cat := diamond()
+	c := &Constraints{ExcludeIDs: []string{"b"}}
+	_, path, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || len(path) != 3 || path[1] != "C" {
+		t.Fatalf("expected A-C-D avoiding B, got %v (ok=%v)", path, ok)
+	}
+}
+
+func TestRunSearchOpts_Waypoints(t *testing.T) {
+	A, B, C, D, cat := diamond()
+	c := &Constraints{Waypoints: []*Artists{C}}
+	h, path, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || len(path) != 3 || path[1] != "C" {
+		t.Fatalf("expected a path through C, got %v (ok=%v)", path, ok)
+	}
+	if steps := h.Steps(path); steps[0].Track != "ac" || steps[1].Track != "cd" {
+		t.Fatalf("unexpected evidence %+v", steps)
+	}
+
+	// B then C: the second leg may not go back through A.
+	cat.reset([]*Artists{A, B, C, D})
+	cat.collab(B, C, "bc")
+	c = &Constraints{Waypoints: []*Artists{B, C}}
+	_, path, ok = RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || len(path) != 4 || path[1] != "B" || path[2] != "C" {
+		t.Fatalf("expected A-B-C-D, got %v (ok=%v)", path, ok)
+	}
+}
+
+func TestWeightedSearches_HonorConstraints(t *testing.T) {
+	A, B, C, D, cat := diamond()
+	B.Popularity, C.Popularity = 80, 20
+	c := &Constraints{MaxPopularity: 50}
+	all := []*Artists{A, B, C, D}
+
+	_, steps, ok := RunWeightedSearch(A, D, CollabStrengthStrategy{}, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || len(steps) != 2 || steps[0].To != "C" {
+		t.Fatalf("weighted: expected A-C-D, got %+v", steps)
+	}
+
+	cat.reset(all)
+	_, steps, ok = RunAStarSearch(A, D, CollabStrengthStrategy{}, nil, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || len(steps) != 2 || steps[0].To != "C" {
+		t.Fatalf("astar: expected A-C-D, got %+v", steps)
+	}
+
+	cat.reset(all)
+	c = &Constraints{Waypoints: []*Artists{C}}
+	_, steps, ok = RunAStarSearch(A, D, CollabStrengthStrategy{}, nil, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || len(steps) != 2 || steps[0].To != "C" || steps[1].From != "C" {
+		t.Fatalf("astar waypoint: expected A-C-D, got %+v", steps)
+	}
+}
+
+func TestConstraints_AllowsTrack(t *testing.T) {
+	c := &Constraints{FromYear: 1990, ToYear: 1999}
+	cases := []struct {
+		year int
+		want bool
+	}{{1990, true}, {1999, true}, {1989, false}, {2000, false}, {0, false}}
+	for _, tc := range cases {
+		if got := c.AllowsTrack(Track{Year: tc.year}); got != tc.want {
+			t.Errorf("AllowsTrack(%d) = %v, want %v", tc.year, got, tc.want)
+		}
+	}
+	if !(&Constraints{}).AllowsTrack(Track{}) {
+		t.Fatalf("no bounds should allow undated tracks")
+	}
+}
+
+func TestSearches_HonorYearRange(t *testing.T) {
+	A, B, C, D, cat := diamond()
+	all := []*Artists{A, B, C, D}
+	// Only the A-C-D side was released in the nineties.
+	for _, a := range all {
+		for i := range cat.tracks[a.Name] {
+			switch cat.tracks[a.Name][i].Name {
+			case "ac", "cd":
+				cat.tracks[a.Name][i].Year = 1995
+			default:
+				cat.tracks[a.Name][i].Year = 2010
+			}
+		}
+	}
+	c := &Constraints{ToYear: 1999}
+
+	h, path, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || len(path) != 3 || path[1] != "C" {
+		t.Fatalf("bfs: expected A-C-D, got %v (ok=%v)", path, ok)
+	}
+	if steps := h.Steps(path); steps[0].Year != 1995 || steps[1].Year != 1995 {
+		t.Fatalf("bfs: expected evidence years, got %+v", steps)
+	}
+
+	cat.reset(all)
+	_, steps, ok := RunWeightedSearch(A, D, CollabStrengthStrategy{}, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || len(steps) != 2 || steps[0].To != "C" || steps[0].Year != 1995 {
+		t.Fatalf("weighted: expected A-C-D, got %+v", steps)
+	}
+}
+
+func TestConstraints_Validate(t *testing.T) {
+	for _, c := range []*Constraints{nil, {MinPopularity: 20, MaxPopularity: 60}, {MinPopularity: 20}, {FromYear: 2010, ToYear: 2010}, {FromYear: 2010}} {
+		if err := c.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error %v", c, err)
+		}
+	}
+	if err := (&Constraints{MinPopularity: 60, MaxPopularity: 20}).Validate(); err == nil {
+		t.Errorf("expected an inverted popularity range to be rejected")
+	}
+	if err := (&Constraints{FromYear: 2020, ToYear: 2010}).Validate(); err == nil {
+		t.Errorf("expected an inverted year range to be rejected")
+	}
+}
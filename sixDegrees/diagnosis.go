@@ -0,0 +1,172 @@
+package sixdegrees
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Reasons a search can end without a path.
+const (
+	StopDepthLimit  = "depth_limit" // artists at the depth limit were not searched further
+	StopExhausted   = "exhausted"   // every reachable artist was searched
+	StopConstrained = "constraints" // artists were reached but the constraints rule out every path
+	StopBudget      = "budget"      // the search's Budget ran out
+)
+
+// Weights of the parts of a near miss's score.
+const (
+	nearMissGenreWeight      = 0.7
+	nearMissPopularityWeight = 0.3
+)
+
+// DefaultNearMisses is how many of the closest artists a Diagnosis lists.
+const DefaultNearMisses = 5
+
+// Diagnosis explains why a search found no path.
+type Diagnosis struct {
+	Reason string `json:"reason"`
+
+	// From and To are the ends of the search that failed; with waypoints
+	// that is the leg that failed rather than the whole route.
+	From     string `json:"from"`
+	To       string `json:"to"`
+	MaxDepth int    `json:"max_depth"`
+
+	Budget string `json:"budget,omitempty"` // the Budget limit that ran out, with StopBudget
+
+	ByDepth []int `json:"by_depth"` // artists reached at each hop from From
+
+	// Frontier counts the artists the search reached but did not search
+	// from: those at the depth limit, or those still queued when the budget
+	// ran out.
+	Frontier int `json:"frontier"`
+
+	// Closest lists the reached artists most like To, best first; when the
+	// budget ran out, only artists on the frontier, where the search would
+	// have gone on.
+	Closest []NearMiss `json:"closest,omitempty"`
+}
+
+// NearMiss is an artist the search reached that resembles its target.
+type NearMiss struct {
+	Name string `json:"artist"`
+	ID   string `json:"id,omitempty"`
+	Hops int    `json:"hops"`
+
+	// Score mixes the parts below, each 0..1 with higher meaning more alike.
+	Score         float64 `json:"score"`
+	Genre         float64 `json:"genre"`          // genre overlap with the target
+	PopularityFit float64 `json:"popularity_fit"` // closeness to the target's popularity
+
+	Path []PathStep `json:"path"`
+}
+
+// Reached is how many artists the search reached, start included.
+func (d *Diagnosis) Reached() int {
+	n := 0
+	for _, c := range d.ByDepth {
+		n += c
+	}
+	return n
+}
+
+// Summary says why the search stopped, as a clause following "the search".
+func (d *Diagnosis) Summary() string {
+	switch d.Reason {
+	case StopDepthLimit:
+		return fmt.Sprintf("stopped at the depth limit of %d with %d artists left unexpanded", d.MaxDepth, d.Frontier)
+	case StopBudget:
+		return fmt.Sprintf("ran out of its %s budget with %d artists reached and %d still queued", budgetNames[d.Budget], d.Reached(), d.Frontier)
+	case StopConstrained:
+		return fmt.Sprintf("reached %d artists but the constraints rule out every path to %s", d.Reached(), d.To)
+	default:
+		return fmt.Sprintf("ran out of artists after reaching all %d connected to %s", d.Reached(), d.From)
+	}
+}
+
+// Advice suggests what to change before searching again.
+func (d *Diagnosis) Advice() string {
+	switch d.Reason {
+	case StopDepthLimit:
+		return "raise the depth limit"
+	case StopBudget:
+		return "raise the " + budgetNames[d.Budget] + " budget"
+	case StopConstrained:
+		return "loosen the constraints or try another strategy"
+	default:
+		return "fetch more releases per artist, include appears-on albums, or loosen the constraints"
+	}
+}
+
+// budgetNames describes each Budget limit for Summary and Advice.
+var budgetNames = map[string]string{
+	BudgetAPICalls: "API request",
+	BudgetExpanded: "expanded artist",
+	BudgetDuration: "time",
+}
+
+// diagnose explains a failed search from start to target whose state is in
+// h. frontier names the artists still queued when the search stopped.
+func diagnose(h *Helper, start, target *Artists, maxDepth int, frontier []string) *Diagnosis {
+	d := &Diagnosis{
+		Reason:   StopExhausted,
+		From:     start.Name,
+		To:       target.Name,
+		MaxDepth: maxDepth,
+	}
+	for _, hops := range h.DistTo {
+		for len(d.ByDepth) <= hops {
+			d.ByDepth = append(d.ByDepth, 0)
+		}
+		d.ByDepth[hops]++
+		if maxDepth >= 0 && hops >= maxDepth {
+			d.Frontier++
+		}
+	}
+	if d.Frontier > 0 {
+		d.Reason = StopDepthLimit
+	}
+	candidates := h.DistTo
+	if d.Budget = h.budget.limit(); d.Budget != "" {
+		d.Reason = StopBudget
+		d.Frontier = len(frontier)
+		if len(frontier) > 0 {
+			candidates = make(map[string]int, len(frontier))
+			for _, name := range frontier {
+				candidates[name] = h.DistTo[name]
+			}
+		}
+	}
+
+	var near []NearMiss
+	for name, hops := range candidates {
+		a := h.ArtistMap[name]
+		if a == nil || name == start.Name || name == target.Name {
+			continue
+		}
+		m := NearMiss{
+			Name:          a.Name,
+			ID:            a.ID,
+			Hops:          hops,
+			Genre:         genreSimilarity(target.Genres, a.Genres),
+			PopularityFit: 1 - math.Min(1, math.Abs(target.Popularity-a.Popularity)/100),
+		}
+		m.Score = nearMissGenreWeight*m.Genre + nearMissPopularityWeight*m.PopularityFit
+		near = append(near, m)
+	}
+	sort.Slice(near, func(i, j int) bool {
+		if near[i].Score != near[j].Score {
+			return near[i].Score > near[j].Score
+		}
+		return near[i].Name < near[j].Name
+	})
+	if len(near) > DefaultNearMisses {
+		near = near[:DefaultNearMisses]
+	}
+	for i := range near {
+		near[i].Path = h.Steps(h.ReconstructPath(start.Name, near[i].Name))
+	}
+	d.Closest = near
+	return d
+}
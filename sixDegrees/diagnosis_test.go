@@ -0,0 +1,61 @@
+package sixdegrees
+
+import "testing"
+
+func TestRunSearchOpts_DiagnosesDepthLimit(t *testing.T) {
+	A, B, C, D, cat := diamond()
+	B.Genres = map[string]int{"jazz": 1}
+	C.Genres = map[string]int{"rock": 1}
+	D.Genres = map[string]int{"jazz": 1}
+	h, _, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: 1, Catalog: cat})
+	if ok {
+		t.Fatal("expected no path within depth 1")
+	}
+	d := h.Diagnosis
+	if d == nil || d.Reason != StopDepthLimit {
+		t.Fatalf("expected a depth limit diagnosis, got %+v", d)
+	}
+	if len(d.ByDepth) != 2 || d.ByDepth[0] != 1 || d.ByDepth[1] != 2 || d.Frontier != 2 {
+		t.Fatalf("expected 1 then 2 artists by depth with 2 unexpanded, got %v (frontier %d)", d.ByDepth, d.Frontier)
+	}
+	if len(d.Closest) != 2 || d.Closest[0].Name != "B" || d.Closest[0].Genre != 1 {
+		t.Fatalf("expected B, sharing D's genre, closest, got %+v", d.Closest)
+	}
+	if p := d.Closest[0].Path; len(p) != 1 || p[0].From != "A" || p[0].Track == "" {
+		t.Fatalf("expected the near miss's path from A, got %+v", p)
+	}
+}
+
+func TestRunSearchOpts_DiagnosesExhaustedFrontier(t *testing.T) {
+	A, _, _, _, cat := diamond()
+	E := CreateArtists("E", "e")
+	h, _, ok := RunSearchOpts(A, E, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if ok {
+		t.Fatal("expected no path to an unconnected artist")
+	}
+	d := h.Diagnosis
+	if d == nil || d.Reason != StopExhausted || d.Reached() != 4 || d.Frontier != 0 {
+		t.Fatalf("expected all 4 artists reached and none left, got %+v", d)
+	}
+}
+
+func TestRunSearchOpts_DiagnosesFailedWaypointLeg(t *testing.T) {
+	A, _, _, D, cat := diamond()
+	E := CreateArtists("E", "e")
+	c := &Constraints{Waypoints: []*Artists{E}}
+	h, _, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if ok {
+		t.Fatal("expected no path through an unconnected waypoint")
+	}
+	if d := h.Diagnosis; d == nil || d.From != "A" || d.To != "E" {
+		t.Fatalf("expected the A to E leg diagnosed, got %+v", d)
+	}
+}
+
+func TestRunAStarSearch_Diagnoses(t *testing.T) {
+	A, _, _, D, cat := diamond()
+	h, _, ok := RunAStarSearch(A, D, nil, nil, SearchOptions{MaxDepth: 1, Catalog: cat})
+	if ok || h.Diagnosis == nil || h.Diagnosis.Reason != StopDepthLimit {
+		t.Fatalf("expected a depth limit diagnosis, got ok=%v %+v", ok, h.Diagnosis)
+	}
+}
@@ -0,0 +1,201 @@
+package sixdegrees
+
+import "sort"
+
+// Neighborhood is the part of the collaboration graph within a few hops of
+// one artist, found by a breadth-first search with no target.
+type Neighborhood struct {
+	Seed  *Artists
+	Depth int // hops explored
+
+	// Helper holds the search state: DistTo is each reached artist's
+	// distance from Seed, and Prev with the evidence maps gives a shortest
+	// path to it.
+	Helper *Helper
+
+	// Edges holds every collaboration seen from an expanded artist, keyed
+	// from the expanded artist to its collaborator. Artists at distance
+	// Depth are not expanded, so edges among them are missing.
+	Edges map[EdgeKey]EdgeContext
+
+	// Budget names the limit of opts.Budget that cut the exploration
+	// short, leaving farther artists unreached; empty when it finished.
+	Budget string
+}
+
+// ExploreNeighborhood expands every artist within depth-1 hops of seed, so
+// that every artist within depth hops is reached. opts.MaxDepth is ignored;
+// opts.Constraints keeps disallowed artists and tracks out of the
+// neighborhood entirely.
+func ExploreNeighborhood(seed *Artists, depth int, opts SearchOptions) *Neighborhood {
+	h := newSearchHelper(opts)
+	defer h.Stats.measure("explore")()
+	if opts.Known != nil {
+		defer opts.Known.MergeArtists(h)
+	}
+	log := h.logger()
+	n := &Neighborhood{Seed: seed, Depth: depth, Helper: h, Edges: make(map[EdgeKey]EdgeContext)}
+	h.ArtistMap[seed.Name] = seed
+	h.DistTo[seed.Name] = 0
+
+	queue := []*Artists{seed}
+	noTarget := false
+	for len(queue) > 0 && !h.outOfBudget() {
+		current := queue[0]
+		queue = queue[1:]
+		h.Stats.Dequeued++
+		if h.DistTo[current.Name] >= depth {
+			continue
+		}
+		log.Debug("exploring", "artist", current.Name, "depth", h.DistTo[current.Name])
+		if err := opts.expand(current, h, "", &noTarget); err != nil {
+			log.Warn("expansion failed", "artist", current.Name, "err", err)
+		}
+		for _, c := range collaborators(current, opts.Constraints) {
+			next, ok := h.ArtistMap[c.artist.Name]
+			if !ok {
+				next = c.artist
+				h.ArtistMap[next.Name] = next
+			}
+			if !opts.Constraints.Allows(next, seed) {
+				continue
+			}
+			n.Edges[EdgeKey{From: current.Name, To: next.Name}] = c.ctx
+			if _, seen := h.DistTo[next.Name]; seen {
+				continue
+			}
+			h.DistTo[next.Name] = h.DistTo[current.Name] + 1
+			h.linkEdge(current.Name, next.Name, c.ctx)
+			queue = append(queue, next)
+		}
+	}
+	n.Budget = h.budget.limit()
+	return n
+}
+
+// Artist returns the reached artist with the given name, or nil.
+func (n *Neighborhood) Artist(name string) *Artists {
+	if _, ok := n.Helper.DistTo[name]; !ok {
+		return nil
+	}
+	return n.Helper.ArtistMap[name]
+}
+
+// PathTo returns the hops of a shortest path from the seed to name.
+func (n *Neighborhood) PathTo(name string) []PathStep {
+	if _, ok := n.Helper.DistTo[name]; !ok {
+		return nil
+	}
+	return n.Helper.Steps(n.Helper.ReconstructPath(n.Seed.Name, name))
+}
+
+// Exploration summarizes a neighborhood: how far its artists are from the
+// seed, who the seed works with most, and which neighbors lead furthest.
+type Exploration struct {
+	Seed  string `json:"seed"`
+	Depth int    `json:"depth"`
+
+	// Histogram holds the number of artists at each distance, from the
+	// seed itself at 0 to Depth.
+	Histogram []int `json:"histogram"`
+
+	TopCollaborators []Collaborator   `json:"top_collaborators"`
+	GenresByHop      []map[string]int `json:"genres_by_hop"` // artists per genre at each distance
+	Bridges          []Bridge         `json:"bridges"`
+}
+
+// Collaborator is an artist one hop from the seed with the tracks they
+// share.
+type Collaborator struct {
+	Name         string `json:"artist"`
+	ID           string `json:"id,omitempty"`
+	SharedTracks int    `json:"shared_tracks"`
+	Track        string `json:"track"` // one of the shared tracks
+}
+
+// Bridge is a neighbor of the seed rated by how much of the next hop it
+// leads to.
+type Bridge struct {
+	Name    string `json:"artist"`
+	Reaches int    `json:"reaches"` // artists two hops out it collaborates with
+	Only    int    `json:"only"`    // of those, reached through no other neighbor
+}
+
+// Explore summarizes the neighborhood, keeping the top collaborators and
+// bridges (all of them when top is negative).
+func (n *Neighborhood) Explore(top int) Exploration {
+	h := n.Helper
+	e := Exploration{
+		Seed:        n.Seed.Name,
+		Depth:       n.Depth,
+		Histogram:   make([]int, n.Depth+1),
+		GenresByHop: make([]map[string]int, n.Depth+1),
+	}
+	for d := range e.GenresByHop {
+		e.GenresByHop[d] = make(map[string]int)
+	}
+	for name, d := range h.DistTo {
+		e.Histogram[d]++
+		for g := range h.ArtistMap[name].Genres {
+			e.GenresByHop[d][g]++
+		}
+	}
+
+	// Neighbors of each artist two hops out, to find who leads to it.
+	via := make(map[string][]string)
+	for k, ctx := range n.Edges {
+		switch {
+		case k.From == n.Seed.Name && h.DistTo[k.To] == 1:
+			e.TopCollaborators = append(e.TopCollaborators, Collaborator{
+				Name: k.To, ID: h.ArtistMap[k.To].ID, SharedTracks: ctx.SharedCount, Track: ctx.Evidence,
+			})
+		case h.DistTo[k.From] == 1 && h.DistTo[k.To] == 2:
+			via[k.To] = append(via[k.To], k.From)
+		}
+	}
+	sort.Slice(e.TopCollaborators, func(i, j int) bool {
+		a, b := e.TopCollaborators[i], e.TopCollaborators[j]
+		if a.SharedTracks != b.SharedTracks {
+			return a.SharedTracks > b.SharedTracks
+		}
+		return a.Name < b.Name
+	})
+
+	bridges := make(map[string]*Bridge)
+	for _, froms := range via {
+		for _, from := range froms {
+			b, ok := bridges[from]
+			if !ok {
+				b = &Bridge{Name: from}
+				bridges[from] = b
+			}
+			b.Reaches++
+			if len(froms) == 1 {
+				b.Only++
+			}
+		}
+	}
+	for _, b := range bridges {
+		e.Bridges = append(e.Bridges, *b)
+	}
+	sort.Slice(e.Bridges, func(i, j int) bool {
+		a, b := e.Bridges[i], e.Bridges[j]
+		if a.Only != b.Only {
+			return a.Only > b.Only
+		}
+		if a.Reaches != b.Reaches {
+			return a.Reaches > b.Reaches
+		}
+		return a.Name < b.Name
+	})
+
+	if top >= 0 {
+		if len(e.TopCollaborators) > top {
+			e.TopCollaborators = e.TopCollaborators[:top]
+		}
+		if len(e.Bridges) > top {
+			e.Bridges = e.Bridges[:top]
+		}
+	}
+	return e
+}
@@ -0,0 +1,101 @@
+package sixdegrees
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExploreNeighborhood_DistancesAndEdges(t *testing.T) {
+	A, _, _, _, cat := diamond()
+	n := ExploreNeighborhood(A, 1, SearchOptions{Catalog: cat})
+	if n.Artist("B") == nil || n.Artist("C") == nil || n.Artist("D") != nil {
+		t.Fatalf("depth 1 should reach B and C only, got %v", n.Helper.DistTo)
+	}
+	if cat.fetched["B"] {
+		t.Fatalf("artists at the edge of the neighborhood should not be fetched")
+	}
+	if ctx := n.Edges[EdgeKey{From: "A", To: "B"}]; ctx.SharedCount != 2 {
+		t.Fatalf("expected A-B to share 2 tracks, got %+v", ctx)
+	}
+
+	cat.reset([]*Artists{A, n.Artist("B"), n.Artist("C")})
+	n = ExploreNeighborhood(A, 2, SearchOptions{Catalog: cat})
+	if d, ok := n.Helper.DistTo["D"]; !ok || d != 2 {
+		t.Fatalf("expected D at distance 2, got %v", n.Helper.DistTo)
+	}
+	if steps := n.PathTo("D"); len(steps) != 2 || steps[0].From != "A" || steps[1].To != "D" {
+		t.Fatalf("unexpected path to D %+v", steps)
+	}
+}
+
+// egoFixture: S works with X twice and Y once; X leads to P and Q, Y only
+// to Q.
+func egoFixture() (*Artists, *fixtureCatalog) {
+	S, X, Y := CreateArtists("S", "s"), CreateArtists("X", "x"), CreateArtists("Y", "y")
+	P, Q := CreateArtists("P", "p"), CreateArtists("Q", "q")
+	X.Genres["jazz"], P.Genres["jazz"], Q.Genres["soul"] = 1, 1, 1
+	cat := newFixtureCatalog()
+	cat.collab(S, X, "sx1")
+	cat.collab(S, X, "sx2")
+	cat.collab(S, Y, "sy")
+	cat.collab(X, P, "xp")
+	cat.collab(X, Q, "xq")
+	cat.collab(Y, Q, "yq")
+	return S, cat
+}
+
+func TestNeighborhoodExplore(t *testing.T) {
+	S, cat := egoFixture()
+	n := ExploreNeighborhood(S, 2, SearchOptions{Catalog: cat})
+	e := n.Explore(-1)
+	if len(e.Histogram) != 3 || e.Histogram[0] != 1 || e.Histogram[1] != 2 || e.Histogram[2] != 2 {
+		t.Fatalf("unexpected histogram %v", e.Histogram)
+	}
+	if len(e.TopCollaborators) != 2 || e.TopCollaborators[0].Name != "X" || e.TopCollaborators[0].SharedTracks != 2 {
+		t.Fatalf("expected X first with 2 shared tracks, got %+v", e.TopCollaborators)
+	}
+	if e.GenresByHop[1]["jazz"] != 1 || e.GenresByHop[2]["jazz"] != 1 || e.GenresByHop[2]["soul"] != 1 {
+		t.Fatalf("unexpected genres by hop %v", e.GenresByHop)
+	}
+	want := []Bridge{{Name: "X", Reaches: 2, Only: 1}, {Name: "Y", Reaches: 1, Only: 0}}
+	if len(e.Bridges) != 2 || e.Bridges[0] != want[0] || e.Bridges[1] != want[1] {
+		t.Fatalf("expected bridges %+v, got %+v", want, e.Bridges)
+	}
+	if top := n.Explore(1); len(top.Bridges) != 1 || len(top.TopCollaborators) != 1 {
+		t.Fatalf("top 1 should keep one of each, got %+v", top)
+	}
+}
+
+func TestNeighborhoodWriteNetwork(t *testing.T) {
+	S, cat := egoFixture()
+	n := ExploreNeighborhood(S, 2, SearchOptions{Catalog: cat})
+
+	var buf bytes.Buffer
+	if err := n.WriteNetwork(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var net Network
+	if err := json.Unmarshal(buf.Bytes(), &net); err != nil {
+		t.Fatal(err)
+	}
+	if len(net.Nodes) != 5 || net.Nodes[0].Name != "S" || net.Nodes[0].Distance != 0 {
+		t.Fatalf("unexpected nodes %+v", net.Nodes)
+	}
+	// S-X is seen from both ends but exported once.
+	if len(net.Edges) != 5 || net.Edges[0] != (NetworkEdge{From: "P", To: "X", SharedTracks: 1, Track: "xp", TrackID: "xp"}) {
+		t.Fatalf("unexpected edges %+v", net.Edges)
+	}
+
+	buf.Reset()
+	if err := n.WriteNetwork(&buf, ExportFormat("ego.dot")); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.HasPrefix(out, `graph "S" {`) || !strings.Contains(out, `"S" -- "X" [weight=2`) {
+		t.Fatalf("unexpected DOT output\n%s", out)
+	}
+	if err := n.WriteNetwork(&buf, "gexf"); err == nil {
+		t.Fatalf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,128 @@
+package sixdegrees
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportFormats lists the formats WriteNetwork accepts.
+var ExportFormats = []string{"json", "dot"}
+
+// NetworkNode is an artist of an exported network.
+type NetworkNode struct {
+	Name       string   `json:"name"`
+	ID         string   `json:"id,omitempty"`
+	Distance   int      `json:"distance"`
+	Popularity float64  `json:"popularity"`
+	Genres     []string `json:"genres,omitempty"`
+}
+
+// NetworkEdge is a collaboration of an exported network. Edges are
+// undirected; From sorts before To.
+type NetworkEdge struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	SharedTracks int    `json:"shared_tracks"`
+	Track        string `json:"track,omitempty"`
+	TrackID      string `json:"track_id,omitempty"`
+	Year         int    `json:"year,omitempty"`
+}
+
+// Network is a neighborhood flattened for export, in a stable order.
+type Network struct {
+	Seed  string        `json:"seed"`
+	Depth int           `json:"depth"`
+	Nodes []NetworkNode `json:"nodes"`
+	Edges []NetworkEdge `json:"edges"`
+}
+
+// Network flattens the neighborhood: nodes by distance then name, edges by
+// their ends.
+func (n *Neighborhood) Network() Network {
+	net := Network{Seed: n.Seed.Name, Depth: n.Depth}
+	for name, d := range n.Helper.DistTo {
+		a := n.Helper.ArtistMap[name]
+		node := NetworkNode{Name: name, ID: a.ID, Distance: d, Popularity: a.Popularity}
+		for g := range a.Genres {
+			node.Genres = append(node.Genres, g)
+		}
+		sort.Strings(node.Genres)
+		net.Nodes = append(net.Nodes, node)
+	}
+	sort.Slice(net.Nodes, func(i, j int) bool {
+		if net.Nodes[i].Distance != net.Nodes[j].Distance {
+			return net.Nodes[i].Distance < net.Nodes[j].Distance
+		}
+		return net.Nodes[i].Name < net.Nodes[j].Name
+	})
+
+	seen := make(map[EdgeKey]bool)
+	for k, ctx := range n.Edges {
+		if k.From > k.To {
+			k = EdgeKey{From: k.To, To: k.From}
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		net.Edges = append(net.Edges, NetworkEdge{From: k.From, To: k.To, SharedTracks: ctx.SharedCount,
+			Track: ctx.Evidence, TrackID: ctx.EvidenceID, Year: ctx.Year})
+	}
+	sort.Slice(net.Edges, func(i, j int) bool {
+		if net.Edges[i].From != net.Edges[j].From {
+			return net.Edges[i].From < net.Edges[j].From
+		}
+		return net.Edges[i].To < net.Edges[j].To
+	})
+	return net
+}
+
+// ExportFormat guesses the export format from a file name's extension,
+// defaulting to json.
+func ExportFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".dot") || strings.EqualFold(filepath.Ext(path), ".gv") {
+		return "dot"
+	}
+	return "json"
+}
+
+// WriteNetwork writes the neighborhood as JSON or as a Graphviz DOT graph
+// whose nodes are ranked by distance from the seed.
+func (n *Neighborhood) WriteNetwork(w io.Writer, format string) error {
+	net := n.Network()
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(net)
+	case "dot":
+		return writeDOT(w, net)
+	}
+	return fmt.Errorf("unknown export format %q (want %s)", format, strings.Join(ExportFormats, " or "))
+}
+
+func writeDOT(w io.Writer, net Network) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "graph %s {\n", strconv.Quote(net.Seed))
+	byDistance := make([][]string, net.Depth+1)
+	for _, node := range net.Nodes {
+		fmt.Fprintf(&b, "  %s [distance=%d, popularity=%s];\n", strconv.Quote(node.Name), node.Distance, strconv.FormatFloat(node.Popularity, 'g', -1, 64))
+		byDistance[node.Distance] = append(byDistance[node.Distance], strconv.Quote(node.Name))
+	}
+	for _, names := range byDistance {
+		if len(names) > 0 {
+			fmt.Fprintf(&b, "  { rank=same; %s; }\n", strings.Join(names, "; "))
+		}
+	}
+	for _, e := range net.Edges {
+		fmt.Fprintf(&b, "  %s -- %s [weight=%d, label=%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), e.SharedTracks, strconv.Quote(e.Track))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
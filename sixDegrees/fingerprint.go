@@ -0,0 +1,81 @@
+package sixdegrees
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FingerprintVersion changes whenever searches start ordering or weighing
+// artists differently, so fingerprints from before and after the change
+// never match.
+const FingerprintVersion = 1
+
+// Fingerprint records everything a search's result depends on, so the
+// search can be reproduced and its results diffed between versions: the
+// same fingerprint over the same catalog gives the same path. Artists not in
+// the snapshot are still fetched from Spotify, whose answers can change.
+type Fingerprint struct {
+	Hash    string `json:"hash"` // digest of the fields below, to compare at a glance
+	Version int    `json:"version"`
+
+	// Params holds the search's endpoints, mode and options as strings.
+	Params map[string]string `json:"params"`
+
+	Snapshot string `json:"snapshot,omitempty"` // Snapshot.Version of the warm graph; empty when none
+	Known    int    `json:"known_artists"`      // artists known before the search
+}
+
+// NewFingerprint describes a search from start to target under opts. mode
+// names the algorithm with its settings, e.g. "astar collab genre 0.1";
+// snapshot is the Version of the snapshot opts.Known was loaded from, or
+// empty.
+func NewFingerprint(start, target *Artists, mode string, opts SearchOptions, snapshot string) Fingerprint {
+	p := map[string]string{
+		"start":     start.Name + " " + start.ID,
+		"target":    target.Name + " " + target.ID,
+		"mode":      mode,
+		"max_depth": strconv.Itoa(opts.MaxDepth),
+
+		"include_groups": strings.Join(sorted(opts.Fetch.IncludeGroups), ","),
+		"market":         opts.Fetch.Market,
+		"album_limit":    strconv.Itoa(opts.Fetch.AlbumLimit),
+		"track_limit":    strconv.Itoa(opts.Fetch.TrackLimit),
+
+		"budget": strconv.Itoa(opts.Budget.APICalls) + " " + strconv.Itoa(opts.Budget.Expanded) + " " + opts.Budget.Duration.String(),
+	}
+	if opts.Catalog != nil {
+		p["catalog"] = "custom"
+	}
+	if c := opts.Constraints; c != nil {
+		via := make([]string, len(c.Waypoints))
+		for i, w := range c.Waypoints {
+			via[i] = w.Name + " " + w.ID
+		}
+		p["exclude_ids"] = strings.Join(sorted(c.ExcludeIDs), ",")
+		p["via"] = strings.Join(via, ",") // in visiting order
+		p["genres"] = strings.Join(sorted(c.AllowGenres), ",")
+		p["exclude_genres"] = strings.Join(sorted(c.DenyGenres), ",")
+		p["popularity"] = strconv.FormatFloat(c.MinPopularity, 'g', -1, 64) + "-" + strconv.FormatFloat(c.MaxPopularity, 'g', -1, 64)
+		p["years"] = strconv.Itoa(c.FromYear) + "-" + strconv.Itoa(c.ToYear)
+	}
+	f := Fingerprint{Version: FingerprintVersion, Params: p, Snapshot: snapshot}
+	if opts.Known != nil {
+		f.Known = len(opts.Known.ArtistMap)
+	}
+	// Map keys marshal sorted, so equal fingerprints hash alike.
+	b, _ := json.Marshal(f)
+	sum := sha256.Sum256(b)
+	f.Hash = hex.EncodeToString(sum[:])[:16]
+	return f
+}
+
+// sorted returns a sorted copy of ss.
+func sorted(ss []string) []string {
+	out := append([]string(nil), ss...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,29 @@
+package sixdegrees
+
+import "testing"
+
+func TestNewFingerprint(t *testing.T) {
+	A, B, C, D, _ := diamond()
+	opts := func(depth int, c *Constraints) SearchOptions {
+		return SearchOptions{MaxDepth: depth, Constraints: c}
+	}
+	base := NewFingerprint(A, D, "bfs", opts(3, &Constraints{ExcludeIDs: []string{"b", "c"}}), "abc")
+	if same := NewFingerprint(A, D, "bfs", opts(3, &Constraints{ExcludeIDs: []string{"c", "b"}}), "abc"); same.Hash != base.Hash {
+		t.Fatal("the order of excluded artists should not matter")
+	}
+	for name, fp := range map[string]Fingerprint{
+		"depth":    NewFingerprint(A, D, "bfs", opts(4, &Constraints{ExcludeIDs: []string{"b", "c"}}), "abc"),
+		"mode":     NewFingerprint(A, D, "astar collab genre 0.1", opts(3, &Constraints{ExcludeIDs: []string{"b", "c"}}), "abc"),
+		"snapshot": NewFingerprint(A, D, "bfs", opts(3, &Constraints{ExcludeIDs: []string{"b", "c"}}), "abd"),
+		"target":   NewFingerprint(A, C, "bfs", opts(3, &Constraints{ExcludeIDs: []string{"b", "c"}}), "abc"),
+	} {
+		if fp.Hash == base.Hash {
+			t.Errorf("changing the %s should change the fingerprint", name)
+		}
+	}
+	viaBC := NewFingerprint(A, D, "bfs", opts(3, &Constraints{Waypoints: []*Artists{B, C}}), "")
+	viaCB := NewFingerprint(A, D, "bfs", opts(3, &Constraints{Waypoints: []*Artists{C, B}}), "")
+	if viaBC.Hash == viaCB.Hash {
+		t.Fatal("waypoints are visited in order, so their order should matter")
+	}
+}
@@ -0,0 +1,233 @@
+package sixdegrees
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// CollabGraph is an undirected collaboration graph keyed by artist ID, such
+// as the stored collaborations table loaded into memory. Unlike Helper it
+// holds no tracks, so whole crawls fit for graph-wide analysis.
+type CollabGraph struct {
+	ids    []string
+	index  map[string]int
+	adj    [][]int
+	shared [][]int // shared-track counts, parallel to adj
+}
+
+// NewCollabGraph returns an empty graph.
+func NewCollabGraph() *CollabGraph {
+	return &CollabGraph{index: make(map[string]int)}
+}
+
+func (g *CollabGraph) node(id string) int {
+	i, ok := g.index[id]
+	if !ok {
+		i = len(g.ids)
+		g.index[id] = i
+		g.ids = append(g.ids, id)
+		g.adj = append(g.adj, nil)
+		g.shared = append(g.shared, nil)
+	}
+	return i
+}
+
+// AddEdge records that a and b share tracks. Each pair should be added once.
+func (g *CollabGraph) AddEdge(a, b string, shared int) {
+	if a == b {
+		return
+	}
+	i, j := g.node(a), g.node(b)
+	g.adj[i] = append(g.adj[i], j)
+	g.shared[i] = append(g.shared[i], shared)
+	g.adj[j] = append(g.adj[j], i)
+	g.shared[j] = append(g.shared[j], shared)
+}
+
+// Len returns the number of artists.
+func (g *CollabGraph) Len() int { return len(g.ids) }
+
+// Has reports whether id collaborates with anyone in the graph.
+func (g *CollabGraph) Has(id string) bool {
+	_, ok := g.index[id]
+	return ok
+}
+
+// IDs returns every artist ID in the order they were added.
+func (g *CollabGraph) IDs() []string { return g.ids }
+
+// Distances returns the number of hops from id to every artist it reaches,
+// itself included at 0.
+func (g *CollabGraph) Distances(id string) map[string]int {
+	src, ok := g.index[id]
+	if !ok {
+		return nil
+	}
+	dist, _ := g.bfs(src, nil)
+	out := make(map[string]int)
+	for i, d := range dist {
+		if d >= 0 {
+			out[g.ids[i]] = d
+		}
+	}
+	return out
+}
+
+// Path returns the artist IDs of a shortest path from a to b, both
+// included, or nil when they are not connected.
+func (g *CollabGraph) Path(a, b string) []string {
+	src, ok1 := g.index[a]
+	dst, ok2 := g.index[b]
+	if !ok1 || !ok2 {
+		return nil
+	}
+	prev := make([]int, len(g.ids))
+	dist, _ := g.bfs(src, prev)
+	if dist[dst] < 0 {
+		return nil
+	}
+	path := make([]string, dist[dst]+1)
+	for i, v := len(path)-1, dst; i >= 0; i, v = i-1, prev[v] {
+		path[i] = g.ids[v]
+	}
+	return path
+}
+
+// bfs returns the hop count from src to every node (-1 when unreached) and
+// the nodes in the order they were reached. When prev is non-nil it is
+// filled with each node's predecessor.
+func (g *CollabGraph) bfs(src int, prev []int) ([]int, []int) {
+	dist := make([]int, len(g.ids))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[src] = 0
+	order := []int{src}
+	for q := 0; q < len(order); q++ {
+		v := order[q]
+		for _, w := range g.adj[v] {
+			if dist[w] < 0 {
+				dist[w] = dist[v] + 1
+				if prev != nil {
+					prev[w] = v
+				}
+				order = append(order, w)
+			}
+		}
+	}
+	return dist, order
+}
+
+// Centrality describes how central one artist is in a CollabGraph.
+type Centrality struct {
+	ID       string `json:"id"`
+	Degree   int    `json:"degree"`   // distinct collaborators
+	Strength int    `json:"strength"` // shared tracks over all collaborators
+
+	// Closeness is the share of other artists reached divided by the mean
+	// hops to them (Wasserman-Faust), so it stays comparable across
+	// disconnected components. 1 means everyone is one hop away.
+	Closeness float64 `json:"closeness"`
+
+	// Betweenness is the number of artist pairs whose shortest paths run
+	// through this artist, split evenly between equally short paths.
+	Betweenness float64 `json:"betweenness"`
+}
+
+// Centrality computes every artist's centrality. Closeness and betweenness
+// need a breadth-first search per source artist; when samples is positive
+// and below Len, only that many sources, picked with seed, are searched and
+// the results are estimates scaled to the whole graph.
+func (g *CollabGraph) Centrality(samples int, seed int64) []Centrality {
+	n := len(g.ids)
+	out := make([]Centrality, n)
+	for i, id := range g.ids {
+		out[i] = Centrality{ID: id, Degree: len(g.adj[i])}
+		for _, s := range g.shared[i] {
+			out[i].Strength += s
+		}
+	}
+	if n < 2 {
+		return out
+	}
+
+	sources := make([]int, n)
+	for i := range sources {
+		sources[i] = i
+	}
+	if samples > 0 && samples < n {
+		sources = rand.New(rand.NewSource(seed)).Perm(n)[:samples]
+		sort.Ints(sources)
+	}
+	sampled := make([]bool, n)
+	for _, s := range sources {
+		sampled[s] = true
+	}
+
+	reached := make([]int, n) // sources other than v that reach v
+	sumDist := make([]int, n)
+	between := make([]float64, n)
+	sigma := make([]float64, n)
+	delta := make([]float64, n)
+	for _, s := range sources {
+		dist, order := g.bfs(s, nil)
+		// Brandes: count shortest paths forwards, then accumulate each
+		// node's dependency backwards.
+		for _, v := range order {
+			sigma[v], delta[v] = 0, 0
+		}
+		sigma[s] = 1
+		for _, v := range order {
+			for _, w := range g.adj[v] {
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+				}
+			}
+		}
+		for i := len(order) - 1; i > 0; i-- {
+			w := order[i]
+			for _, v := range g.adj[w] {
+				if dist[v] == dist[w]-1 {
+					delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+				}
+			}
+			between[w] += delta[w]
+			reached[w]++
+			sumDist[w] += dist[w]
+		}
+	}
+
+	// Every pair is counted from both ends when all sources are searched.
+	scale := float64(n) / float64(len(sources)) / 2
+	for v := range out {
+		out[v].Betweenness = between[v] * scale
+		others := len(sources)
+		if sampled[v] {
+			others--
+		}
+		if reached[v] > 0 && others > 0 {
+			share := float64(reached[v]) / float64(others)
+			meanDist := float64(sumDist[v]) / float64(reached[v])
+			out[v].Closeness = share / meanDist
+		}
+	}
+	return out
+}
+
+// GenreCenters picks the most central artist of each genre: the one with
+// the highest closeness among those tagged with it, ties going to more
+// collaborators and then to the lower ID. genres maps artist IDs to their
+// genres; artists outside the graph are ignored.
+func GenreCenters(cs []Centrality, genres map[string][]string) map[string]Centrality {
+	out := make(map[string]Centrality)
+	for _, c := range cs {
+		for _, g := range genres[c.ID] {
+			best, ok := out[g]
+			if !ok || c.Closeness > best.Closeness ||
+				(c.Closeness == best.Closeness && (c.Degree > best.Degree || (c.Degree == best.Degree && c.ID < best.ID))) {
+				out[g] = c
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package sixdegrees
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// chain builds a-b-c-d plus the separate pair x-y.
+func chain() *CollabGraph {
+	g := NewCollabGraph()
+	g.AddEdge("a", "b", 3)
+	g.AddEdge("b", "c", 1)
+	g.AddEdge("c", "d", 1)
+	g.AddEdge("x", "y", 2)
+	return g
+}
+
+func centralityByID(cs []Centrality) map[string]Centrality {
+	out := make(map[string]Centrality, len(cs))
+	for _, c := range cs {
+		out[c.ID] = c
+	}
+	return out
+}
+
+func TestCollabGraph_Centrality(t *testing.T) {
+	c := centralityByID(chain().Centrality(0, 1))
+	if c["b"].Degree != 2 || c["b"].Strength != 4 || c["a"].Degree != 1 {
+		t.Fatalf("unexpected degrees %+v %+v", c["a"], c["b"])
+	}
+	// b lies on a-c and a-d; a and d lie on nothing.
+	if c["b"].Betweenness != 2 || c["c"].Betweenness != 2 || c["a"].Betweenness != 0 {
+		t.Fatalf("unexpected betweenness b=%v c=%v a=%v", c["b"].Betweenness, c["c"].Betweenness, c["a"].Betweenness)
+	}
+	// b reaches 3 of the 5 others at a mean of 4/3 hops.
+	if want := 3.0 / 5 / (4.0 / 3); math.Abs(c["b"].Closeness-want) > 1e-9 {
+		t.Fatalf("expected closeness %v for b, got %v", want, c["b"].Closeness)
+	}
+	if c["x"].Closeness >= c["b"].Closeness {
+		t.Fatalf("the small component should be less central: x=%v b=%v", c["x"].Closeness, c["b"].Closeness)
+	}
+}
+
+func TestCollabGraph_SampledCentrality(t *testing.T) {
+	// A star: the hub lies on every pair of spokes.
+	g := NewCollabGraph()
+	for i := 0; i < 40; i++ {
+		g.AddEdge("hub", fmt.Sprintf("s%d", i), 1)
+	}
+	exact := centralityByID(g.Centrality(0, 1))
+	if want := float64(40 * 39 / 2); exact["hub"].Betweenness != want {
+		t.Fatalf("expected hub betweenness %v, got %v", want, exact["hub"].Betweenness)
+	}
+	sampled := centralityByID(g.Centrality(10, 7))
+	if b := sampled["hub"].Betweenness; math.Abs(b-exact["hub"].Betweenness)/exact["hub"].Betweenness > 0.2 {
+		t.Fatalf("sampled hub betweenness %v too far from %v", b, exact["hub"].Betweenness)
+	}
+	if sampled["hub"].Closeness != 1 || sampled["s3"].Closeness >= sampled["hub"].Closeness {
+		t.Fatalf("unexpected sampled closeness hub=%v s3=%v", sampled["hub"].Closeness, sampled["s3"].Closeness)
+	}
+}
+
+func TestCollabGraph_DistancesAndPath(t *testing.T) {
+	g := chain()
+	d := g.Distances("a")
+	if d["d"] != 3 || d["a"] != 0 {
+		t.Fatalf("unexpected distances %v", d)
+	}
+	if _, ok := d["x"]; ok {
+		t.Fatalf("x is in another component, got %v", d)
+	}
+	if p := g.Path("d", "a"); fmt.Sprint(p) != "[d c b a]" {
+		t.Fatalf("unexpected path %v", p)
+	}
+	if p := g.Path("a", "x"); p != nil {
+		t.Fatalf("expected no path, got %v", p)
+	}
+	if g.Distances("nobody") != nil {
+		t.Fatalf("expected nil distances for an unknown artist")
+	}
+}
+
+func TestGenreCenters(t *testing.T) {
+	cs := chain().Centrality(0, 1)
+	centers := GenreCenters(cs, map[string][]string{
+		"a": {"rock"}, "b": {"rock", "pop"}, "d": {"pop"}, "x": {"jazz"}, "y": {"jazz"}, "nobody": {"folk"},
+	})
+	if centers["rock"].ID != "b" || centers["pop"].ID != "b" {
+		t.Fatalf("expected b to center rock and pop, got %+v", centers)
+	}
+	if centers["jazz"].ID != "x" {
+		t.Fatalf("expected the tie in jazz to go to x, got %+v", centers["jazz"])
+	}
+	if _, ok := centers["folk"]; ok {
+		t.Fatalf("artists outside the graph should be ignored")
+	}
+}
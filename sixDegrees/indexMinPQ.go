@@ -0,0 +1,99 @@
+package sixdegrees
+
+// IndexMinPQ is an indexed binary min-heap of float64 keys, addressed by
+// vertex index. It backs Dijkstra's priority queue and supports changing the
+// key of an index that is already queued.
+type IndexMinPQ struct {
+	pq   []int     // heap position -> index (1-based)
+	qp   []int     // index -> heap position, -1 when absent
+	keys []float64 // key per index
+	n    int
+}
+
+// NewIndexMinPQ creates a queue able to hold indexes 0..maxN-1.
+func NewIndexMinPQ(maxN int) IndexMinPQ {
+	if maxN < 0 {
+		maxN = 0
+	}
+	q := IndexMinPQ{
+		pq:   make([]int, maxN+1),
+		qp:   make([]int, maxN),
+		keys: make([]float64, maxN),
+	}
+	for i := range q.qp {
+		q.qp[i] = -1
+	}
+	return q
+}
+
+func (q *IndexMinPQ) IsEmpty() bool { return q.n == 0 }
+
+func (q *IndexMinPQ) Size() int { return q.n }
+
+func (q *IndexMinPQ) Contains(i int) bool {
+	return i >= 0 && i < len(q.qp) && q.qp[i] != -1
+}
+
+// Insert adds index i with the given key. Out-of-range or already queued
+// indexes are ignored.
+func (q *IndexMinPQ) Insert(i int, key float64) {
+	if i < 0 || i >= len(q.qp) || q.Contains(i) {
+		return
+	}
+	q.n++
+	q.qp[i] = q.n
+	q.pq[q.n] = i
+	q.keys[i] = key
+	q.swim(q.n)
+}
+
+// DelMin removes and returns the index with the smallest key, or -1 if empty.
+func (q *IndexMinPQ) DelMin() int {
+	if q.n == 0 {
+		return -1
+	}
+	min := q.pq[1]
+	q.exch(1, q.n)
+	q.n--
+	q.sink(1)
+	q.qp[min] = -1
+	return min
+}
+
+// DecreaseKey lowers the key of a queued index; larger keys are ignored.
+func (q *IndexMinPQ) DecreaseKey(i int, key float64) {
+	if !q.Contains(i) || key >= q.keys[i] {
+		return
+	}
+	q.keys[i] = key
+	q.swim(q.qp[i])
+}
+
+func (q *IndexMinPQ) greater(a, b int) bool { return q.keys[q.pq[a]] > q.keys[q.pq[b]] }
+
+func (q *IndexMinPQ) exch(a, b int) {
+	q.pq[a], q.pq[b] = q.pq[b], q.pq[a]
+	q.qp[q.pq[a]] = a
+	q.qp[q.pq[b]] = b
+}
+
+func (q *IndexMinPQ) swim(k int) {
+	for k > 1 && q.greater(k/2, k) {
+		q.exch(k, k/2)
+		k = k / 2
+	}
+}
+
+func (q *IndexMinPQ) sink(k int) {
+	for 2*k <= q.n {
+		j := 2 * k
+		if j < q.n && q.greater(j, j+1) {
+			j++
+		}
+		if !q.greater(k, j) {
+			break
+		}
+		q.exch(k, j)
+		k = j
+	}
+}
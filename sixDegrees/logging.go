@@ -0,0 +1,62 @@
+package sixdegrees
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// packageLogger is used when a search does not bring its own logger.
+var packageLogger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger for package code that runs outside a search,
+// such as InputArtist. nil restores slog.Default.
+func SetLogger(l *slog.Logger) {
+	packageLogger.Store(l)
+}
+
+// logger returns the package logger tagged with this package's component.
+func logger() *slog.Logger {
+	l := packageLogger.Load()
+	if l == nil {
+		l = slog.Default()
+	}
+	return l.With("component", "search")
+}
+
+// logger returns the logger of the search h belongs to.
+func (h *Helper) logger() *slog.Logger {
+	if h == nil || h.log == nil {
+		return logger()
+	}
+	return h.log
+}
+
+// NewLogID returns a short random ID for tagging the log records of one
+// request or job.
+func NewLogID() string {
+	var b [6]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewLogger builds a logger writing to w. level is one of debug, info, warn
+// or error; format is text or json.
+func NewLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return nil, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", level)
+	}
+	opts := &slog.HandlerOptions{Level: lv}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want text or json)", format)
+}
@@ -0,0 +1,52 @@
+package sixdegrees
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger_ValidatesLevelAndFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(&buf, "warn", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hidden")
+	l.Warn("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, `"msg":"shown"`) {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if _, err := NewLogger(&buf, "loud", "text"); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+	if _, err := NewLogger(&buf, "info", "xml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestSearch_LogsThroughOptionsLogger(t *testing.T) {
+	A, _, _, D, cat := diamond()
+	var buf bytes.Buffer
+	l, _ := NewLogger(&buf, "debug", "json")
+	if _, _, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Logger: l.With("request_id", "r1")}); !ok {
+		t.Fatal("expected a path")
+	}
+	var explored int
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("bad log line %q: %v", line, err)
+		}
+		if rec["request_id"] != "r1" || rec["component"] != "search" {
+			t.Fatalf("missing fields in %v", rec)
+		}
+		if rec["msg"] == "exploring" {
+			explored++
+		}
+	}
+	if explored == 0 {
+		t.Fatalf("no exploring records in %s", buf.String())
+	}
+}
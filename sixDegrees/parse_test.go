@@ -3,6 +3,8 @@ package sixdegrees
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
 )
 
 // Minimal fixtures that mimic the spotify client aggregated shape: { Items: [] }
@@ -28,7 +30,13 @@ func TestParseAlbums_IgnoresVariousArtists(t *testing.T) {
 }
 
 func TestCreateTracks_ParsesArtistsAndIds(t *testing.T) {
-	artist := &Artists{Name: "A"}
+	old := fetchArtists
+	fetchArtists = func(ids []string) ([]byte, error) {
+		return []byte(`{"artists":[{"id":"b","name":"B"}]}`), nil
+	}
+	defer func() { fetchArtists = old }()
+
+	artist := &Artists{Name: "A", ID: "a"}
 	h := NewHelper()
 	payload := map[string]interface{}{
 		"items": []interface{}{
@@ -36,8 +44,8 @@ func TestCreateTracks_ParsesArtistsAndIds(t *testing.T) {
 				"id": "t1",
 				"name": "Track 1",
 				"artists": []interface{}{
-					map[string]interface{}{"name": "A"},
-					map[string]interface{}{"name": "B"},
+					map[string]interface{}{"name": "A", "id": "a"},
+					map[string]interface{}{"name": "B", "id": "b"},
 				},
 			},
 		},
@@ -50,7 +58,156 @@ func TestCreateTracks_ParsesArtistsAndIds(t *testing.T) {
 	if tracks[0].ID != "t1" || tracks[0].Name != "Track 1" {
 		t.Fatalf("unexpected track fields: %+v", tracks[0])
 	}
-	if len(tracks[0].Featured) != 2 {
-		t.Fatalf("expected 2 artists in Featured, got %d", len(tracks[0].Featured))
+	// The artist itself is the track's Artist, not one of its features.
+	if tracks[0].Artist != artist || len(tracks[0].Featured) != 1 || tracks[0].Featured[0].Name != "B" {
+		t.Fatalf("expected only B in Featured, got %+v", tracks[0].Featured)
+	}
+}
+
+func TestParseAlbums_KeepsVariousArtistsGuestAppearances(t *testing.T) {
+	artist := &Artists{Name: "X"}
+	b := []byte(`{"items":[
+		{"id":"va-own","album_group":"compilation","artists":[{"name":"Various Artists"}]},
+		{"id":"va-guest","album_group":"appears_on","artists":[{"name":"Various Artists"}]},
+		{"id":"host","album_group":"appears_on","artists":[{"name":"Y"}]}]}`)
+	ids := artist.ParseAlbums(b)
+	if len(ids) != 2 || ids[0] != "va-guest" || ids[1] != "host" {
+		t.Fatalf("expected the guest albums, got %v", ids)
+	}
+	if info := ParseAlbumInfo(b); !info["va-guest"].Guest || info["va-own"].Guest {
+		t.Fatalf("unexpected album info %+v", info)
+	}
+}
+
+func TestCreateGuestTracks_KeepsOnlyCreditedTracks(t *testing.T) {
+	artist := &Artists{Name: "X", ID: "x"}
+	h := NewHelper()
+	for _, name := range []string{"Y", "Z"} {
+		h.ArtistMap[name] = &Artists{Name: name}
+	}
+	// A compilation: only the second track credits X (by ID, under another name).
+	b := []byte(`{"items":[
+		{"id":"t1","name":"Other","artists":[{"name":"Unknown Elsewhere"}]},
+		{"id":"t2","name":"Guest Verse","artists":[{"name":"Y"},{"name":"X (feat.)","id":"x"},{"name":"Various Artists"},{"name":"Z"}]}]}`)
+	tracks := artist.AlbumTracks(b, AlbumInfo{Year: 2001, Guest: true}, h)
+	if len(tracks) != 1 {
+		t.Fatalf("expected only the credited track, got %+v", tracks)
+	}
+	tr := tracks[0]
+	if tr.ID != "t2" || !tr.Guest || tr.Year != 2001 || tr.Artist != artist {
+		t.Fatalf("unexpected guest track %+v", tr)
+	}
+	if len(tr.Featured) != 2 || tr.Featured[0].Name != "Y" || tr.Featured[1].Name != "Z" {
+		t.Fatalf("expected the host first and no Various Artists, got %v", tr.Featured)
+	}
+}
+
+func TestCreateTracks_HydratesCollaboratorsInOneBatch(t *testing.T) {
+	var calls [][]string
+	old := fetchArtists
+	fetchArtists = func(ids []string) ([]byte, error) {
+		calls = append(calls, ids)
+		return []byte(`{"artists":[{"id":"b","name":"B","popularity":60,"genres":["rap"]},null]}`), nil
+	}
+	defer func() { fetchArtists = old }()
+
+	artist := &Artists{Name: "A", ID: "a"}
+	h := NewHelper()
+	h.ArtistMap["Known"] = &Artists{Name: "Known", ID: "k"}
+	b := []byte(`{"items":[
+		{"id":"t1","name":"One","artists":[{"name":"A","id":"a"},{"name":"B","id":"b"},{"name":"Known","id":"k"}]},
+		{"id":"t2","name":"Two","artists":[{"name":"A","id":"a"},{"name":"B","id":"b"},{"name":"Gone","id":"g"}]}]}`)
+	tracks, _ := artist.CreateTracks(b, h)
+
+	if len(calls) != 1 || len(calls[0]) != 2 || calls[0][0] != "b" || calls[0][1] != "g" {
+		t.Fatalf("expected one lookup for the unknown IDs, got %v", calls)
+	}
+	// A itself is skipped; each track features the other two credits.
+	if len(tracks) != 2 || len(tracks[0].Featured) != 2 || len(tracks[1].Featured) != 2 ||
+		tracks[0].Featured[0].Name != "B" || tracks[1].Featured[1].Name != "Gone" {
+		t.Fatalf("unexpected tracks %+v", tracks)
+	}
+	B := h.ArtistMap["B"]
+	if B == nil || B.Popularity != 60 || B.Genres["rap"] != 1 || tracks[1].Featured[0] != B {
+		t.Fatalf("B should be hydrated and shared: %+v", B)
+	}
+	if g := h.ArtistMap["Gone"]; g == nil || g.ID != "g" {
+		t.Fatalf("an unresolved ID should still leave a placeholder: %+v", g)
+	}
+	if tracks[0].Featured[1] != h.ArtistMap["Known"] {
+		t.Fatalf("known artists should be reused")
+	}
+}
+
+func TestPrefetchAlbumTracks(t *testing.T) {
+	old := fetchAlbums
+	var asked []string
+	fetchAlbums = func(ids []string, o spotify.FetchOptions) ([]byte, error) {
+		asked = append(asked, ids...)
+		return []byte(`{"albums":[
+			{"id":"p1","tracks":{"items":[{"id":"t1"},{"id":"t2"}],"next":null}},
+			{"id":"p2","tracks":{"items":[{"id":"t3"}],"next":"https://api.spotify.com/v1/albums/p2/tracks?offset=1"}}]}`), nil
+	}
+	defer func() { fetchAlbums = old }()
+	defer func() { delete(albumCache, "p1"); delete(albumCache, "p2"); delete(albumCache, "p2/1"); delete(albumCache, "p1/1") }()
+
+	if err := prefetchAlbumTracks([]string{"p1", "p2"}, spotify.FetchOptions{}); err != nil {
+		t.Fatalf("prefetch: %v", err)
+	}
+	if string(albumCache["p1"]) != `{"items":[{"id":"t1"},{"id":"t2"}]}` {
+		t.Fatalf("p1 not cached: %s", albumCache["p1"])
+	}
+	if _, ok := albumCache["p2"]; ok {
+		t.Fatalf("p2 has more tracks and must not be cached from its first page")
+	}
+
+	// With a track limit the first page is enough; p1 is cached separately.
+	if err := prefetchAlbumTracks([]string{"p2"}, spotify.FetchOptions{TrackLimit: 1}); err != nil {
+		t.Fatalf("prefetch: %v", err)
+	}
+	if string(albumCache["p2/1"]) != `{"items":[{"id":"t3"}]}` {
+		t.Fatalf("p2 not cached under its track limit: %s", albumCache["p2/1"])
+	}
+}
+
+func TestNowPlaying_ResolvesCredits(t *testing.T) {
+	oldPlayback, oldFetch := currentPlayback, fetchArtists
+	defer func() { currentPlayback, fetchArtists = oldPlayback, oldFetch }()
+
+	currentPlayback = func() spotify.Queue {
+		return spotify.Queue{TrackID: "t1", TrackName: "Song", Artists: []spotify.Credit{{Name: "Host", ID: "h"}, {Name: "Guest", ID: "g"}}}
+	}
+	fetchArtists = func(ids []string) ([]byte, error) {
+		return []byte(`{"artists":[{"id":"h","name":"Host","popularity":70},null]}`), nil
+	}
+	track, credits, err := NowPlaying()
+	if err != nil || track != "Song" || len(credits) != 2 {
+		t.Fatalf("got %q %v %v", track, credits, err)
+	}
+	if credits[0].Name != "Host" || credits[0].Popularity != 70 || credits[1].ID != "g" {
+		t.Fatalf("unexpected credits %+v %+v", credits[0], credits[1])
+	}
+
+	// A local file has no track ID, and its credits no artist IDs; they are
+	// searched by name.
+	ts := fakeSpotify()
+	defer ts.Close()
+	spotify.SetBaseURL(ts.URL)
+	spotify.SetAccessToken("test")
+	defer func() {
+		spotify.SetBaseURL("")
+		spotify.SetAccessToken("")
+	}()
+	currentPlayback = func() spotify.Queue {
+		return spotify.Queue{TrackName: "Demo", Artists: []spotify.Credit{{Name: "C"}}}
+	}
+	track, credits, err = NowPlaying()
+	if err != nil || track != "Demo" || len(credits) != 1 || credits[0].ID != "c" || credits[0].Popularity != 90 {
+		t.Fatalf("local file: got %q %+v %v", track, credits, err)
+	}
+
+	currentPlayback = func() spotify.Queue { return spotify.Queue{} }
+	if _, _, err := NowPlaying(); err == nil {
+		t.Fatal("expected an error when nothing is playing")
 	}
 }
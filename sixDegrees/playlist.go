@@ -0,0 +1,91 @@
+package sixdegrees
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+// maxPlaylistDescription is Spotify's limit on playlist descriptions.
+const maxPlaylistDescription = 300
+
+// Test seams for the playlist calls.
+var (
+	createPlaylist    = spotify.CreatePlaylist
+	addPlaylistTracks = spotify.AddPlaylistTracks
+)
+
+// PathPlaylist is a playlist made from a found path: each hop's evidence
+// track, in path order.
+type PathPlaylist struct {
+	Name        string
+	Description string
+	TrackIDs    []string
+}
+
+// NewPathPlaylist titles and describes the playlist for steps. Hops without
+// a known track ID are left out, and a track connecting several hops in a
+// row is listed once. It fails when no hop has a track ID.
+func NewPathPlaylist(steps []PathStep) (PathPlaylist, error) {
+	if len(steps) == 0 {
+		return PathPlaylist{}, errors.New("empty path")
+	}
+	start, target := steps[0].From, steps[len(steps)-1].To
+	p := PathPlaylist{Name: fmt.Sprintf("Six Degrees: %s → %s", start, target)}
+
+	var chain strings.Builder
+	chain.WriteString(start)
+	for _, st := range steps {
+		fmt.Fprintf(&chain, " → %s", st.To)
+	}
+	if p.TrackIDs = PathTrackIDs(steps); len(p.TrackIDs) == 0 {
+		return PathPlaylist{}, errors.New("no hop has a Spotify track ID")
+	}
+	hops := "hops"
+	if len(steps) == 1 {
+		hops = "hop"
+	}
+	p.Description = truncate(fmt.Sprintf("%d %s, one connecting track each: %s", len(steps), hops, chain.String()), maxPlaylistDescription)
+	return p, nil
+}
+
+// PathTrackIDs lists the Spotify IDs of the evidence tracks of steps in
+// order, skipping hops without one and repeats of the previous track.
+func PathTrackIDs(steps []PathStep) []string {
+	var ids []string
+	for _, st := range steps {
+		if st.TrackID != "" && (len(ids) == 0 || ids[len(ids)-1] != st.TrackID) {
+			ids = append(ids, st.TrackID)
+		}
+	}
+	return ids
+}
+
+// CreatePathPlaylist creates the playlist for steps on the authorized user's
+// account and fills it with the evidence tracks.
+func CreatePathPlaylist(steps []PathStep, public bool) (spotify.Playlist, error) {
+	p, err := NewPathPlaylist(steps)
+	if err != nil {
+		return spotify.Playlist{}, err
+	}
+	pl, err := createPlaylist(p.Name, p.Description, public)
+	if err != nil {
+		return spotify.Playlist{}, fmt.Errorf("create playlist: %w", err)
+	}
+	if err := addPlaylistTracks(pl.ID, p.TrackIDs); err != nil {
+		return pl, err
+	}
+	logger().Info("created playlist", "playlist_id", pl.ID, "tracks", len(p.TrackIDs))
+	return pl, nil
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
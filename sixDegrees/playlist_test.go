@@ -0,0 +1,66 @@
+package sixdegrees
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+func TestNewPathPlaylist(t *testing.T) {
+	steps := []PathStep{
+		{From: "A", To: "B", Track: "ab", TrackID: "t1"},
+		{From: "B", To: "C", Track: "abc", TrackID: "t1"}, // same track again
+		{From: "C", To: "D", Track: "cd"},                 // no ID
+		{From: "D", To: "E", Track: "de", TrackID: "t2"},
+	}
+	p, err := NewPathPlaylist(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Six Degrees: A → E" {
+		t.Fatalf("name = %q", p.Name)
+	}
+	if strings.Join(p.TrackIDs, ",") != "t1,t2" {
+		t.Fatalf("tracks = %v", p.TrackIDs)
+	}
+	if !strings.Contains(p.Description, "4 hops") || !strings.Contains(p.Description, "A → B → C → D → E") {
+		t.Fatalf("description = %q", p.Description)
+	}
+
+	if _, err := NewPathPlaylist([]PathStep{{From: "A", To: "B"}}); err == nil {
+		t.Fatal("expected an error without track IDs")
+	}
+
+	long := []PathStep{{From: strings.Repeat("x", 400), To: "B", TrackID: "t"}}
+	if p, _ := NewPathPlaylist(long); len([]rune(p.Description)) != maxPlaylistDescription {
+		t.Fatalf("description not truncated: %d runes", len([]rune(p.Description)))
+	}
+}
+
+func TestCreatePathPlaylist(t *testing.T) {
+	oldCreate, oldAdd := createPlaylist, addPlaylistTracks
+	defer func() { createPlaylist, addPlaylistTracks = oldCreate, oldAdd }()
+
+	var gotName string
+	var gotTracks []string
+	createPlaylist = func(name, description string, public bool) (spotify.Playlist, error) {
+		gotName = name
+		return spotify.Playlist{ID: "pl1", URL: "https://open.spotify.com/playlist/pl1"}, nil
+	}
+	addPlaylistTracks = func(id string, tracks []string) error {
+		if id != "pl1" {
+			t.Fatalf("tracks added to %q", id)
+		}
+		gotTracks = tracks
+		return nil
+	}
+
+	pl, err := CreatePathPlaylist([]PathStep{{From: "A", To: "B", TrackID: "t1"}, {From: "B", To: "C", TrackID: "t2"}}, false)
+	if err != nil || pl.ID != "pl1" {
+		t.Fatalf("got %+v, %v", pl, err)
+	}
+	if gotName != "Six Degrees: A → C" || strings.Join(gotTracks, ",") != "t1,t2" {
+		t.Fatalf("created %q with %v", gotName, gotTracks)
+	}
+}
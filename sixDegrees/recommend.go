@@ -0,0 +1,95 @@
+package sixdegrees
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Weights of the parts of a recommendation's score.
+const (
+	recommendCollabWeight     = 0.5
+	recommendGenreWeight      = 0.3
+	recommendPopularityWeight = 0.2
+)
+
+// DefaultRecommendations is how many recommendations Recommend returns when
+// the caller does not say.
+const DefaultRecommendations = 20
+
+// Recommendation is an artist suggested for a seed, with the path that
+// explains it.
+type Recommendation struct {
+	Artist *Artists `json:"-"`
+	Name   string   `json:"artist"`
+	ID     string   `json:"id,omitempty"`
+	Hops   int      `json:"hops"`
+
+	// Score mixes the parts below, each 0..1 with higher meaning a better fit.
+	Score         float64 `json:"score"`
+	Collab        float64 `json:"collab"`         // shared tracks with the artists leading to it
+	Genre         float64 `json:"genre"`          // genre overlap with the seed
+	PopularityFit float64 `json:"popularity_fit"` // closeness to the seed's popularity
+
+	SharedTracks int        `json:"shared_tracks"` // summed over its edges from one hop closer
+	Path         []PathStep `json:"path"`
+}
+
+// Recommend suggests artists between minHops and maxHops away from seed in
+// the collaboration graph, best first. Candidates are ranked by how strongly
+// they collaborate with the artists one hop closer to seed, how much their
+// genres overlap the seed's, and how close their popularity is to the
+// seed's. opts.Constraints filters candidates and the artists leading to
+// them; a limit of 0 returns DefaultRecommendations, a negative one all.
+func Recommend(seed *Artists, minHops, maxHops, limit int, opts SearchOptions) (*Neighborhood, []Recommendation, error) {
+	if minHops < 1 || maxHops < minHops {
+		return nil, nil, errors.New("need 1 <= min hops <= max hops")
+	}
+	if limit == 0 {
+		limit = DefaultRecommendations
+	}
+	n := ExploreNeighborhood(seed, maxHops, opts)
+
+	// Only edges from one hop closer count, so artists near the seed are
+	// not credited for their ties to each other or to deeper artists.
+	shared := make(map[string]int)
+	for k, ctx := range n.Edges {
+		from, ok := n.Helper.DistTo[k.From]
+		if to, ok2 := n.Helper.DistTo[k.To]; ok && ok2 && from == to-1 {
+			shared[k.To] += ctx.SharedCount
+		}
+	}
+
+	var recs []Recommendation
+	for name, d := range n.Helper.DistTo {
+		if d < minHops || d > maxHops || name == seed.Name {
+			continue
+		}
+		a := n.Helper.ArtistMap[name]
+		r := Recommendation{
+			Artist:        a,
+			Name:          a.Name,
+			ID:            a.ID,
+			Hops:          d,
+			Collab:        1 - 1/(1+float64(shared[name])),
+			Genre:         genreSimilarity(seed.Genres, a.Genres),
+			PopularityFit: 1 - math.Min(1, math.Abs(seed.Popularity-a.Popularity)/100),
+			SharedTracks:  shared[name],
+		}
+		r.Score = recommendCollabWeight*r.Collab + recommendGenreWeight*r.Genre + recommendPopularityWeight*r.PopularityFit
+		recs = append(recs, r)
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].Name < recs[j].Name
+	})
+	if limit > 0 && len(recs) > limit {
+		recs = recs[:limit]
+	}
+	for i := range recs {
+		recs[i].Path = n.PathTo(recs[i].Name)
+	}
+	return n, recs, nil
+}
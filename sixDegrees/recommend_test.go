@@ -0,0 +1,83 @@
+package sixdegrees
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRecommend_RanksAndExplains(t *testing.T) {
+	A, B, C, D, cat := diamond()
+	A.Genres["jazz"] = 1
+	D.Genres["jazz"] = 1
+	A.Popularity, B.Popularity, C.Popularity, D.Popularity = 50, 90, 50, 50
+
+	_, recs, err := Recommend(A, 2, 2, 0, SearchOptions{Catalog: cat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Name != "D" || recs[0].Hops != 2 {
+		t.Fatalf("expected only D two hops out, got %+v", recs)
+	}
+	if recs[0].SharedTracks != 2 || recs[0].Genre != 1 || recs[0].PopularityFit != 1 {
+		t.Fatalf("unexpected score parts %+v", recs[0])
+	}
+	if len(recs[0].Path) != 2 || recs[0].Path[1].To != "D" {
+		t.Fatalf("expected a two-hop explanation, got %+v", recs[0].Path)
+	}
+
+	// B shares more tracks with A, C is closer in popularity; B wins on
+	// collaboration strength.
+	cat.reset([]*Artists{A, B, C, D})
+	_, recs, err = Recommend(A, 1, 1, 1, SearchOptions{Catalog: cat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Name != "B" {
+		t.Fatalf("expected B first, got %+v", recs)
+	}
+}
+
+func TestRecommend_Filters(t *testing.T) {
+	A, B, C, D, cat := diamond()
+	_, recs, err := Recommend(A, 1, 2, -1, SearchOptions{Catalog: cat, Constraints: &Constraints{ExcludeIDs: []string{B.ID}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[0].Name == "B" || recs[1].Name == "B" {
+		t.Fatalf("expected C and D without B, got %+v", recs)
+	}
+	for _, r := range recs {
+		if r.Name == D.Name && (len(r.Path) != 2 || r.Path[0].To != C.Name) {
+			t.Fatalf("D should be reached through C, got %+v", r.Path)
+		}
+	}
+	if _, _, err := Recommend(A, 0, 2, 0, SearchOptions{Catalog: cat}); err == nil {
+		t.Fatalf("expected an error for min hops 0")
+	}
+	if _, _, err := Recommend(A, 3, 2, 0, SearchOptions{Catalog: cat}); err == nil {
+		t.Fatalf("expected an error for min hops above max hops")
+	}
+}
+
+func TestRecommend_CountsOnlyEdgesFromOneHopCloser(t *testing.T) {
+	A, B, C := CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c")
+	cat := newFixtureCatalog()
+	cat.collab(A, B, "ab")
+	cat.collab(A, C, "ac")
+	for i := 0; i < 5; i++ {
+		cat.collab(B, C, fmt.Sprintf("bc%d", i)) // both one hop from A
+	}
+
+	_, recs, err := Recommend(A, 1, 2, -1, SearchOptions{Catalog: cat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected B and C, got %+v", recs)
+	}
+	for _, r := range recs {
+		if r.SharedTracks != 1 {
+			t.Errorf("%s: shared tracks = %d, want only the 1 with A", r.Name, r.SharedTracks)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sixdegrees
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+// fakeSpotify serves a three-artist catalog, A —[ab]→ B —[bc]→ C, in the
+// shape of the Web API endpoints a search uses.
+func fakeSpotify() *httptest.Server {
+	type artist struct {
+		ID         string   `json:"id"`
+		Name       string   `json:"name"`
+		Popularity float64  `json:"popularity"`
+		Genres     []string `json:"genres"`
+	}
+	artists := map[string]artist{
+		"a": {"a", "A", 10, []string{"jazz"}},
+		"b": {"b", "B", 50, []string{"jazz"}},
+		"c": {"c", "C", 90, []string{"soul"}},
+	}
+	type credit struct {
+		Name string `json:"name"`
+		ID   string `json:"id"`
+	}
+	track := func(id, name string, by ...string) map[string]interface{} {
+		var credits []credit
+		for _, a := range by {
+			credits = append(credits, credit{artists[a].Name, a})
+		}
+		return map[string]interface{}{"id": id, "name": name, "artists": credits}
+	}
+	albums := map[string][]map[string]interface{}{
+		"al-a": {track("ab", "AB", "a", "b")},
+		"al-b": {track("bc", "BC", "b", "c")},
+		"al-c": {track("cc", "CC", "c")},
+	}
+	reply := func(w http.ResponseWriter, v interface{}) { json.NewEncoder(w).Encode(v) }
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case r.URL.Path == "/v1/search":
+			var items []artist
+			for _, a := range artists {
+				if a.Name == q.Get("q") {
+					items = append(items, a)
+				}
+			}
+			reply(w, map[string]interface{}{"artists": map[string]interface{}{"items": items}})
+		case r.URL.Path == "/v1/artists":
+			var found []interface{}
+			for _, id := range strings.Split(q.Get("ids"), ",") {
+				found = append(found, artists[id])
+			}
+			reply(w, map[string]interface{}{"artists": found})
+		case len(parts) == 4 && parts[1] == "artists" && parts[3] == "albums":
+			album := map[string]interface{}{"id": "al-" + parts[2], "album_type": "album", "album_group": "album",
+				"release_date": "2001", "artists": []credit{{artists[parts[2]].Name, parts[2]}}}
+			reply(w, map[string]interface{}{"items": []interface{}{album}, "next": nil})
+		case r.URL.Path == "/v1/albums":
+			var found []interface{}
+			for _, id := range strings.Split(q.Get("ids"), ",") {
+				found = append(found, map[string]interface{}{"id": id, "tracks": map[string]interface{}{"items": albums[id], "next": nil}})
+			}
+			reply(w, map[string]interface{}{"albums": found})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRunSearchOpts_ReplaysRecordedSpotify(t *testing.T) {
+	ts := fakeSpotify()
+	defer ts.Close()
+	spotify.SetBaseURL(ts.URL)
+	spotify.SetAccessToken("test")
+	defer func() {
+		spotify.SetBaseURL("")
+		spotify.SetTransport(nil)
+		spotify.SetAccessToken("")
+	}()
+
+	search := func() []PathStep {
+		albumCache = make(map[string][]byte)
+		start, target := InputArtist("A"), InputArtist("C")
+		h, path, ok := RunSearchOpts(start, target, SearchOptions{MaxDepth: -1})
+		if !ok {
+			t.Fatalf("no path found: %+v", h.Diagnosis)
+		}
+		return h.Steps(path)
+	}
+
+	dir := t.TempDir()
+	if _, err := spotify.UseFixtures(dir, spotify.Record); err != nil {
+		t.Fatal(err)
+	}
+	recorded := search()
+	if len(recorded) != 2 || recorded[0].Track != "AB" || recorded[1].Track != "BC" {
+		t.Fatalf("unexpected recorded path %+v", recorded)
+	}
+
+	ts.Close() // replay must not need the network
+	f, err := spotify.UseFixtures(dir, spotify.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := search()
+	if misses := f.Misses(); len(misses) > 0 {
+		t.Fatalf("requests without fixtures: %q", misses)
+	}
+	if len(replayed) != len(recorded) {
+		t.Fatalf("replayed %+v, recorded %+v", replayed, recorded)
+	}
+	for i := range recorded {
+		if replayed[i] != recorded[i] {
+			t.Fatalf("hop %d replayed as %+v, recorded %+v", i, replayed[i], recorded[i])
+		}
+	}
+}
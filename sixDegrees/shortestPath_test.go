@@ -22,10 +22,10 @@ func TestShortestPath(t *testing.T) {
 
 	art := InputArtist("lil wayne")
 	target := InputArtist("YG")
-	albums,_ := spotify.ArtistAlbums(art.ID,10)
+	albums,_ := spotify.ArtistAlbums(art.ID,spotify.FetchOptions{AlbumLimit: 10})
 	h := NewHelper()
 	for _,al := range art.ParseAlbums(albums) {
-		tr,_  := spotify.GetAlbumTracks(al)
+		tr,_  := spotify.GetAlbumTracks(al,spotify.FetchOptions{})
 		T,_ := art.CreateTracks(tr,h)
 		art.Tracks = append(art.Tracks,T...)
 	}
@@ -0,0 +1,713 @@
+package sixdegrees
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Snapshot file layout
+//
+// A snapshot is a magic header followed by a stream of records. Artists are
+// numbered in the order their first node record appears, and later records
+// refer to them by that number, so a file can be extended by appending
+// records without rewriting what is already there.
+//
+//	header:  "SDSNAP" version(byte)
+//	node:    'N' idx(uvarint) name(str) id(str) popularity(float64 LE)
+//	         genres(uvarint) { genre(str) count(uvarint) }
+//	tracks:  'T' idx(uvarint) count(uvarint)
+//	         { name(str) id(str) year(uvarint) flags(byte)
+//	           primary(uvarint, idx+1, 0 = none)
+//	           featured(uvarint) { idx(uvarint) } }
+//
+// Strings are a uvarint byte length followed by the bytes. Track flags bit 0
+// marks compilation tracks and bit 1 guest appearances. Version 1 files lack year and flags; they are
+// still read, and OpenSnapshot rewrites them in the current version. A node
+// record for an index that already exists replaces the artist's metadata; a
+// tracks record replaces the artist's track list. A record cut off by the end
+// of the file, as an interrupted append leaves it, is dropped when the file is
+// opened.
+
+const (
+	snapshotMagic   = "SDSNAP"
+	snapshotVersion = 2
+
+	recNode   = 'N'
+	recTracks = 'T'
+
+	trackCompilation = 1 << 0
+	trackGuest       = 1 << 1
+)
+
+// Snapshot is an append-only on-disk copy of an explored collaboration graph.
+// Load it at startup to start searches warm, and Append after searches to
+// persist what they discovered.
+type Snapshot struct {
+	Path string
+
+	index map[string]int    // artist name -> record index
+	saved map[string]int    // artist name -> number of tracks already written
+	meta  map[string]string // artist name -> artistMeta as last written
+	sum   hash.Hash         // SHA-256 of the file's bytes so far
+}
+
+// OpenSnapshot loads the snapshot at path into h, creating an empty snapshot
+// file if none exists yet. A truncated record at the end of the file is cut
+// off with a warning, keeping every complete record before it.
+func OpenSnapshot(path string, h *Helper) (*Snapshot, error) {
+	s := newSnapshot(path)
+	s.sum = sha256.New()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, s.rewrite(nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	version, end, err := s.read(bufio.NewReaderSize(io.TeeReader(f, s.sum), 1<<20), h)
+	if errors.Is(err, errTornRecord) {
+		logger().Warn("dropping truncated record at the end of the snapshot", "path", path, "offset", end)
+		err = s.truncate(f, end)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	if version < snapshotVersion {
+		// Appending current records to an older file would corrupt it.
+		if err := s.Compact(h); err != nil {
+			return nil, fmt.Errorf("snapshot %s: upgrade: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+// truncate cuts the file off after its first end bytes and hashes what is
+// left.
+func (s *Snapshot) truncate(f *os.File, end int64) error {
+	if err := os.Truncate(s.Path, end); err != nil {
+		return err
+	}
+	s.sum = sha256.New()
+	_, err := io.Copy(s.sum, io.NewSectionReader(f, 0, end))
+	return err
+}
+
+func newSnapshot(path string) *Snapshot {
+	return &Snapshot{Path: path, index: make(map[string]int), saved: make(map[string]int), meta: make(map[string]string)}
+}
+
+// Len reports how many artists the snapshot holds.
+func (s *Snapshot) Len() int { return len(s.index) }
+
+// Version identifies the snapshot's current contents: a prefix of the
+// SHA-256 of its file, kept up to date as records are appended. Two searches
+// warmed from snapshots of the same version started from the same graph.
+func (s *Snapshot) Version() string {
+	if s == nil || s.sum == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.sum.Sum(nil))[:16]
+}
+
+// Append writes every artist in h that is new to the snapshot, has gained
+// tracks or whose ID, popularity or genres changed since it was last
+// written, along with any artists those tracks credit. It returns the number
+// of artists written. The snapshot only counts the records as saved once
+// they are synced to disk; when the write fails, the file is cut back to its
+// old length and the same artists are written again by the next Append.
+func (s *Snapshot) Append(h *Helper) (int, error) {
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	sum := cloneSum(s.sum)
+	out := io.Writer(f)
+	if sum != nil {
+		out = io.MultiWriter(f, sum)
+	}
+	w := bufio.NewWriterSize(out, 1<<20)
+	d, err := s.write(w, h)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Truncate(info.Size())
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	s.apply(d)
+	s.sum = sum
+	return d.n, nil
+}
+
+// Compact rewrites the snapshot from h, dropping superseded records.
+func (s *Snapshot) Compact(h *Helper) error {
+	fresh := newSnapshot(s.Path)
+	if err := fresh.rewrite(h); err != nil {
+		return err
+	}
+	*s = *fresh
+	return nil
+}
+
+func (s *Snapshot) rewrite(h *Helper) error {
+	tmp := s.Path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	sum := sha256.New()
+	w := bufio.NewWriterSize(io.MultiWriter(f, sum), 1<<20)
+	var d snapDelta
+	if _, err = w.WriteString(snapshotMagic); err == nil {
+		err = w.WriteByte(snapshotVersion)
+	}
+	if err == nil && h != nil {
+		d, err = s.write(w, h)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return err
+	}
+	s.apply(d)
+	s.sum = sum
+	return nil
+}
+
+// cloneSum copies a running SHA-256, so bytes can be hashed before they are
+// known to be on disk. It returns nil for nil.
+func cloneSum(sum hash.Hash) hash.Hash {
+	if sum == nil {
+		return nil
+	}
+	state, err := sum.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	c := sha256.New()
+	if err := c.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// WriteSnapshot writes a complete snapshot of h to w.
+func WriteSnapshot(w io.Writer, h *Helper) error {
+	s := newSnapshot("")
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(snapshotVersion); err != nil {
+		return err
+	}
+	if _, err := s.write(bw, h); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadSnapshot loads a snapshot written by WriteSnapshot or Snapshot into h.
+func ReadSnapshot(r io.Reader, h *Helper) error {
+	s := newSnapshot("")
+	_, _, err := s.read(bufio.NewReader(r), h)
+	return err
+}
+
+// ============================== Encoding ===================================
+
+type snapWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (sw *snapWriter) uvarint(v uint64) {
+	if sw.err != nil {
+		return
+	}
+	n := binary.PutUvarint(sw.buf[:], v)
+	_, sw.err = sw.w.Write(sw.buf[:n])
+}
+
+func (sw *snapWriter) str(v string) {
+	sw.uvarint(uint64(len(v)))
+	if sw.err == nil {
+		_, sw.err = sw.w.WriteString(v)
+	}
+}
+
+func (sw *snapWriter) byte(b byte) {
+	if sw.err == nil {
+		sw.err = sw.w.WriteByte(b)
+	}
+}
+
+func (sw *snapWriter) float(v float64) {
+	if sw.err != nil {
+		return
+	}
+	binary.LittleEndian.PutUint64(sw.buf[:8], math.Float64bits(v))
+	_, sw.err = sw.w.Write(sw.buf[:8])
+}
+
+// snapDelta is what a write adds to a snapshot's indexes, track counts and
+// metadata. It is applied once the records are safely written, so a failed
+// write leaves the snapshot as it was.
+type snapDelta struct {
+	index map[string]int
+	saved map[string]int
+	meta  map[string]string
+	n     int // artists written
+}
+
+func (s *Snapshot) apply(d snapDelta) {
+	for name, idx := range d.index {
+		s.index[name] = idx
+	}
+	for name, n := range d.saved {
+		s.saved[name] = n
+	}
+	for name, meta := range d.meta {
+		s.meta[name] = meta
+	}
+}
+
+// write encodes the records h adds to the snapshot without changing s; apply
+// the returned delta once they are on disk.
+func (s *Snapshot) write(w *bufio.Writer, h *Helper) (snapDelta, error) {
+	sw := &snapWriter{w: w}
+	d := snapDelta{index: make(map[string]int), saved: make(map[string]int), meta: make(map[string]string)}
+	indexOf := func(name string) (int, bool) {
+		if idx, ok := d.index[name]; ok {
+			return idx, true
+		}
+		idx, ok := s.index[name]
+		return idx, ok
+	}
+	metaOf := func(name string) string {
+		if meta, ok := d.meta[name]; ok {
+			return meta
+		}
+		return s.meta[name]
+	}
+
+	// Sort for stable files: the same graph always produces the same bytes.
+	names := make([]string, 0, len(h.ArtistMap))
+	for name := range h.ArtistMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	touched := make(map[string]bool)
+	// node writes a node record for a unless the snapshot already holds a
+	// with the same metadata, and returns a's index.
+	node := func(a *Artists) int {
+		// Tracks may credit a stand-in with less metadata than h's artist.
+		if known := h.ArtistMap[a.Name]; known != nil {
+			a = known
+		}
+		meta := artistMeta(a)
+		idx, ok := indexOf(a.Name)
+		if ok && metaOf(a.Name) == meta {
+			return idx
+		}
+		if !ok {
+			idx = len(s.index) + len(d.index)
+			d.index[a.Name] = idx
+		}
+		d.meta[a.Name] = meta
+		touched[a.Name] = true
+		sw.byte(recNode)
+		sw.uvarint(uint64(idx))
+		sw.str(a.Name)
+		sw.str(a.ID)
+		sw.float(a.Popularity)
+		genres := sortedGenres(a.Genres)
+		sw.uvarint(uint64(len(genres)))
+		for _, g := range genres {
+			sw.str(g)
+			sw.uvarint(uint64(a.Genres[g]))
+		}
+		return idx
+	}
+
+	for _, name := range names {
+		a := h.ArtistMap[name]
+		if a == nil || a.Name == "" {
+			continue
+		}
+		_, known := s.index[a.Name]
+		if known && s.saved[a.Name] >= len(a.Tracks) {
+			node(a) // metadata may still have changed
+			continue
+		}
+		idx := node(a)
+		if len(a.Tracks) == 0 {
+			continue
+		}
+		// Every referenced artist needs a node record before the tracks record.
+		for _, t := range a.Tracks {
+			if t.Artist != nil && t.Artist.Name != "" {
+				node(t.Artist)
+			}
+			for _, f := range t.Featured {
+				if f != nil && f.Name != "" {
+					node(f)
+				}
+			}
+		}
+		sw.byte(recTracks)
+		sw.uvarint(uint64(idx))
+		sw.uvarint(uint64(len(a.Tracks)))
+		for _, t := range a.Tracks {
+			sw.str(t.Name)
+			sw.str(t.ID)
+			sw.uvarint(uint64(t.Year))
+			var flags byte
+			if t.Compilation {
+				flags |= trackCompilation
+			}
+			if t.Guest {
+				flags |= trackGuest
+			}
+			sw.byte(flags)
+			if t.Artist != nil && t.Artist.Name != "" {
+				idx, _ := indexOf(t.Artist.Name)
+				sw.uvarint(uint64(idx) + 1)
+			} else {
+				sw.uvarint(0)
+			}
+			var feat []int
+			for _, f := range t.Featured {
+				if f != nil && f.Name != "" {
+					fi, _ := indexOf(f.Name)
+					feat = append(feat, fi)
+				}
+			}
+			sw.uvarint(uint64(len(feat)))
+			for _, fi := range feat {
+				sw.uvarint(uint64(fi))
+			}
+		}
+		d.saved[a.Name] = len(a.Tracks)
+		touched[a.Name] = true
+	}
+	d.n = len(touched)
+	return d, sw.err
+}
+
+// artistMeta summarizes the metadata a node record holds, to tell whether an
+// artist changed since it was written.
+func artistMeta(a *Artists) string {
+	var b strings.Builder
+	b.WriteString(a.ID)
+	b.WriteByte(0)
+	b.WriteString(strconv.FormatFloat(a.Popularity, 'g', -1, 64))
+	for _, g := range sortedGenres(a.Genres) {
+		b.WriteByte(0)
+		b.WriteString(g)
+		b.WriteByte('=')
+		b.WriteString(strconv.Itoa(a.Genres[g]))
+	}
+	return b.String()
+}
+
+func sortedGenres(genres map[string]int) []string {
+	out := make([]string, 0, len(genres))
+	for g := range genres {
+		out = append(out, g)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ============================== Decoding ===================================
+
+// Limits on the counts a record may declare, so a corrupt file fails to load
+// instead of allocating without bound.
+const (
+	maxSnapshotGenres   = 1 << 10 // genres per artist
+	maxSnapshotTracks   = 1 << 20 // tracks per artist
+	maxSnapshotFeatured = 1 << 12 // featured artists per track
+)
+
+var (
+	errBadSnapshot = errors.New("corrupt snapshot")
+	errTornRecord  = errors.New("truncated snapshot record")
+)
+
+// countingReader counts the bytes read through it, to tell where the last
+// complete record ends.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+type snapReader struct {
+	r *countingReader
+}
+
+func (sr snapReader) uvarint() (uint64, error) { return binary.ReadUvarint(sr.r) }
+
+func (sr snapReader) str() (string, error) {
+	n, err := sr.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if n > 1<<20 {
+		return "", errBadSnapshot
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(sr.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (sr snapReader) float() (float64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(sr.r, b[:]); err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+}
+
+// read loads a snapshot into h and returns the file's format version and the
+// length of its complete records. A record cut off by the end of the input
+// is not loaded and fails with errTornRecord.
+func (s *Snapshot) read(br *bufio.Reader, h *Helper) (version int, end int64, err error) {
+	r := &countingReader{r: br}
+	sr := snapReader{r: r}
+	head := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, 0, err
+	}
+	if string(head[:len(snapshotMagic)]) != snapshotMagic {
+		return 0, 0, errBadSnapshot
+	}
+	version = int(head[len(snapshotMagic)])
+	if version < 1 || version > snapshotVersion {
+		return 0, 0, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	// Records are only applied once read in full, so input ending inside
+	// one leaves everything before it loaded.
+	defer func() {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = errTornRecord
+		}
+	}()
+
+	var byIdx []*Artists
+	artist := func(idx uint64) (*Artists, error) {
+		if idx >= uint64(len(byIdx)) || byIdx[idx] == nil {
+			return nil, errBadSnapshot
+		}
+		return byIdx[idx], nil
+	}
+
+	for {
+		end = r.n
+		kind, err := r.ReadByte()
+		if err == io.EOF {
+			return version, end, nil
+		}
+		if err != nil {
+			return version, end, err
+		}
+		switch kind {
+		case recNode:
+			idx, err := sr.uvarint()
+			if err != nil {
+				return version, end, err
+			}
+			name, err := sr.str()
+			if err != nil {
+				return version, end, err
+			}
+			id, err := sr.str()
+			if err != nil {
+				return version, end, err
+			}
+			pop, err := sr.float()
+			if err != nil {
+				return version, end, err
+			}
+			ng, err := sr.uvarint()
+			if err != nil {
+				return version, end, err
+			}
+			if ng > maxSnapshotGenres {
+				return version, end, errBadSnapshot
+			}
+			genres := make(map[string]int, ng)
+			for i := uint64(0); i < ng; i++ {
+				g, err := sr.str()
+				if err != nil {
+					return version, end, err
+				}
+				c, err := sr.uvarint()
+				if err != nil {
+					return version, end, err
+				}
+				genres[g] = int(c)
+			}
+
+			// A later record for an artist of this file replaces its
+			// metadata; one already in h only has its gaps filled.
+			_, update := s.index[name]
+			a, ok := h.ArtistMap[name]
+			if !ok {
+				a = CreateArtists(name, id)
+				h.ArtistMap[name] = a
+			}
+			if a.ID == "" || update && id != "" {
+				a.ID = id
+			}
+			a.Popularity = pop
+			if len(genres) > 0 || update {
+				a.Genres = genres
+			}
+			if a.Genres == nil {
+				a.Genres = make(map[string]int)
+			}
+			switch {
+			case idx == uint64(len(byIdx)):
+				byIdx = append(byIdx, a)
+			case idx < uint64(len(byIdx)):
+				byIdx[idx] = a
+			default:
+				return version, end, errBadSnapshot
+			}
+			s.index[name] = int(idx)
+			s.meta[name] = artistMeta(&Artists{ID: id, Popularity: pop, Genres: genres})
+
+		case recTracks:
+			idx, err := sr.uvarint()
+			if err != nil {
+				return version, end, err
+			}
+			owner, err := artist(idx)
+			if err != nil {
+				return version, end, err
+			}
+			n, err := sr.uvarint()
+			if err != nil {
+				return version, end, err
+			}
+			if n > maxSnapshotTracks {
+				return version, end, errBadSnapshot
+			}
+			tracks := make([]Track, 0, min(n, 256))
+			for i := uint64(0); i < n; i++ {
+				name, err := sr.str()
+				if err != nil {
+					return version, end, err
+				}
+				id, err := sr.str()
+				if err != nil {
+					return version, end, err
+				}
+				var year uint64
+				var flags byte
+				if version >= 2 {
+					if year, err = sr.uvarint(); err != nil {
+						return version, end, err
+					}
+					if flags, err = r.ReadByte(); err != nil {
+						return version, end, err
+					}
+				}
+				p, err := sr.uvarint()
+				if err != nil {
+					return version, end, err
+				}
+				var primary *Artists
+				if p > 0 {
+					if primary, err = artist(p - 1); err != nil {
+						return version, end, err
+					}
+				}
+				nf, err := sr.uvarint()
+				if err != nil {
+					return version, end, err
+				}
+				if nf > maxSnapshotFeatured {
+					return version, end, errBadSnapshot
+				}
+				feat := make([]*Artists, 0, min(nf, 16))
+				for j := uint64(0); j < nf; j++ {
+					fi, err := sr.uvarint()
+					if err != nil {
+						return version, end, err
+					}
+					f, err := artist(fi)
+					if err != nil {
+						return version, end, err
+					}
+					feat = append(feat, f)
+				}
+				t := newTrack(primary, name, "", id, feat)
+				t.Year = int(year)
+				t.Compilation = flags&trackCompilation != 0
+				t.Guest = flags&trackGuest != 0
+				tracks = append(tracks, t)
+			}
+			owner.Tracks = tracks
+			s.saved[owner.Name] = len(tracks)
+
+		default:
+			return version, end, errBadSnapshot
+		}
+	}
+}
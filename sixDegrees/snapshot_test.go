@@ -0,0 +1,320 @@
+package sixdegrees
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func snapshotFixture() *Helper {
+	h := NewHelper()
+	A := &Artists{Name: "A", ID: "a1", Popularity: 42, Genres: map[string]int{"rap": 2}}
+	B := &Artists{Name: "B", ID: "b1", Popularity: 7}
+	C := &Artists{Name: "C", ID: "c1"}
+	A.Tracks = []Track{{Artist: A, Name: "t1", ID: "tid1", Featured: []*Artists{B, C}}}
+	h.ArtistMap["A"] = A
+	h.ArtistMap["B"] = B
+	return h
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, snapshotFixture()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	h := NewHelper()
+	if err := ReadSnapshot(&buf, h); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	A, ok := h.ArtistMap["A"]
+	if !ok || A.ID != "a1" || A.Popularity != 42 || A.Genres["rap"] != 2 {
+		t.Fatalf("artist A not restored: %+v", A)
+	}
+	if len(A.Tracks) != 1 || A.Tracks[0].ID != "tid1" || A.Tracks[0].Artist != A {
+		t.Fatalf("tracks not restored: %+v", A.Tracks)
+	}
+	feat := A.Tracks[0].Featured
+	if len(feat) != 2 || feat[0] != h.ArtistMap["B"] || feat[1] != h.ArtistMap["C"] {
+		t.Fatalf("featured artists should resolve to shared nodes: %+v", feat)
+	}
+	if h.ArtistMap["B"].Popularity != 7 {
+		t.Fatalf("metadata for B not restored: %+v", h.ArtistMap["B"])
+	}
+}
+
+func TestSnapshot_AppendOnlyWritesChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.snap")
+	h := snapshotFixture()
+	snap, err := OpenSnapshot(path, NewHelper())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if n, err := snap.Append(h); err != nil || n != 3 {
+		t.Fatalf("first append wrote %d artists (err %v), want 3", n, err)
+	}
+	if n, err := snap.Append(h); err != nil || n != 0 {
+		t.Fatalf("unchanged append wrote %d artists (err %v), want 0", n, err)
+	}
+
+	// B gets expanded later; only B and its new collaborator D should be appended.
+	B := h.ArtistMap["B"]
+	D := &Artists{Name: "D", ID: "d1"}
+	B.Tracks = []Track{{Artist: B, Name: "t2", Featured: []*Artists{D}}}
+	if n, err := snap.Append(h); err != nil || n != 2 {
+		t.Fatalf("incremental append wrote %d artists (err %v), want 2", n, err)
+	}
+
+	warm := NewHelper()
+	reopened, err := OpenSnapshot(path, warm)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if reopened.Len() != 4 {
+		t.Fatalf("expected 4 artists in snapshot, got %d", reopened.Len())
+	}
+	if got := warm.ArtistMap["B"]; len(got.Tracks) != 1 || got.Tracks[0].Featured[0] != warm.ArtistMap["D"] {
+		t.Fatalf("appended tracks for B not loaded: %+v", got)
+	}
+
+	if err := reopened.Compact(warm); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	again := NewHelper()
+	if _, err := OpenSnapshot(path, again); err != nil {
+		t.Fatalf("open compacted: %v", err)
+	}
+	if len(again.ArtistMap["A"].Tracks) != 1 || len(again.ArtistMap["B"].Tracks) != 1 {
+		t.Fatalf("compacted snapshot lost tracks")
+	}
+}
+
+func TestSnapshot_RejectsGarbage(t *testing.T) {
+	if err := ReadSnapshot(bytes.NewReader([]byte("not a snapshot")), NewHelper()); err == nil {
+		t.Fatalf("expected error for invalid snapshot")
+	}
+}
+
+func TestSnapshot_AppendUpdatesMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.snap")
+	h := snapshotFixture()
+	snap, err := OpenSnapshot(path, NewHelper())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := snap.Append(h); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	A := h.ArtistMap["A"]
+	A.ID, A.Popularity, A.Genres = "a2", 50, map[string]int{"pop": 1}
+	if n, err := snap.Append(h); err != nil || n != 1 {
+		t.Fatalf("metadata append wrote %d artists (err %v), want 1", n, err)
+	}
+	if n, err := snap.Append(h); err != nil || n != 0 {
+		t.Fatalf("unchanged append wrote %d artists (err %v), want 0", n, err)
+	}
+
+	warm := NewHelper()
+	if _, err := OpenSnapshot(path, warm); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got := warm.ArtistMap["A"]
+	if got.ID != "a2" || got.Popularity != 50 || len(got.Genres) != 1 || got.Genres["pop"] != 1 {
+		t.Fatalf("updated metadata not loaded: %+v", got)
+	}
+	if len(got.Tracks) != 1 {
+		t.Fatalf("metadata update lost A's tracks: %+v", got.Tracks)
+	}
+}
+
+func TestSnapshot_RejectsOversizedCounts(t *testing.T) {
+	node := func(genres uint64) []byte {
+		b := append([]byte(snapshotMagic), snapshotVersion, recNode, 0, 1, 'A', 0)
+		b = append(b, make([]byte, 8)...) // popularity
+		return binary.AppendUvarint(b, genres)
+	}
+	tracks := binary.AppendUvarint(append(node(0), recTracks, 0), 1<<40)
+	featured := append(node(0), recTracks, 0, 1, 0, 0, 0, 0, 0)
+	featured = binary.AppendUvarint(featured, 1<<40)
+
+	for name, b := range map[string][]byte{"genres": node(1 << 40), "tracks": tracks, "featured": featured} {
+		if err := ReadSnapshot(bytes.NewReader(b), NewHelper()); err == nil {
+			t.Errorf("%s: expected an error for an oversized count", name)
+		}
+	}
+}
+
+func TestRunSearchOpts_MergesIntoKnown(t *testing.T) {
+	A := &Artists{Name: "A"}
+	B := &Artists{Name: "B"}
+	C := &Artists{Name: "C"}
+	A.Tracks = []Track{{Artist: A, Name: "t1", Featured: []*Artists{B}}}
+	B.Tracks = []Track{{Artist: B, Name: "t2", Featured: []*Artists{C}}}
+
+	known := NewHelper()
+	_, _, found := RunSearchOpts(A, C, SearchOptions{MaxDepth: -1, Known: known})
+	if !found {
+		t.Fatalf("expected to find C")
+	}
+	for _, name := range []string{"A", "B"} {
+		if known.ArtistMap[name] == nil {
+			t.Fatalf("expected %s to be merged into the known artists", name)
+		}
+	}
+}
+
+// BenchmarkReadSnapshot loads a 200k-artist graph where each artist has a
+// handful of tracks with two collaborators.
+func BenchmarkReadSnapshot(b *testing.B) {
+	const n = 200000
+	src := NewHelper()
+	artists := make([]*Artists, n)
+	for i := range artists {
+		artists[i] = CreateArtists(fmt.Sprintf("artist-%d", i), fmt.Sprintf("id-%d", i))
+		src.ArtistMap[artists[i].Name] = artists[i]
+	}
+	for i, a := range artists {
+		for j := 1; j <= 4; j++ {
+			feat := []*Artists{artists[(i+j)%n], artists[(i*7+j)%n]}
+			a.Tracks = append(a.Tracks, newTrack(a, fmt.Sprintf("track-%d-%d", i, j), "", "", feat))
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, src); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ReadSnapshot(bytes.NewReader(buf.Bytes()), NewHelper()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSnapshot_KeepsTrackMetadata(t *testing.T) {
+	h := snapshotFixture()
+	A := h.ArtistMap["A"]
+	A.Tracks[0].Year, A.Tracks[0].Compilation, A.Tracks[0].Guest = 1997, true, true
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, h); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got := NewHelper()
+	if err := ReadSnapshot(&buf, got); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if tr := got.ArtistMap["A"].Tracks[0]; tr.Year != 1997 || !tr.Compilation || !tr.Guest {
+		t.Fatalf("track metadata not restored: %+v", tr)
+	}
+}
+
+func TestSnapshot_VersionFollowsContents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.snap")
+	snap, err := OpenSnapshot(path, NewHelper())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	empty := snap.Version()
+	if _, err := snap.Append(snapshotFixture()); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if snap.Version() == empty {
+		t.Fatal("version should change when records are appended")
+	}
+	reopened, err := OpenSnapshot(path, NewHelper())
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if reopened.Version() != snap.Version() {
+		t.Fatalf("reopened version %s, want %s", reopened.Version(), snap.Version())
+	}
+}
+
+func TestSnapshot_DropsTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.snap")
+	h := snapshotFixture()
+	snap, err := OpenSnapshot(path, NewHelper())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := snap.Append(h); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	complete := info.Size()
+
+	// An append interrupted halfway through B's tracks record.
+	B := h.ArtistMap["B"]
+	B.Tracks = []Track{{Artist: B, Name: "t2", ID: "tid2", Featured: []*Artists{h.ArtistMap["A"]}}}
+	if _, err := snap.Append(h); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := os.Truncate(path, complete+5); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	warm := NewHelper()
+	reopened, err := OpenSnapshot(path, warm)
+	if err != nil {
+		t.Fatalf("reopen torn snapshot: %v", err)
+	}
+	if len(warm.ArtistMap["A"].Tracks) != 1 || len(warm.ArtistMap["B"].Tracks) != 0 {
+		t.Fatalf("expected A's tracks and none of B's, got %+v and %+v", warm.ArtistMap["A"].Tracks, warm.ArtistMap["B"].Tracks)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != complete {
+		t.Fatalf("torn record not cut off: size %d, want %d (err %v)", info.Size(), complete, err)
+	}
+
+	// Appending after the cut writes B's tracks again.
+	warm.ArtistMap["B"].Tracks = B.Tracks
+	if n, err := reopened.Append(warm); err != nil || n != 1 {
+		t.Fatalf("append after recovery wrote %d artists (err %v), want 1", n, err)
+	}
+	again := NewHelper()
+	if _, err := OpenSnapshot(path, again); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if len(again.ArtistMap["B"].Tracks) != 1 {
+		t.Fatalf("B's tracks not appended after recovery: %+v", again.ArtistMap["B"])
+	}
+}
+
+func TestSnapshot_FailedAppendKeepsState(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("needs /dev/full")
+	}
+	path := filepath.Join(t.TempDir(), "graph.snap")
+	snap, err := OpenSnapshot(path, NewHelper())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	version := snap.Version()
+
+	h := snapshotFixture()
+	snap.Path = "/dev/full"
+	if _, err := snap.Append(h); err == nil {
+		t.Fatalf("expected appending to a full device to fail")
+	}
+	if snap.Len() != 0 || snap.Version() != version {
+		t.Fatalf("failed append changed the snapshot: %d artists, version %s", snap.Len(), snap.Version())
+	}
+
+	snap.Path = path
+	if n, err := snap.Append(h); err != nil || n != 3 {
+		t.Fatalf("retried append wrote %d artists (err %v), want 3", n, err)
+	}
+	warm := NewHelper()
+	if _, err := OpenSnapshot(path, warm); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if len(warm.ArtistMap["A"].Tracks) != 1 {
+		t.Fatalf("retried append did not save A's tracks")
+	}
+}
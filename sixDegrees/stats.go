@@ -0,0 +1,115 @@
+package sixdegrees
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+// SearchStats describes the work one search did. Every search entry point
+// leaves its stats in the returned Helper.
+type SearchStats struct {
+	Dequeued      int `json:"dequeued"`       // artists taken off the search queue
+	Expanded      int `json:"expanded"`       // artists whose tracks were fetched
+	AlbumsFetched int `json:"albums_fetched"` // albums whose tracks were requested from Spotify
+	TracksFetched int `json:"tracks_fetched"` // tracks added by expansions
+	CacheHits     int `json:"cache_hits"`     // album track lists already in memory
+	CacheMisses   int `json:"cache_misses"`   // album track lists requested from Spotify
+
+	// APICalls counts Spotify requests by endpoint. Counts come from
+	// process-wide counters, so searches running at the same time include
+	// each other's requests.
+	APICalls      map[string]int `json:"api_calls"`
+	ThrottleWaits int            `json:"throttle_waits"` // pauses Spotify asked for (429 Retry-After)
+	ThrottleMS    float64        `json:"throttle_ms"`
+
+	// PhaseMS is the wall time of each phase in milliseconds. Phases may
+	// nest: "expand" is part of "bfs" or "astar".
+	PhaseMS map[string]float64 `json:"phase_ms"`
+}
+
+func newSearchStats() *SearchStats {
+	return &SearchStats{APICalls: make(map[string]int), PhaseMS: make(map[string]float64)}
+}
+
+// TotalAPICalls sums APICalls over every endpoint.
+func (s *SearchStats) TotalAPICalls() int {
+	n := 0
+	for _, c := range s.APICalls {
+		n += c
+	}
+	return n
+}
+
+func (s *SearchStats) addPhase(name string, d time.Duration) {
+	s.PhaseMS[name] += float64(d) / float64(time.Millisecond)
+}
+
+func (s *SearchStats) addCalls(c spotify.CallStats) {
+	for k, v := range c.Calls {
+		s.APICalls[k] += v
+	}
+	s.ThrottleWaits += c.ThrottleWaits
+	s.ThrottleMS += float64(c.Throttled) / float64(time.Millisecond)
+}
+
+// Add folds other into s, e.g. the legs of a waypoint search.
+func (s *SearchStats) Add(other *SearchStats) {
+	if other == nil {
+		return
+	}
+	s.Dequeued += other.Dequeued
+	s.Expanded += other.Expanded
+	s.AlbumsFetched += other.AlbumsFetched
+	s.TracksFetched += other.TracksFetched
+	s.CacheHits += other.CacheHits
+	s.CacheMisses += other.CacheMisses
+	for k, v := range other.APICalls {
+		s.APICalls[k] += v
+	}
+	s.ThrottleWaits += other.ThrottleWaits
+	s.ThrottleMS += other.ThrottleMS
+	for k, v := range other.PhaseMS {
+		s.PhaseMS[k] += v
+	}
+}
+
+// measure starts timing a search phase and its Spotify requests. Call the
+// returned function when the phase ends.
+func (s *SearchStats) measure(phase string) func() {
+	began, calls := time.Now(), spotify.Stats()
+	return func() {
+		s.addPhase(phase, time.Since(began))
+		s.addCalls(spotify.Stats().Since(calls))
+	}
+}
+
+// traceEvent is one line of a search trace.
+type traceEvent struct {
+	Time   time.Time `json:"time"`
+	Seq    int       `json:"seq"` // expansion order, from 1
+	Artist string    `json:"artist"`
+	Depth  int       `json:"depth"`
+	Tracks int       `json:"tracks"`
+	TookMS float64   `json:"took_ms"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// trace appends one JSON line describing the expansion of a to w.
+func trace(w io.Writer, seq int, a *Artists, depth int, took time.Duration, err error) {
+	ev := traceEvent{
+		Time:   time.Now(),
+		Seq:    seq,
+		Artist: a.Name,
+		Depth:  depth,
+		Tracks: len(a.Tracks),
+		TookMS: float64(took) / float64(time.Millisecond),
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	line, _ := json.Marshal(ev)
+	w.Write(append(line, '\n'))
+}
@@ -0,0 +1,97 @@
+package sixdegrees
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Jonnymurillo288/SixDegreesSpotify/spotify"
+)
+
+func TestSearchStats_CountExpansionsAndTrace(t *testing.T) {
+	A, _, _, D, cat := diamond()
+	var buf bytes.Buffer
+	h, path, ok := RunSearchOpts(A, D, SearchOptions{MaxDepth: -1, Catalog: cat, Trace: &buf})
+	if !ok {
+		t.Fatalf("expected a path, got %v", path)
+	}
+	s := h.Stats
+	if s.Expanded != len(cat.fetched) || s.Expanded == 0 {
+		t.Fatalf("expanded %d artists, catalog fetched %d", s.Expanded, len(cat.fetched))
+	}
+	if s.Dequeued == 0 || s.TracksFetched == 0 {
+		t.Fatalf("expected dequeues and tracks, got %+v", s)
+	}
+	if _, ok := s.PhaseMS["bfs"]; !ok {
+		t.Fatalf("missing bfs phase: %+v", s.PhaseMS)
+	}
+
+	var events []traceEvent
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var ev traceEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatalf("bad trace line %q: %v", sc.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != s.Expanded || events[0].Artist != "A" || events[0].Seq != 1 || events[0].Tracks != 3 {
+		t.Fatalf("unexpected trace %+v", events)
+	}
+}
+
+func TestSearchStats_EveryEntryPoint(t *testing.T) {
+	A, B, C, D, cat := diamond()
+	all := []*Artists{A, B, C, D}
+
+	h, _, ok := RunWeightedSearch(A, D, CollabStrengthStrategy{}, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok || h.Stats.Expanded == 0 {
+		t.Fatalf("weighted: no stats %+v", h.Stats)
+	}
+	for _, phase := range []string{"bfs", "graph", "dijkstra"} {
+		if _, ok := h.Stats.PhaseMS[phase]; !ok {
+			t.Fatalf("weighted: missing phase %q in %+v", phase, h.Stats.PhaseMS)
+		}
+	}
+
+	cat.reset(all)
+	h, _, ok = RunAStarSearch(A, D, CollabStrengthStrategy{}, nil, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok || h.Stats.Expanded != len(cat.fetched) || h.Stats.Dequeued == 0 {
+		t.Fatalf("astar: unexpected stats %+v (fetched %d)", h.Stats, len(cat.fetched))
+	}
+
+	// Waypoint searches add up their legs.
+	cat.reset(all)
+	c := &Constraints{Waypoints: []*Artists{C}}
+	h, _, ok = RunAStarSearch(A, D, CollabStrengthStrategy{}, nil, SearchOptions{MaxDepth: -1, Catalog: cat, Constraints: c})
+	if !ok || h.Stats.Expanded == 0 || h.Stats.Dequeued < 2 {
+		t.Fatalf("waypoints: unexpected stats %+v", h.Stats)
+	}
+}
+
+func TestSearchStats_CountsOnlyAlbumsFetchedFromSpotify(t *testing.T) {
+	oldAlbums, oldArtistAlbums := fetchAlbums, fetchArtistAlbums
+	defer func() { fetchAlbums, fetchArtistAlbums = oldAlbums, oldArtistAlbums }()
+	defer func() { delete(albumCache, "s1"); delete(albumCache, "s2") }()
+	fetchArtistAlbums = func(string, spotify.FetchOptions) ([]byte, error) {
+		return []byte(`{"items":[{"id":"s1","album_group":"album"},{"id":"s2","album_group":"album"}]}`), nil
+	}
+	fetchAlbums = func([]string, spotify.FetchOptions) ([]byte, error) {
+		return []byte(`{"albums":[{"id":"s2","tracks":{"items":[],"next":null}}]}`), nil
+	}
+	albumCache["s1"] = []byte(`{"items":[]}`)
+
+	h := NewHelper()
+	found := false
+	if err := enrichArtist(CreateArtists("A", "a"), h, "T", &found, spotify.FetchOptions{}); err != nil {
+		t.Fatalf("enrich: %v", err)
+	}
+	s := h.Stats
+	if s.CacheHits != 1 || s.CacheMisses != 1 || s.AlbumsFetched != 1 {
+		t.Fatalf("expected 1 hit, 1 miss and 1 fetched album, got %+v", s)
+	}
+	if s.ThrottleWaits != 0 || s.ThrottleMS != 0 {
+		t.Fatalf("the courtesy pause is not throttling: %+v", s)
+	}
+}
@@ -0,0 +1,90 @@
+package synth
+
+import (
+	"testing"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+// Benchmarks run each search over the same generated catalogs and endpoint
+// pairs, reporting time per search and how many artists it fetched through
+// the catalog (fetches/search). Catalogs are generated once per size and
+// shared between benchmarks. At 1M artists a weighted search takes seconds,
+// so pick sizes and iterations explicitly, e.g.
+//
+//	go test ./sixDegrees/synth -run XXX -bench . -benchtime 8x
+//	go test ./sixDegrees/synth -run XXX -bench /100k
+
+const benchPairs = 8
+
+var benchCatalogs = make(map[int]*Catalog)
+
+func benchCatalog(n int) *Catalog {
+	if c, ok := benchCatalogs[n]; ok {
+		return c
+	}
+	c := Generate(Config{Artists: n, Seed: 1})
+	benchCatalogs[n] = c
+	return c
+}
+
+var benchSizes = []struct {
+	name string
+	n    int
+}{
+	{"10k", 10_000},
+	{"100k", 100_000},
+	{"1M", 1_000_000},
+}
+
+func benchmarkSearch(b *testing.B, search func(start, target *sixdegrees.Artists, c *Catalog) bool) {
+	for _, size := range benchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			c := benchCatalog(size.n)
+			pairs := c.Pairs(benchPairs, 2)
+			fetched := 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p := pairs[i%len(pairs)]
+				b.StopTimer()
+				c.Reset()
+				b.StartTimer()
+				if !search(p[0], p[1], c) {
+					b.Fatalf("no path between %s and %s", p[0].Name, p[1].Name)
+				}
+				fetched += c.Fetched
+			}
+			b.ReportMetric(float64(fetched)/float64(b.N), "fetches/search")
+		})
+	}
+}
+
+func BenchmarkBFS(b *testing.B) {
+	benchmarkSearch(b, func(start, target *sixdegrees.Artists, c *Catalog) bool {
+		_, _, ok := sixdegrees.RunSearchOpts(start, target, sixdegrees.SearchOptions{MaxDepth: -1, Catalog: c})
+		return ok
+	})
+}
+
+func BenchmarkBidirectional(b *testing.B) {
+	benchmarkSearch(b, func(start, target *sixdegrees.Artists, c *Catalog) bool {
+		_, _, ok := sixdegrees.RunBidirectionalSearch(start, target, sixdegrees.SearchOptions{MaxDepth: -1, Catalog: c})
+		return ok
+	})
+}
+
+func BenchmarkDijkstra(b *testing.B) {
+	benchmarkSearch(b, func(start, target *sixdegrees.Artists, c *Catalog) bool {
+		_, _, ok := sixdegrees.RunWeightedSearch(start, target, sixdegrees.CollabStrengthStrategy{}, sixdegrees.SearchOptions{MaxDepth: -1, Catalog: c})
+		return ok
+	})
+}
+
+// Repeated collaborations cost less than 1 under CollabStrengthStrategy, so
+// the genre heuristic is scaled down to stay close to admissible.
+func BenchmarkAStar(b *testing.B) {
+	benchmarkSearch(b, func(start, target *sixdegrees.Artists, c *Catalog) bool {
+		_, _, ok := sixdegrees.RunAStarSearch(start, target, sixdegrees.CollabStrengthStrategy{}, sixdegrees.GenreOverlapHeuristic{Scale: 0.5}, sixdegrees.SearchOptions{MaxDepth: -1, Catalog: c})
+		return ok
+	})
+}
@@ -0,0 +1,432 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+// Config describes a synthetic collaboration graph. Zero fields take the
+// defaults noted beside them.
+type Config struct {
+	Artists int   // artists in the catalog; 10000
+	Seed    int64 // the same seed always generates the same catalog
+
+	// Genres is the number of genre clusters. Each artist belongs to one,
+	// and a quarter also list the next cluster's genre; 20.
+	Genres int
+
+	// MeanDegree is the average number of collaborations per artist; 6.
+	// Collaborations may repeat, which strengthens the edge for
+	// CollabStrengthStrategy.
+	MeanDegree float64
+
+	// Exponent of the power law that collaboration counts follow; 2.5.
+	// Smaller exponents give fewer, busier hubs.
+	Exponent float64
+
+	// CrossGenre is the share of collaborations between clusters; 0.1.
+	CrossGenre float64
+
+	// Compilations is the number of tracks crediting CompilationSize
+	// artists at once, marked as compilation tracks; Artists/2000 and 12.
+	Compilations    int
+	CompilationSize int
+
+	// SameName is the share of artists named after an earlier artist, as
+	// distinct artists with the same name are on Spotify; 0.01. A negative
+	// value gives every artist its own name.
+	SameName float64
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Artists <= 0 {
+		cfg.Artists = 10000
+	}
+	if cfg.Genres <= 0 {
+		cfg.Genres = 20
+	}
+	if cfg.MeanDegree <= 0 {
+		cfg.MeanDegree = 6
+	}
+	if cfg.Exponent <= 1 {
+		cfg.Exponent = 2.5
+	}
+	if cfg.CrossGenre <= 0 {
+		cfg.CrossGenre = 0.1
+	}
+	if cfg.Compilations == 0 {
+		cfg.Compilations = cfg.Artists / 2000
+	}
+	if cfg.CompilationSize <= 0 {
+		cfg.CompilationSize = 12
+	}
+	if cfg.SameName == 0 {
+		cfg.SameName = 0.01
+	}
+	return cfg
+}
+
+// Catalog is a generated collaboration graph behind the sixdegrees.Catalog
+// interface. The graph is kept as compact adjacency lists; artists and their
+// tracks are only built when a search reaches them, so catalogs of millions
+// of artists fit in memory. A Catalog is not safe for concurrent searches.
+type Catalog struct {
+	cfg    Config
+	weight []float64 // collaboration propensity; degrees follow it
+
+	trackStart []int32 // credits of track t are credits[trackStart[t]:trackStart[t+1]]
+	credits    []int32 // primary artist first
+	year       []int16
+	comp       []bool
+
+	artistStart  []int32 // tracks of artist i are artistTracks[artistStart[i]:artistStart[i+1]]
+	artistTracks []int32
+
+	artists  []*sixdegrees.Artists // built on first use
+	expanded []int32               // artists whose tracks were handed out
+
+	component []int32 // component label of each artist; built by Pairs
+
+	Fetched int // artists expanded since the last Reset
+}
+
+// Generate builds the catalog cfg describes. Each collaboration picks its
+// artists in proportion to their weight, drawn from a power law, so degrees
+// follow the same law; most pick a partner in the same genre cluster.
+func Generate(cfg Config) *Catalog {
+	cfg = cfg.withDefaults()
+	n := cfg.Artists
+	c := &Catalog{cfg: cfg, weight: make([]float64, n), artists: make([]*sixdegrees.Artists, n)}
+
+	// Pareto weights, capped so no artist is expected to work with more
+	// than a tenth of the catalog.
+	maxWeight := math.Max(1, float64(n)/(10*cfg.MeanDegree))
+	for i := range c.weight {
+		u := unit(mix(cfg.Seed, i, saltWeight))
+		c.weight[i] = math.Min(maxWeight, math.Pow(1-u, -1/(cfg.Exponent-1)))
+	}
+
+	// Cumulative weights over all artists and within each cluster, whose
+	// members are i, i+Genres, i+2*Genres, ...
+	all := newSampler(n, func(k int) int { return k }, c.weight)
+	clusters := make([]*sampler, cfg.Genres)
+	for g := range clusters {
+		size := (n - g + cfg.Genres - 1) / cfg.Genres
+		clusters[g] = newSampler(size, func(k int) int { return g + k*cfg.Genres }, c.weight)
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	edges := int(math.Round(float64(n) * cfg.MeanDegree / 2))
+	c.trackStart = make([]int32, 0, edges+cfg.Compilations+1)
+	c.credits = make([]int32, 0, 2*edges+cfg.Compilations*cfg.CompilationSize)
+	add := func(credits []int32, compilation bool) {
+		c.trackStart = append(c.trackStart, int32(len(c.credits)))
+		c.credits = append(c.credits, credits...)
+		c.year = append(c.year, int16(1960+rng.Intn(66)))
+		c.comp = append(c.comp, compilation)
+	}
+	for e := 0; e < edges && n > 1; e++ {
+		a := all.pick(rng)
+		b := a
+		for tries := 0; b == a && tries < 8; tries++ {
+			if rng.Float64() < cfg.CrossGenre {
+				b = all.pick(rng)
+			} else {
+				b = clusters[a%cfg.Genres].pick(rng)
+			}
+		}
+		if b != a {
+			add([]int32{int32(a), int32(b)}, false)
+		}
+	}
+	size := min(cfg.CompilationSize, n)
+	for k := 0; k < cfg.Compilations && size > 1; k++ {
+		seen := make(map[int]bool, size)
+		credits := make([]int32, 0, size)
+		for len(credits) < size {
+			if a := all.pick(rng); !seen[a] {
+				seen[a] = true
+				credits = append(credits, int32(a))
+			}
+		}
+		add(credits, true)
+	}
+	c.trackStart = append(c.trackStart, int32(len(c.credits)))
+
+	// Index tracks by every artist they credit.
+	c.artistStart = make([]int32, n+1)
+	for _, a := range c.credits {
+		c.artistStart[a+1]++
+	}
+	for i := 0; i < n; i++ {
+		c.artistStart[i+1] += c.artistStart[i]
+	}
+	c.artistTracks = make([]int32, len(c.credits))
+	next := append([]int32(nil), c.artistStart[:n]...)
+	for t := 0; t+1 < len(c.trackStart); t++ {
+		for _, a := range c.credits[c.trackStart[t]:c.trackStart[t+1]] {
+			c.artistTracks[next[a]] = int32(t)
+			next[a]++
+		}
+	}
+	return c
+}
+
+// Len is the number of artists in the catalog.
+func (c *Catalog) Len() int { return len(c.artists) }
+
+// Tracks is the number of tracks crediting artist i.
+func (c *Catalog) Tracks(i int) int { return int(c.artistStart[i+1] - c.artistStart[i]) }
+
+// Artist returns artist i, building it on first use. Artists carry their
+// genres and popularity, which follows their weight so the busiest
+// collaborators are also the most popular, but no tracks until expanded.
+func (c *Catalog) Artist(i int) *sixdegrees.Artists {
+	if a := c.artists[i]; a != nil {
+		return a
+	}
+	a := sixdegrees.CreateArtists(c.name(i), idPrefix+strconv.Itoa(i))
+	a.Popularity = math.Floor(100 * (1 - 1/math.Sqrt(c.weight[i])))
+	g := i % c.cfg.Genres
+	a.Genres[genreName(g)] = 1
+	if unit(mix(c.cfg.Seed, i, saltGenre)) < 0.25 {
+		a.Genres[genreName((g+1)%c.cfg.Genres)] = 1
+	}
+	c.artists[i] = a
+	return a
+}
+
+// name is artist i's name, which it shares with an earlier artist when it
+// is one of the SameName share.
+func (c *Catalog) name(i int) string { return "artist-" + strconv.Itoa(c.namesake(i)) }
+
+// namesake is the first artist with artist i's name.
+func (c *Catalog) namesake(i int) int {
+	for i > 0 && unit(mix(c.cfg.Seed, i, saltName)) < c.cfg.SameName {
+		i = int(mix(c.cfg.Seed, i, saltNameOf) % uint64(i))
+	}
+	return i
+}
+
+func genreName(g int) string { return "genre-" + strconv.Itoa(g) }
+
+const idPrefix = "synth-"
+
+// index finds the catalog position of a by its ID.
+func (c *Catalog) index(a *sixdegrees.Artists) (int, bool) {
+	i, err := strconv.Atoi(strings.TrimPrefix(a.ID, idPrefix))
+	if err != nil || !strings.HasPrefix(a.ID, idPrefix) || i < 0 || i >= len(c.artists) {
+		return 0, false
+	}
+	return i, true
+}
+
+// Expand hands out the tracks crediting a the way the Spotify expansion
+// builds them: a is the track's artist, everyone else credited is featured,
+// and tracks where a is not the primary artist are guest appearances.
+// Collaborators are registered in h, and one h already knows by name is
+// reused, so artists sharing a name are confused just as they are on Spotify.
+func (c *Catalog) Expand(a *sixdegrees.Artists, h *sixdegrees.Helper) error {
+	if len(a.Tracks) > 0 {
+		return nil
+	}
+	i, ok := c.index(a)
+	if !ok {
+		return fmt.Errorf("artist %q (%s) is not in the synthetic catalog", a.Name, a.ID)
+	}
+	c.Fetched++
+	c.expanded = append(c.expanded, int32(i))
+	tracks := make([]sixdegrees.Track, 0, c.Tracks(i))
+	for _, t := range c.artistTracks[c.artistStart[i]:c.artistStart[i+1]] {
+		credits := c.credits[c.trackStart[t]:c.trackStart[t+1]]
+		tr := sixdegrees.Track{
+			Artist:      a,
+			Name:        "track-" + strconv.Itoa(int(t)),
+			ID:          "t" + strconv.Itoa(int(t)),
+			Featured:    make([]*sixdegrees.Artists, 0, len(credits)-1),
+			Compilation: c.comp[t],
+			Year:        int(c.year[t]),
+			Guest:       int(credits[0]) != i,
+		}
+		for _, f := range credits {
+			if int(f) == i {
+				continue
+			}
+			x := c.Artist(int(f))
+			if known, ok := h.ArtistMap[x.Name]; ok {
+				x = known
+			} else {
+				h.ArtistMap[x.Name] = x
+			}
+			tr.Featured = append(tr.Featured, x)
+		}
+		tracks = append(tracks, tr)
+	}
+	a.Tracks = tracks
+	return nil
+}
+
+// Reset forgets the tracks handed out so far, so the catalog can be searched
+// again from scratch.
+func (c *Catalog) Reset() {
+	for _, i := range c.expanded {
+		c.artists[i].Tracks = nil
+	}
+	c.expanded = c.expanded[:0]
+	c.Fetched = 0
+}
+
+// Pairs picks n start and target artists from the largest connected
+// component, so a path between them exists when no constraints apply.
+// Searches tell artists apart by name and confuse those sharing one, so the
+// component only links artists whose names are unique.
+func (c *Catalog) Pairs(n int, seed int64) [][2]*sixdegrees.Artists {
+	if c.component == nil {
+		c.label()
+	}
+	size := make(map[int32]int)
+	for _, l := range c.component {
+		size[l]++
+	}
+	largest := int32(-1)
+	for l, s := range size {
+		if l >= 0 && (largest < 0 || s > size[largest] || s == size[largest] && l < largest) {
+			largest = l
+		}
+	}
+	var members []int
+	for i, l := range c.component {
+		if l == largest {
+			members = append(members, i)
+		}
+	}
+	if len(members) < 2 {
+		return nil
+	}
+	rng := rand.New(rand.NewSource(seed))
+	pairs := make([][2]*sixdegrees.Artists, 0, n)
+	for len(pairs) < n {
+		a, b := members[rng.Intn(len(members))], members[rng.Intn(len(members))]
+		if a != b {
+			pairs = append(pairs, [2]*sixdegrees.Artists{c.Artist(a), c.Artist(b)})
+		}
+	}
+	return pairs
+}
+
+// label finds connected components with union-find over the tracks,
+// leaving out artists who share a name; their label is -1.
+func (c *Catalog) label() {
+	n := len(c.artists)
+	shared := make([]int32, n) // artists named after each artist, itself included
+	for i := 0; i < n; i++ {
+		shared[c.namesake(i)]++
+	}
+	unique := func(i int32) bool { return shared[c.namesake(int(i))] == 1 }
+
+	parent := make([]int32, n)
+	for i := range parent {
+		parent[i] = int32(i)
+	}
+	find := func(x int32) int32 {
+		for parent[x] != x {
+			parent[x] = parent[parent[x]]
+			x = parent[x]
+		}
+		return x
+	}
+	for t := 0; t+1 < len(c.trackStart); t++ {
+		first := int32(-1)
+		for _, a := range c.credits[c.trackStart[t]:c.trackStart[t+1]] {
+			switch {
+			case !unique(a):
+			case first < 0:
+				first = a
+			default:
+				if ra, rb := find(first), find(a); ra != rb {
+					parent[ra] = rb
+				}
+			}
+		}
+	}
+	c.component = make([]int32, n)
+	for i := range parent {
+		c.component[i] = -1
+		if unique(int32(i)) {
+			c.component[i] = find(int32(i))
+		}
+	}
+}
+
+// DegreeQuantiles returns the number of distinct collaborators at each of
+// the quantiles qs (0..1) over all artists, to check the generated
+// distribution.
+func (c *Catalog) DegreeQuantiles(qs ...float64) []int {
+	degrees := make([]int, len(c.artists))
+	seen := make(map[int32]bool)
+	for i := range degrees {
+		clear(seen)
+		for _, t := range c.artistTracks[c.artistStart[i]:c.artistStart[i+1]] {
+			for _, a := range c.credits[c.trackStart[t]:c.trackStart[t+1]] {
+				if int(a) != i {
+					seen[a] = true
+				}
+			}
+		}
+		degrees[i] = len(seen)
+	}
+	sort.Ints(degrees)
+	out := make([]int, len(qs))
+	for k, q := range qs {
+		out[k] = degrees[min(len(degrees)-1, int(q*float64(len(degrees))))]
+	}
+	return out
+}
+
+// sampler picks artists in proportion to their weight.
+type sampler struct {
+	members []int
+	cum     []float64
+}
+
+func newSampler(size int, member func(k int) int, weight []float64) *sampler {
+	s := &sampler{members: make([]int, size), cum: make([]float64, size)}
+	total := 0.0
+	for k := range s.members {
+		s.members[k] = member(k)
+		total += weight[s.members[k]]
+		s.cum[k] = total
+	}
+	return s
+}
+
+func (s *sampler) pick(rng *rand.Rand) int {
+	x := rng.Float64() * s.cum[len(s.cum)-1]
+	return s.members[min(len(s.members)-1, sort.SearchFloat64s(s.cum, x))]
+}
+
+// Salts separate the per-artist random streams.
+const (
+	saltWeight uint64 = iota + 1
+	saltGenre
+	saltName
+	saltNameOf
+)
+
+// mix derives a random number for artist i that does not depend on the
+// order artists are built in (splitmix64).
+func mix(seed int64, i int, salt uint64) uint64 {
+	z := uint64(seed)*0x9E3779B97F4A7C15 + uint64(i)*0xBF58476D1CE4E5B9 + salt*0x94D049BB133111EB
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	return z ^ z>>31
+}
+
+// unit maps x to [0, 1).
+func unit(x uint64) float64 { return float64(x>>11) / (1 << 53) }
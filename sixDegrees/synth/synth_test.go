@@ -0,0 +1,112 @@
+package synth
+
+import (
+	"reflect"
+	"testing"
+
+	sixdegrees "github.com/Jonnymurillo288/SixDegreesSpotify/sixDegrees"
+)
+
+func TestGenerate_IsDeterministic(t *testing.T) {
+	a, b := Generate(Config{Artists: 2000, Seed: 5}), Generate(Config{Artists: 2000, Seed: 5})
+	if !reflect.DeepEqual(a.credits, b.credits) || !reflect.DeepEqual(a.trackStart, b.trackStart) {
+		t.Fatalf("same seed generated different tracks")
+	}
+	// Build b's artists in the opposite order; they must come out the same.
+	for i := b.Len() - 1; i >= 0; i-- {
+		b.Artist(i)
+	}
+	for i := 0; i < a.Len(); i++ {
+		x, y := a.Artist(i), b.Artist(i)
+		if x.Name != y.Name || x.Popularity != y.Popularity || !reflect.DeepEqual(x.Genres, y.Genres) {
+			t.Fatalf("artist %d differs: %+v vs %+v", i, x, y)
+		}
+	}
+	if c := Generate(Config{Artists: 2000, Seed: 6}); reflect.DeepEqual(a.credits, c.credits) {
+		t.Fatalf("different seeds generated the same tracks")
+	}
+}
+
+func TestGenerate_Shape(t *testing.T) {
+	cfg := Config{Artists: 20000, Seed: 1}
+	c := Generate(cfg)
+	cfg = cfg.withDefaults()
+
+	q := c.DegreeQuantiles(0.5, 0.999)
+	if q[1] < 8*q[0] {
+		t.Errorf("degree median %d, 99.9th percentile %d: want a heavy tail", q[0], q[1])
+	}
+
+	inCluster, pairs, compilations := 0, 0, 0
+	for tr := 0; tr+1 < len(c.trackStart); tr++ {
+		credits := c.credits[c.trackStart[tr]:c.trackStart[tr+1]]
+		if c.comp[tr] {
+			compilations++
+			if len(credits) != cfg.CompilationSize {
+				t.Errorf("compilation track %d credits %d artists, want %d", tr, len(credits), cfg.CompilationSize)
+			}
+			continue
+		}
+		pairs++
+		if int(credits[0])%cfg.Genres == int(credits[1])%cfg.Genres {
+			inCluster++
+		}
+	}
+	if compilations != cfg.Compilations {
+		t.Errorf("compilations = %d, want %d", compilations, cfg.Compilations)
+	}
+	if share := float64(inCluster) / float64(pairs); share < 0.85 || share > 0.95 {
+		t.Errorf("in-cluster share = %.2f, want about %.2f", share, 1-cfg.CrossGenre)
+	}
+
+	ids := make(map[string]int)
+	names := make(map[string]int)
+	lowPop := 0
+	for i := 0; i < c.Len(); i++ {
+		a := c.Artist(i)
+		ids[a.ID]++
+		names[a.Name]++
+		if a.Popularity < 50 {
+			lowPop++
+		}
+	}
+	if len(ids) != c.Len() {
+		t.Errorf("%d distinct IDs for %d artists", len(ids), c.Len())
+	}
+	if dup := c.Len() - len(names); dup < c.Len()/200 || dup > c.Len()/50 {
+		t.Errorf("%d artists share a name, want about %d", dup, c.Len()/100)
+	}
+	if lowPop < c.Len()*3/4 {
+		t.Errorf("%d of %d artists below popularity 50, want most", lowPop, c.Len())
+	}
+}
+
+func TestCatalog_ServesSearches(t *testing.T) {
+	c := Generate(Config{Artists: 5000, Seed: 3, SameName: -1})
+	for _, p := range c.Pairs(5, 1) {
+		c.Reset()
+		h, path, ok := sixdegrees.RunSearchOpts(p[0], p[1], sixdegrees.SearchOptions{MaxDepth: -1, Catalog: c})
+		if !ok {
+			t.Fatalf("no path between %s and %s: %+v", p[0].Name, p[1].Name, h.Diagnosis)
+		}
+		if path[0] != p[0].Name || path[len(path)-1] != p[1].Name {
+			t.Fatalf("path %v does not join %s and %s", path, p[0].Name, p[1].Name)
+		}
+		if c.Fetched == 0 || c.Fetched != h.Stats.Expanded {
+			t.Errorf("catalog fetched %d artists, search expanded %d", c.Fetched, h.Stats.Expanded)
+		}
+	}
+	c.Reset()
+	for i := 0; i < c.Len(); i++ {
+		if a := c.artists[i]; a != nil && len(a.Tracks) > 0 {
+			t.Fatalf("%s kept its tracks after Reset", a.Name)
+		}
+	}
+}
+
+func TestCatalog_ExpandRejectsStrangers(t *testing.T) {
+	c := Generate(Config{Artists: 10, Seed: 1})
+	if err := c.Expand(sixdegrees.CreateArtists("Someone", "abc"), sixdegrees.NewHelper()); err == nil {
+		t.Fatalf("expected an error for an artist from elsewhere")
+	}
+}
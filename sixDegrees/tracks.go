@@ -4,7 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
+	"strconv"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -15,8 +15,20 @@ type Track struct {
 	PhotoURL string
 	ID       string
 	Featured []*Artists // Featured artists
+
+	Compilation bool // appears on a compilation album
+	Year        int  // album release year; 0 when unknown
+
+	// Guest marks a track found on another artist's release (Spotify's
+	// appears_on group). Artist is then the guest whose catalog listed it,
+	// and Featured holds the track's own credits, its primary artist first.
+	Guest bool
 }
 
+// variousArtists is the placeholder Spotify credits on multi-artist
+// compilations; it is not a real collaborator.
+const variousArtists = "Various Artists"
+
 type trackResponse struct {
 	Items []struct {
 		ID      string `json:"id"`
@@ -30,8 +42,11 @@ type trackResponse struct {
 
 type albumResponse struct {
 	Items []struct {
-		ID      string `json:"id"`
-		Artists []struct {
+		ID          string `json:"id"`
+		AlbumType   string `json:"album_type"`
+		AlbumGroup  string `json:"album_group"`
+		ReleaseDate string `json:"release_date"`
+		Artists     []struct {
 			Name string `json:"name"`
 		} `json:"artists"`
 	} `json:"items"`
@@ -50,51 +65,124 @@ func newTrack(art *Artists, name, photo, id string, feat []*Artists) Track {
 
 // CreateTracks converts raw Spotify album-track JSON into Track structs.
 func (a *Artists) CreateTracks(data []byte, h *Helper) ([]Track, *Helper) {
+	return a.createTracks(data, h, false)
+}
+
+// CreateGuestTracks converts the tracks of another artist's album on which a
+// appears. Only tracks crediting a are kept, so a compilation does not link a
+// to every artist on it.
+func (a *Artists) CreateGuestTracks(data []byte, h *Helper) ([]Track, *Helper) {
+	return a.createTracks(data, h, true)
+}
+
+// AlbumTracks converts one album's track JSON as described by info, for
+// both a's own releases and its guest appearances.
+func (a *Artists) AlbumTracks(data []byte, info AlbumInfo, h *Helper) []Track {
+	var tracks []Track
+	if info.Guest {
+		tracks, _ = a.CreateGuestTracks(data, h)
+	} else {
+		tracks, _ = a.CreateTracks(data, h)
+	}
+	SetAlbumInfo(tracks, info)
+	return tracks
+}
+
+func (a *Artists) createTracks(data []byte, h *Helper, guest bool) ([]Track, *Helper) {
 	if h == nil {
 		h = NewHelper()
 	}
 
 	var parsed trackResponse
 	if err := json.Unmarshal(data, &parsed); err != nil {
-		log.Printf("CreateTracks: failed to parse tracks for %s: %v", a.Name, err)
+		h.logger().Warn("cannot parse tracks", "artist", a.Name, "err", err)
 		return nil, h
 	}
 	if len(parsed.Items) == 0 {
-		log.Printf("CreateTracks: no tracks found for %s", a.Name)
+		h.logger().Debug("no tracks found", "artist", a.Name)
 		return nil, h
 	}
 
-	var tracks []Track
+	isSelf := func(name, id string) bool { return name == a.Name || (id != "" && id == a.ID) }
+	items := parsed.Items[:0]
 	for _, item := range parsed.Items {
+		if guest {
+			// Skip tracks a is not credited on before resolving anyone
+			credited := false
+			for _, art := range item.Artists {
+				credited = credited || isSelf(art.Name, art.ID)
+			}
+			if !credited {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+
+	// Hydrate every collaborator we have not seen in one batched lookup
+	// instead of searching for each name.
+	var unknown []string
+	queued := make(map[string]bool)
+	for _, item := range items {
+		for _, art := range item.Artists {
+			if art.ID == "" || queued[art.ID] || isSelf(art.Name, art.ID) || art.Name == variousArtists {
+				continue
+			}
+			if _, ok := h.ArtistMap[art.Name]; !ok {
+				queued[art.ID] = true
+				unknown = append(unknown, art.ID)
+			}
+		}
+	}
+	hydrated, err := LookupArtists(unknown)
+	if err != nil {
+		h.logger().Warn("collaborator lookup failed", "artist", a.Name, "ids", len(unknown), "err", err)
+	}
+
+	var tracks []Track
+	for _, item := range items {
 		var feat []*Artists
 		for _, art := range item.Artists {
-			if art.Name == a.Name {
+			if isSelf(art.Name, art.ID) || art.Name == variousArtists {
 				continue
 			}
-			if existing, ok := h.ArtistMap[art.Name]; ok {
-				feat = append(feat, existing)
-			} else {
-				if newA := InputArtist(art.Name); newA != nil {
-					h.ArtistMap[newA.Name] = newA
-					feat = append(feat, newA)
+			existing, ok := h.ArtistMap[art.Name]
+			if !ok {
+				switch {
+				case hydrated[art.ID] != nil:
+					existing = hydrated[art.ID]
+				case art.ID != "":
+					// Lookup failed; the ID is still enough to expand it later.
+					existing = CreateArtists(art.Name, art.ID)
+				default:
+					existing = InputArtist(art.Name)
 				}
+				if existing == nil {
+					continue
+				}
+				h.ArtistMap[art.Name] = existing
 			}
+			feat = append(feat, existing)
 		}
-		tracks = append(tracks, newTrack(a, item.Name, "", item.ID, feat))
+		t := newTrack(a, item.Name, "", item.ID, feat)
+		t.Guest = guest
+		tracks = append(tracks, t)
 	}
-	log.Printf("Created %d tracks for %s", len(tracks), a.Name)
+	h.logger().Debug("created tracks", "artist", a.Name, "tracks", len(tracks), "guest", guest)
 	return tracks, h
 }
 
 // ParseAlbums extracts album IDs from Spotify's artist-albums JSON response.
+// "Various Artists" albums are skipped unless a appears on them as a guest;
+// their tracks are then filtered by CreateGuestTracks.
 func (a *Artists) ParseAlbums(data []byte) []string {
 	var parsed albumResponse
 	if err := json.Unmarshal(data, &parsed); err != nil {
-		log.Printf("ParseAlbums: failed for %s: %v", a.Name, err)
+		logger().Warn("cannot parse albums", "artist", a.Name, "err", err)
 		return nil
 	}
 	if len(parsed.Items) == 0 {
-		log.Printf("ParseAlbums: no albums found for %s", a.Name)
+		logger().Debug("no albums found", "artist", a.Name)
 		return nil
 	}
 
@@ -102,7 +190,7 @@ func (a *Artists) ParseAlbums(data []byte) []string {
 	for _, item := range parsed.Items {
 		skip := false
 		for _, art := range item.Artists {
-			if art.Name == "Various Artists" {
+			if art.Name == variousArtists && item.AlbumGroup != "appears_on" {
 				skip = true
 				break
 			}
@@ -111,10 +199,69 @@ func (a *Artists) ParseAlbums(data []byte) []string {
 			ids = append(ids, item.ID)
 		}
 	}
-	log.Printf("%s: parsed %d album IDs", a.Name, len(ids))
 	return ids
 }
 
+// AlbumInfo is what the artist-albums response tells us about an album
+// beyond its ID.
+type AlbumInfo struct {
+	Year        int // release year; 0 when unknown
+	Compilation bool
+	Guest       bool // another artist's release that a appears on
+}
+
+// ParseAlbumInfo maps album IDs in Spotify's artist-albums JSON response to
+// their release year and album type.
+func ParseAlbumInfo(data []byte) map[string]AlbumInfo {
+	var parsed albumResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil
+	}
+	info := make(map[string]AlbumInfo, len(parsed.Items))
+	for _, item := range parsed.Items {
+		info[item.ID] = AlbumInfo{
+			Year:        releaseYear(item.ReleaseDate),
+			Compilation: item.AlbumType == "compilation" || item.AlbumGroup == "compilation",
+			Guest:       item.AlbumGroup == "appears_on",
+		}
+	}
+	return info
+}
+
+// releaseYear reads the year from a Spotify release date, which may be
+// "1997", "1997-06" or "1997-06-03" depending on its precision.
+func releaseYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil || year <= 0 {
+		return 0
+	}
+	return year
+}
+
+// CompilationAlbums returns the IDs of compilation albums in Spotify's
+// artist-albums JSON response.
+func CompilationAlbums(data []byte) map[string]bool {
+	ids := make(map[string]bool)
+	for id, info := range ParseAlbumInfo(data) {
+		if info.Compilation {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// SetAlbumInfo stamps tracks from one album with its release year and type.
+func SetAlbumInfo(tracks []Track, info AlbumInfo) {
+	for i := range tracks {
+		tracks[i].Year = info.Year
+		tracks[i].Compilation = info.Compilation
+		tracks[i].Guest = tracks[i].Guest || info.Guest
+	}
+}
+
 // CheckTracks returns the number of track rows in a database.
 func (art *Artists) CheckTracks(db *sql.DB) (int, error) {
 	if db == nil {
@@ -124,6 +271,6 @@ func (art *Artists) CheckTracks(db *sql.DB) (int, error) {
 	if err := db.QueryRow("SELECT COUNT(*) FROM Tracks").Scan(&count); err != nil {
 		return 0, err
 	}
-	log.Printf("Database contains %d tracks total", count)
+	logger().Debug("counted tracks", "tracks", count)
 	return count, nil
 }
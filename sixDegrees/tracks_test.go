@@ -1,3 +1,6 @@
+//go:build integration
+// +build integration
+
 package sixdegrees
 
 import (
@@ -17,7 +20,7 @@ func TestTracks(t *testing.T) {
 
 func TestAlbumGetsTracks(t *testing.T) {
 	a := CreateArtists("Eminem","7dGJo4pcD2V6oG8kP0tJRR")
-	albums,_ := spotify.ArtistAlbums(a.ID,1)
+	albums,_ := spotify.ArtistAlbums(a.ID,spotify.FetchOptions{AlbumLimit: 1})
 	h := NewHelper()
 	tracks,_ := a.CreateTracks(albums,h)
 	if len(tracks) < 50 {
@@ -29,7 +32,7 @@ func TestAlbumGetsTracks(t *testing.T) {
 
 func TestAlbumGetsFeatured(t *testing.T) {
 	a := CreateArtists("Eminem","7dGJo4pcD2V6oG8kP0tJRR")
-	albums,_ := spotify.ArtistAlbums(a.ID,1)
+	albums,_ := spotify.ArtistAlbums(a.ID,spotify.FetchOptions{AlbumLimit: 1})
 	res := a.ParseAlbums(albums)
 	if len(res) < 2 {
 		log.Fatalf("Problem with getting artists from Album %v",res)
@@ -1,9 +1,11 @@
 package sixdegrees
 
 import (
+	"fmt"
 	"math"
-
-	utils "github.com/Jonnymurillo288/GoUtils"
+	"sort"
+	"strings"
+	"time"
 )
 
 // ============================ Strategies & Context ============================
@@ -16,6 +18,46 @@ type EdgeContext struct {
 	SharedCount   int     // number of shared tracks connecting from->to
 	IsCompilation bool    // whether connection derives from a compilation only
 	RecencyScore  float64 // 0..1 where 1 is most recent
+	Evidence      string  // name of a track connecting from->to
+	EvidenceID    string  // Spotify ID of Evidence; empty when unknown
+	Year          int     // release year of Evidence; 0 when unknown
+	Guest         bool    // whether connection derives from guest appearances only
+}
+
+// StoredCollabs supplies collaborations saved from earlier crawls, such as
+// the database's collaborations table.
+type StoredCollabs interface {
+	// Collaborations returns what is stored about the pairs among the
+	// given artist IDs.
+	Collaborations(ids []string) (map[PairKey]EdgeContext, error)
+}
+
+// PairKey is an unordered pair of artist IDs; A sorts before B.
+type PairKey struct{ A, B string }
+
+// NewPairKey orders two artist IDs into a PairKey.
+func NewPairKey(x, y string) PairKey {
+	if x > y {
+		x, y = y, x
+	}
+	return PairKey{A: x, B: y}
+}
+
+// recencyHorizon is the number of years over which RecencyScore decays to 0.
+const recencyHorizon = 30.0
+
+// RecencyScore maps a release year onto EdgeContext.RecencyScore: 1 for the
+// current year, falling linearly to 0 over recencyHorizon years. Unknown
+// years (<= 0) score 0.
+func RecencyScore(year, currentYear int) float64 {
+	if year <= 0 {
+		return 0
+	}
+	age := float64(currentYear - year)
+	if age <= 0 {
+		return 1
+	}
+	return math.Max(0, 1-age/recencyHorizon)
 }
 
 type PopularityDiffStrategy struct{}
@@ -33,6 +75,90 @@ func (CollabStrengthStrategy) Weight(_ *Artists, _ *Artists, _ *Artists, ctx Edg
 	return 1.0 / float64(ctx.SharedCount)
 }
 
+// RecencyStrategy prefers connections on recent releases: 0 for a track out
+// this year, rising to 1 for old or undated ones.
+type RecencyStrategy struct{}
+
+func (RecencyStrategy) Weight(_, _, _ *Artists, ctx EdgeContext) float64 {
+	return 1 - ctx.RecencyScore
+}
+
+// CompilationPenaltyStrategy charges Penalty for hops that only rest on
+// compilation tracks, which rarely reflect a real collaboration. A zero
+// Penalty counts as 1.
+type CompilationPenaltyStrategy struct{ Penalty float64 }
+
+func (c CompilationPenaltyStrategy) Weight(_, _, _ *Artists, ctx EdgeContext) float64 {
+	if !ctx.IsCompilation {
+		return 0
+	}
+	if c.Penalty == 0 {
+		return 1
+	}
+	return c.Penalty
+}
+
+// GenreDistanceStrategy prefers hops between artists with similar genres:
+// 1 minus the Jaccard similarity of their genre sets, or 0.5 when either
+// artist has no genres.
+type GenreDistanceStrategy struct{}
+
+func (GenreDistanceStrategy) Weight(_, from, to *Artists, _ EdgeContext) float64 {
+	if len(from.Genres) == 0 || len(to.Genres) == 0 {
+		return 0.5
+	}
+	return 1 - genreSimilarity(from.Genres, to.Genres)
+}
+
+// ObscurityStrategy prefers stepping through less popular artists.
+type ObscurityStrategy struct{}
+
+func (ObscurityStrategy) Weight(_, _, to *Artists, _ EdgeContext) float64 {
+	return math.Min(1, math.Max(0, to.Popularity/100))
+}
+
+// genreSimilarity is the Jaccard similarity of two genre sets.
+func genreSimilarity(a, b map[string]int) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for g := range a {
+		if _, ok := b[g]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// strategies maps the names accepted by StrategyByName to their implementations.
+var strategies = map[string]WeightStrategy{
+	"popularity":  PopularityDiffStrategy{},
+	"collab":      CollabStrengthStrategy{},
+	"recency":     RecencyStrategy{},
+	"compilation": CompilationPenaltyStrategy{Penalty: 1},
+	"genre":       GenreDistanceStrategy{},
+	"obscurity":   ObscurityStrategy{},
+}
+
+// StrategyNames lists the built-in strategy names in sorted order.
+func StrategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StrategyByName resolves a built-in strategy such as "popularity" or "collab".
+func StrategyByName(name string) (WeightStrategy, error) {
+	if s, ok := strategies[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q (want one of %s)", name, strings.Join(StrategyNames(), ", "))
+}
+
 // ============================== Graph & Edges ================================
 
 type EdgeKey struct{ From, To string }
@@ -103,17 +229,26 @@ func (g *Graph) AddEdge(e Edge) {
 type Dijkstra struct {
 	DistTo   []float64
 	EdgeTo   []Edge
-	PQ       utils.IndexMinPQ
+	PQ       IndexMinPQ
 	Strategy WeightStrategy
 	Meta     map[EdgeKey]EdgeContext
 	Target   *Artists
+	Start    *Artists
 }
 
 func NewDijkstras(g *Graph, s *Artists) Dijkstra {
 	return NewDijkstrasWithStrategy(g, s, nil, nil)
 }
 
+// NewDijkstrasWithStrategy runs Dijkstra from s, passing s to the strategy as
+// the target. Use NewDijkstrasToTarget when the strategy depends on the target.
 func NewDijkstrasWithStrategy(g *Graph, s *Artists, strat WeightStrategy, meta map[EdgeKey]EdgeContext) Dijkstra {
+	return NewDijkstrasToTarget(g, s, s, strat, meta)
+}
+
+// NewDijkstrasToTarget runs Dijkstra from s with strategy weights computed
+// relative to target.
+func NewDijkstrasToTarget(g *Graph, s, target *Artists, strat WeightStrategy, meta map[EdgeKey]EdgeContext) Dijkstra {
 	// determine capacity from max key
 	maxKey := -1
 	for _, idx := range g.Keys {
@@ -121,7 +256,7 @@ func NewDijkstrasWithStrategy(g *Graph, s *Artists, strat WeightStrategy, meta m
 			maxKey = idx
 		}
 	}
-	n := maxKey + 2 // breathing room for PQ implementations using 1-based indexing
+	n := maxKey + 2 // room for a start vertex that is not yet keyed
 	if n < 2 {
 		n = 2
 	}
@@ -129,10 +264,11 @@ func NewDijkstrasWithStrategy(g *Graph, s *Artists, strat WeightStrategy, meta m
 	d := Dijkstra{
 		EdgeTo:   make([]Edge, n),
 		DistTo:   make([]float64, n),
-		PQ:       utils.NewIndexMinPQ(n),
+		PQ:       NewIndexMinPQ(n),
 		Strategy: strat,
 		Meta:     meta,
-		Target:   s,
+		Target:   target,
+		Start:    s,
 	}
 	for i := range d.DistTo {
 		d.DistTo[i] = math.Inf(1)
@@ -175,18 +311,8 @@ func (d *Dijkstra) relax(e Edge, keys map[string]int) {
 		return
 	}
 
-	// choose weight
-	edgeWeight := e.Weight
-	if d.Strategy != nil {
-		ctx := EdgeContext{}
-		if d.Meta != nil {
-			if c, ok := d.Meta[EdgeKey{From: fromName, To: toName}]; ok {
-				ctx = c
-			}
-		}
-		edgeWeight = d.Strategy.Weight(d.Target, e.V, e.W, ctx)
-	}
-	if math.IsNaN(edgeWeight) || math.IsInf(edgeWeight, 0) {
+	edgeWeight, ok := weightOf(e, d.Strategy, d.Target, d.Meta)
+	if !ok {
 		return
 	}
 
@@ -195,10 +321,385 @@ func (d *Dijkstra) relax(e Edge, keys map[string]int) {
 		d.DistTo[w] = newDist
 		d.EdgeTo[w] = e
 		if d.PQ.Contains(w) {
-			// best-effort: decrease the key; if your PQ returns an error, ignore
 			d.PQ.DecreaseKey(w, newDist)
 		} else {
 			d.PQ.Insert(w, newDist)
 		}
 	}
 }
+
+// weightOf is e's weight under strat, or its default weight when strat is
+// nil. It reports false for weights no path should use.
+func weightOf(e Edge, strat WeightStrategy, target *Artists, meta map[EdgeKey]EdgeContext) (float64, bool) {
+	w := e.Weight
+	if strat != nil {
+		w = strat.Weight(target, e.V, e.W, meta[EdgeKey{From: e.From(), To: e.To()}])
+	}
+	return w, !math.IsNaN(w) && !math.IsInf(w, 0)
+}
+
+// hopBoundedPath returns the cheapest path from s to target in g that takes
+// at most maxHops edges, with the cost of each edge. Plain Dijkstra would
+// settle a cheaper route with more hops and lose a shorter one, so this
+// relaxes every edge once per hop instead (Bellman-Ford stopped after
+// maxHops rounds).
+func hopBoundedPath(g *Graph, s, target *Artists, strat WeightStrategy, meta map[EdgeKey]EdgeContext, maxHops int) ([]Edge, []float64, bool) {
+	startIdx, ok := g.Keys[s.Name]
+	targetIdx, tok := g.Keys[target.Name]
+	if !ok || !tok {
+		return nil, nil, false
+	}
+	n := len(g.Keys)
+	// dist[k][v] is the cheapest cost of reaching v in at most k hops;
+	// edgeTo[k][v] is the last edge when round k improved it.
+	dist := [][]float64{make([]float64, n)}
+	for i := range dist[0] {
+		dist[0][i] = math.Inf(1)
+	}
+	dist[0][startIdx] = 0
+	edgeTo := []map[int]Edge{nil}
+	cost := []map[int]float64{nil}
+	for k := 1; k <= maxHops; k++ {
+		prev, cur := dist[k-1], append([]float64(nil), dist[k-1]...)
+		edgeTo, cost = append(edgeTo, make(map[int]Edge)), append(cost, make(map[int]float64))
+		for v := 0; v < n; v++ { // in key order, so ties resolve the same way every run
+			if math.IsInf(prev[v], 1) {
+				continue
+			}
+			for _, e := range g.Adj[v] {
+				w, ok := g.Keys[e.To()]
+				if !ok || w == v {
+					continue
+				}
+				ew, ok := weightOf(e, strat, target, meta)
+				if !ok {
+					continue
+				}
+				if d := prev[v] + ew; d < cur[w] {
+					cur[w], edgeTo[k][w], cost[k][w] = d, e, ew
+				}
+			}
+		}
+		dist = append(dist, cur)
+	}
+	if math.IsInf(dist[maxHops][targetIdx], 1) {
+		return nil, nil, false
+	}
+	var edges []Edge
+	var costs []float64
+	for k, w := maxHops, targetIdx; k > 0 && w != startIdx; k-- {
+		if e, ok := edgeTo[k][w]; ok {
+			edges, costs = append(edges, e), append(costs, cost[k][w])
+			w = g.Keys[e.From()]
+		}
+	}
+	for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+		edges[i], edges[j] = edges[j], edges[i]
+		costs[i], costs[j] = costs[j], costs[i]
+	}
+	return edges, costs, true
+}
+
+// PathTo returns the edges of the cheapest path from the start to name.
+func (d *Dijkstra) PathTo(g *Graph, name string) ([]Edge, bool) {
+	w, ok := g.Keys[name]
+	if !ok || w >= len(d.DistTo) || math.IsInf(d.DistTo[w], 1) {
+		return nil, false
+	}
+	var edges []Edge
+	for d.EdgeTo[w].V != nil {
+		e := d.EdgeTo[w]
+		edges = append(edges, e)
+		w = g.Keys[e.From()]
+		if len(edges) > len(d.EdgeTo) {
+			return nil, false // defensive: a cycle in EdgeTo
+		}
+	}
+	for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+		edges[i], edges[j] = edges[j], edges[i]
+	}
+	return edges, true
+}
+
+// ============================ Graph construction =============================
+
+// BuildGraph turns every expanded artist in h into a weighted collaboration
+// graph. Each pair of artists credited on the same track is joined in both
+// directions; the returned meta records how many distinct tracks each pair
+// shares, whether they are all compilation tracks, and one of them as
+// evidence. Default edge weights use NewEdge.
+func BuildGraph(h *Helper, target *Artists) (*Graph, map[EdgeKey]EdgeContext) {
+	return buildGraph(h, target, nil)
+}
+
+// buildGraph is BuildGraph using only the tracks c allows.
+func buildGraph(h *Helper, target *Artists, c *Constraints) (*Graph, map[EdgeKey]EdgeContext) {
+	type pairInfo struct {
+		from, to *Artists
+		acc      edgeAcc
+	}
+	pairs := make(map[EdgeKey]*pairInfo)
+
+	names := make([]string, 0, len(h.ArtistMap))
+	for name := range h.ArtistMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		a := h.ArtistMap[name]
+		if a == nil {
+			continue
+		}
+		for _, t := range a.Tracks {
+			if !c.AllowsTrack(t) {
+				continue
+			}
+			credited := trackCredits(a, t)
+			for i := 0; i < len(credited); i++ {
+				for j := i + 1; j < len(credited); j++ {
+					x, y := credited[i], credited[j]
+					if x.Name > y.Name {
+						x, y = y, x
+					}
+					k := EdgeKey{From: x.Name, To: y.Name}
+					p, ok := pairs[k]
+					if !ok {
+						p = &pairInfo{from: x, to: y}
+						pairs[k] = p
+					}
+					p.acc.add(t)
+				}
+			}
+		}
+	}
+
+	keys := make([]EdgeKey, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].From != keys[j].From {
+			return keys[i].From < keys[j].From
+		}
+		return keys[i].To < keys[j].To
+	})
+
+	g := NewGraph()
+	meta := make(map[EdgeKey]EdgeContext, 2*len(pairs))
+	for _, k := range keys {
+		p := pairs[k]
+		ctx := p.acc.context()
+		g.AddEdge(NewEdge(target, p.from, p.to))
+		g.AddEdge(NewEdge(target, p.to, p.from))
+		meta[EdgeKey{From: p.from.Name, To: p.to.Name}] = ctx
+		meta[EdgeKey{From: p.to.Name, To: p.from.Name}] = ctx
+	}
+	return g, meta
+}
+
+// mergeStored folds what stored knows about each edge of g into meta. The
+// larger shared-track count and the more recent collaboration win, so tracks
+// the search did not fetch still strengthen an edge; the evidence stays a
+// fetched track.
+func mergeStored(g *Graph, meta map[EdgeKey]EdgeContext, stored StoredCollabs) error {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, adj := range g.Adj {
+		for _, e := range adj {
+			if id := e.V.ID; id != "" && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	rows, err := stored.Collaborations(ids)
+	if err != nil {
+		return err
+	}
+	for _, adj := range g.Adj {
+		for _, e := range adj {
+			if e.V.ID == "" || e.W.ID == "" {
+				continue
+			}
+			s, ok := rows[NewPairKey(e.V.ID, e.W.ID)]
+			if !ok {
+				continue
+			}
+			k := EdgeKey{From: e.From(), To: e.To()}
+			ctx := meta[k]
+			if s.SharedCount > ctx.SharedCount {
+				ctx.SharedCount = s.SharedCount
+			}
+			ctx.RecencyScore = math.Max(ctx.RecencyScore, s.RecencyScore)
+			meta[k] = ctx
+		}
+	}
+	return nil
+}
+
+// edgeAcc accumulates the EdgeContext of one artist pair, one shared track
+// at a time.
+type edgeAcc struct {
+	ctx          EdgeContext
+	tracks       map[string]bool // shared tracks, true while only seen as a guest appearance
+	compilations int
+	guests       int
+	evidenceRank int // evidenceRank of the Evidence track
+	latest       int // most recent release year among the shared tracks
+}
+
+func (e *edgeAcc) add(t Track) {
+	key := trackKey(t)
+	if guest, ok := e.tracks[key]; ok {
+		// The same track listed on its own release makes it a regular one.
+		if guest && !t.Guest {
+			e.tracks[key] = false
+			e.guests--
+			e.offer(t)
+		}
+		return
+	}
+	if e.tracks == nil {
+		e.tracks = make(map[string]bool)
+	}
+	e.tracks[key] = t.Guest
+	e.ctx.SharedCount++
+	if t.Compilation {
+		e.compilations++
+	}
+	if t.Guest {
+		e.guests++
+	}
+	if t.Year > e.latest {
+		e.latest = t.Year
+	}
+	e.offer(t)
+}
+
+// offer makes t the edge's evidence if it shows the connection better.
+func (e *edgeAcc) offer(t Track) {
+	if rank := evidenceRank(t); e.ctx.Evidence == "" || rank < e.evidenceRank {
+		e.ctx.Evidence = t.Name
+		e.ctx.EvidenceID = t.ID
+		e.ctx.Year = t.Year
+		e.evidenceRank = rank
+	}
+}
+
+// evidenceRank orders tracks by how well they show a connection: own
+// releases first, then guest appearances, then compilations.
+func evidenceRank(t Track) int {
+	switch {
+	case t.Compilation:
+		return 2
+	case t.Guest:
+		return 1
+	}
+	return 0
+}
+
+func (e *edgeAcc) context() EdgeContext {
+	ctx := e.ctx
+	ctx.IsCompilation = ctx.SharedCount > 0 && e.compilations == ctx.SharedCount
+	ctx.Guest = ctx.SharedCount > 0 && e.guests == ctx.SharedCount
+	ctx.RecencyScore = RecencyScore(e.latest, time.Now().Year())
+	return ctx
+}
+
+// trackKey identifies a track across the artists that list it.
+func trackKey(t Track) string {
+	if t.ID != "" {
+		return t.ID
+	}
+	if t.Artist != nil {
+		return t.Artist.Name + "::" + t.Name
+	}
+	return "::" + t.Name
+}
+
+// trackCredits lists the distinct artists credited on t, owner first.
+func trackCredits(owner *Artists, t Track) []*Artists {
+	seen := map[string]bool{owner.Name: true}
+	credited := []*Artists{owner}
+	add := func(a *Artists) {
+		if a == nil || a.Name == "" || seen[a.Name] {
+			return
+		}
+		seen[a.Name] = true
+		credited = append(credited, a)
+	}
+	add(t.Artist)
+	for _, f := range t.Featured {
+		add(f)
+	}
+	return credited
+}
+
+// ============================= Weighted search ===============================
+
+// RunWeightedSearch explores the collaboration graph with the BFS expansion of
+// RunSearchOpts, then returns the cheapest path to target under strat over
+// everything that was expanded, honoring opts.Constraints. Under a depth
+// limit the path is the cheapest within opts.MaxDepth hops. With
+// opts.Collabs, stored collaborations add to the edges' shared-track counts
+// and recency.
+func RunWeightedSearch(start, target *Artists, strat WeightStrategy, opts SearchOptions) (*Helper, []PathStep, bool) {
+	if opts.hasWaypoints() {
+		return searchViaWaypoints(start, target, opts, func(from, to *Artists, o SearchOptions) (*Helper, []PathStep, bool) {
+			return RunWeightedSearch(from, to, strat, o)
+		})
+	}
+	h, _, ok := RunSearchOpts(start, target, opts)
+	if !ok {
+		return h, nil, false
+	}
+	if strat == nil {
+		strat = PopularityDiffStrategy{} // same as the default NewEdge weights
+	}
+	if opts.Constraints != nil {
+		strat = constrainedStrategy{WeightStrategy: strat, c: opts.Constraints}
+	}
+	done := h.Stats.measure("graph")
+	g, meta := buildGraph(h, target, opts.Constraints)
+	if opts.Collabs != nil {
+		if err := mergeStored(g, meta, opts.Collabs); err != nil {
+			h.logger().Warn("failed to load stored collaborations", "err", err)
+		}
+	}
+	done()
+	done = h.Stats.measure("dijkstra")
+	var edges []Edge
+	var costs []float64
+	if opts.MaxDepth >= 0 {
+		edges, costs, ok = hopBoundedPath(g, start, target, strat, meta, opts.MaxDepth)
+	} else {
+		d := NewDijkstrasToTarget(g, start, target, strat, meta)
+		edges, ok = d.PathTo(g, target.Name)
+		for _, e := range edges {
+			costs = append(costs, d.DistTo[g.Keys[e.To()]]-d.DistTo[g.Keys[e.From()]])
+		}
+	}
+	done()
+	if !ok {
+		h.Diagnosis = diagnose(h, start, target, opts.MaxDepth, nil)
+		h.Diagnosis.Reason = StopConstrained
+		return h, nil, false
+	}
+	steps := make([]PathStep, 0, len(edges))
+	for i, e := range edges {
+		from, to := e.From(), e.To()
+		ctx := meta[EdgeKey{From: from, To: to}]
+		steps = append(steps, PathStep{
+			From:    from,
+			To:      to,
+			Track:   ctx.Evidence,
+			TrackID: ctx.EvidenceID,
+			Year:    ctx.Year,
+			Guest:   ctx.Guest,
+			Cost:    costs[i],
+		})
+	}
+	return h, steps, true
+}
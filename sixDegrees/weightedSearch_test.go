@@ -111,4 +111,144 @@ func TestWeightedSearch_Dijkstra_SelfLoopTerminates(t *testing.T) {
 	if d.DistTo[keyA] != 0 {
 		t.Fatalf("expected distance to start to remain 0, got %v", d.DistTo[keyA])
 	}
-}
\ No newline at end of file
+}
+func TestBuildGraph_CountsSharedTracksBothDirections(t *testing.T) {
+	A := &Artists{Name: "A"}
+	B := &Artists{Name: "B"}
+	C := &Artists{Name: "C"}
+	A.Tracks = []Track{
+		{Artist: A, Name: "t1", ID: "1", Featured: []*Artists{B}},
+		{Artist: A, Name: "t2", ID: "2", Featured: []*Artists{B, C}},
+	}
+	// B lists t2 as well; it must not be counted twice.
+	B.Tracks = []Track{{Artist: A, Name: "t2", ID: "2", Featured: []*Artists{B, C}}}
+	h := NewHelper()
+	h.ArtistMap["A"], h.ArtistMap["B"] = A, B
+
+	g, meta := BuildGraph(h, C)
+	if len(g.Keys) != 3 {
+		t.Fatalf("expected 3 vertices, got %d", len(g.Keys))
+	}
+	if got := meta[EdgeKey{From: "A", To: "B"}].SharedCount; got != 2 {
+		t.Fatalf("expected A->B to share 2 tracks, got %d", got)
+	}
+	if got := meta[EdgeKey{From: "B", To: "A"}].SharedCount; got != 2 {
+		t.Fatalf("expected B->A to share 2 tracks, got %d", got)
+	}
+	if got := meta[EdgeKey{From: "B", To: "C"}]; got.SharedCount != 1 || got.Evidence != "t2" {
+		t.Fatalf("unexpected B->C context %+v", got)
+	}
+}
+
+func TestDijkstra_PathTo(t *testing.T) {
+	g := NewGraph()
+	target := &Artists{Name: "T", Popularity: 50}
+	A := &Artists{Name: "A", Popularity: 10}
+	B := &Artists{Name: "B", Popularity: 40}
+	g.AddEdge(NewEdge(target, A, B))
+	g.AddEdge(NewEdge(target, B, target))
+
+	d := NewDijkstrasToTarget(g, A, target, nil, nil)
+	edges, ok := d.PathTo(g, "T")
+	if !ok || len(edges) != 2 || edges[0].From() != "A" || edges[1].To() != "T" {
+		t.Fatalf("unexpected path %v (ok=%v)", edges, ok)
+	}
+	if _, ok := d.PathTo(g, "missing"); ok {
+		t.Fatalf("expected no path to an unknown vertex")
+	}
+}
+
+func TestRunWeightedSearch_PrefersStrongerCollaborations(t *testing.T) {
+	A := &Artists{Name: "A"}
+	B := &Artists{Name: "B"}
+	C := &Artists{Name: "C"}
+	D := &Artists{Name: "D"}
+	// A-C-D is found first by BFS; A-B-D has the stronger ties.
+	A.Tracks = []Track{
+		{Artist: A, Name: "ac", Featured: []*Artists{C}},
+		{Artist: A, Name: "ab1", Featured: []*Artists{B}},
+		{Artist: A, Name: "ab2", Featured: []*Artists{B}},
+		{Artist: A, Name: "ab3", Featured: []*Artists{B}},
+	}
+	B.Tracks = []Track{{Artist: B, Name: "bd", Featured: []*Artists{D}}}
+	C.Tracks = []Track{{Artist: C, Name: "cd", Featured: []*Artists{D}}}
+
+	known := NewHelper()
+	known.ArtistMap["B"], known.ArtistMap["C"] = B, C
+
+	_, steps, ok := RunWeightedSearch(A, D, CollabStrengthStrategy{}, SearchOptions{MaxDepth: -1, Known: known})
+	if !ok {
+		t.Fatalf("expected a weighted path")
+	}
+	if len(steps) != 2 || steps[0].To != "B" || steps[1].Track != "bd" {
+		t.Fatalf("expected A-B-D via bd, got %+v", steps)
+	}
+	if want := 1.0/3 + 1.0; math.Abs(TotalCost(steps)-want) > 1e-9 {
+		t.Fatalf("expected total cost %v, got %v", want, TotalCost(steps))
+	}
+}
+
+// storedFixture serves stored collaborations from memory.
+type storedFixture map[PairKey]EdgeContext
+
+func (s storedFixture) Collaborations(ids []string) (map[PairKey]EdgeContext, error) {
+	return s, nil
+}
+
+func TestRunWeightedSearch_CountsStoredCollaborations(t *testing.T) {
+	A, B, C, D := CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c"), CreateArtists("D", "d")
+	// Both routes share one fetched track per hop; the store knows more
+	// tracks along A-C-D.
+	A.Tracks = []Track{
+		{Artist: A, Name: "ab", Featured: []*Artists{B}},
+		{Artist: A, Name: "ac", Featured: []*Artists{C}},
+	}
+	B.Tracks = []Track{{Artist: B, Name: "bd", Featured: []*Artists{D}}}
+	C.Tracks = []Track{{Artist: C, Name: "cd", Featured: []*Artists{D}}}
+	known := NewHelper()
+	known.ArtistMap["B"], known.ArtistMap["C"] = B, C
+
+	_, steps, ok := RunWeightedSearch(A, D, CollabStrengthStrategy{}, SearchOptions{MaxDepth: -1, Known: known})
+	if !ok || TotalCost(steps) != 2 {
+		t.Fatalf("expected a path of cost 2 from fetched tracks alone, got %+v", steps)
+	}
+
+	stored := storedFixture{NewPairKey("c", "a"): {SharedCount: 4}, NewPairKey("c", "d"): {SharedCount: 4, RecencyScore: 1}}
+	_, steps, ok = RunWeightedSearch(A, D, CollabStrengthStrategy{}, SearchOptions{MaxDepth: -1, Known: known, Collabs: stored})
+	if !ok || len(steps) != 2 || steps[0].To != "C" || steps[1].Track != "cd" {
+		t.Fatalf("expected A-C-D via the fetched track cd, got %+v", steps)
+	}
+	if want := 0.5; math.Abs(TotalCost(steps)-want) > 1e-9 {
+		t.Fatalf("expected total cost %v, got %v", want, TotalCost(steps))
+	}
+}
+
+func TestStrategyByName(t *testing.T) {
+	if s, err := StrategyByName(" Collab "); err != nil || s == nil {
+		t.Fatalf("expected collab strategy, got %v %v", s, err)
+	}
+	if _, err := StrategyByName("nope"); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+}
+
+func TestRunWeightedSearch_CheaperDeeperRouteKeepsDepthLimit(t *testing.T) {
+	A, T, cat, all := depthTrapGraph()
+	_, steps, ok := RunWeightedSearch(A, T, CollabStrengthStrategy{}, SearchOptions{MaxDepth: 2, Catalog: cat})
+	if !ok {
+		t.Fatalf("expected the 2-hop path")
+	}
+	if len(steps) != 2 || steps[0].To != "B" || steps[1].To != "T" {
+		t.Fatalf("expected A-B-T within depth 2, got %+v", steps)
+	}
+	if want := 1 + 1.0/3; math.Abs(TotalCost(steps)-want) > 1e-9 {
+		t.Fatalf("expected cost %v, got %v", want, TotalCost(steps))
+	}
+
+	// Without the limit the cheaper 3-hop route wins.
+	cat.reset(all)
+	_, steps, ok = RunWeightedSearch(A, T, CollabStrengthStrategy{}, SearchOptions{MaxDepth: -1, Catalog: cat})
+	if !ok || len(steps) != 3 || steps[0].To != "C" {
+		t.Fatalf("expected A-C-B-T without a depth limit, got %+v", steps)
+	}
+}
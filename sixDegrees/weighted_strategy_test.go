@@ -2,6 +2,8 @@ package sixdegrees
 
 import (
 	"math"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -28,3 +30,140 @@ func TestCollabStrengthStrategy(t *testing.T) {
 		t.Fatalf("expected 1/4 weight, got %v", w)
 	}
 }
+
+func TestRecencyScore(t *testing.T) {
+	if got := RecencyScore(2024, 2024); got != 1 {
+		t.Fatalf("current year should score 1, got %v", got)
+	}
+	if got := RecencyScore(2009, 2024); math.Abs(got-0.5) > 1e-9 {
+		t.Fatalf("15 years old should score 0.5, got %v", got)
+	}
+	if got := RecencyScore(1950, 2024); got != 0 {
+		t.Fatalf("old releases should score 0, got %v", got)
+	}
+	if got := RecencyScore(0, 2024); got != 0 {
+		t.Fatalf("unknown year should score 0, got %v", got)
+	}
+}
+
+func TestBuiltinStrategies(t *testing.T) {
+	target := &Artists{Name: "T"}
+	from := &Artists{Name: "A", Genres: map[string]int{"rap": 1, "trap": 1}}
+	to := &Artists{Name: "B", Popularity: 25, Genres: map[string]int{"rap": 1}}
+
+	if w := (RecencyStrategy{}).Weight(target, from, to, EdgeContext{RecencyScore: 0.75}); w != 0.25 {
+		t.Fatalf("recency weight = %v, want 0.25", w)
+	}
+	if w := (CompilationPenaltyStrategy{}).Weight(target, from, to, EdgeContext{IsCompilation: true}); w != 1 {
+		t.Fatalf("compilation weight = %v, want 1", w)
+	}
+	if w := (CompilationPenaltyStrategy{Penalty: 3}).Weight(target, from, to, EdgeContext{}); w != 0 {
+		t.Fatalf("regular release should not be penalized, got %v", w)
+	}
+	if w := (GenreDistanceStrategy{}).Weight(target, from, to, EdgeContext{}); w != 0.5 {
+		t.Fatalf("genre distance = %v, want 0.5", w)
+	}
+	if w := (ObscurityStrategy{}).Weight(target, from, to, EdgeContext{}); w != 0.25 {
+		t.Fatalf("obscurity weight = %v, want 0.25", w)
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("0.7*collab + 0.3*recency")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	c, ok := s.(CompositeStrategy)
+	if !ok || len(c.Terms) != 2 || c.String() != "0.7*collab+0.3*recency" {
+		t.Fatalf("unexpected composite %#v", s)
+	}
+	ctx := EdgeContext{SharedCount: 2, RecencyScore: 1}
+	if w := s.Weight(nil, nil, nil, ctx); math.Abs(w-0.35) > 1e-9 {
+		t.Fatalf("composite weight = %v, want 0.35", w)
+	}
+
+	if s, err := ParseStrategy("collab"); err != nil || s != (CollabStrengthStrategy{}) {
+		t.Fatalf("lone name should resolve to the strategy itself, got %#v %v", s, err)
+	}
+	for _, bad := range []string{"", "x*collab", "-1*collab", "0.5*nope", "collab+"} {
+		if _, err := ParseStrategy(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadStrategyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "strategy.json")
+	if err := os.WriteFile(path, []byte(`{"recency": 0.3, "collab": 0.7}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadStrategyFile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := s.(CompositeStrategy).String(); got != "0.7*collab+0.3*recency" {
+		t.Fatalf("unexpected strategy %s", got)
+	}
+}
+
+func TestBuildGraph_MarksCompilationOnlyEdges(t *testing.T) {
+	A, B, C := &Artists{Name: "A"}, &Artists{Name: "B"}, &Artists{Name: "C"}
+	A.Tracks = []Track{
+		{Artist: A, Name: "hits", ID: "1", Featured: []*Artists{B}, Compilation: true},
+		{Artist: A, Name: "single", ID: "2", Featured: []*Artists{C}},
+		{Artist: A, Name: "best of", ID: "3", Featured: []*Artists{C}, Compilation: true},
+	}
+	h := NewHelper()
+	h.ArtistMap["A"] = A
+	_, meta := BuildGraph(h, C)
+	if ab := meta[EdgeKey{From: "A", To: "B"}]; !ab.IsCompilation {
+		t.Fatalf("A-B only shares a compilation track: %+v", ab)
+	}
+	if ac := meta[EdgeKey{From: "A", To: "C"}]; ac.IsCompilation || ac.Evidence != "single" {
+		t.Fatalf("A-C has a regular release and should cite it: %+v", ac)
+	}
+}
+
+func TestCompilationAlbums(t *testing.T) {
+	body := []byte(`{"items":[{"id":"a1","album_type":"album"},{"id":"c1","album_type":"compilation"},{"id":"c2","album_group":"compilation"}]}`)
+	got := CompilationAlbums(body)
+	if len(got) != 2 || !got["c1"] || !got["c2"] {
+		t.Fatalf("unexpected compilations %v", got)
+	}
+}
+
+func TestParseAlbumInfo(t *testing.T) {
+	body := []byte(`{"items":[{"id":"a1","album_type":"album","release_date":"1997-05-20"},{"id":"a2","release_date":"2004"},{"id":"c1","album_type":"compilation","release_date":""}]}`)
+	got := ParseAlbumInfo(body)
+	if got["a1"].Year != 1997 || got["a2"].Year != 2004 || got["c1"].Year != 0 || !got["c1"].Compilation {
+		t.Fatalf("unexpected album info %+v", got)
+	}
+}
+
+func TestCollaborators_MarksGuestOnlyEdges(t *testing.T) {
+	A, B, C := CreateArtists("A", "a"), CreateArtists("B", "b"), CreateArtists("C", "c")
+	A.Tracks = []Track{
+		{Artist: A, ID: "1", Name: "On B's record", Featured: []*Artists{B}, Guest: true},
+		{Artist: A, ID: "2", Name: "On C's record", Featured: []*Artists{C}, Guest: true},
+		{Artist: A, ID: "3", Name: "Also on C's record", Featured: []*Artists{C}, Guest: true},
+	}
+	for _, c := range collaborators(A, nil) {
+		if !c.ctx.Guest {
+			t.Fatalf("A only lists guest appearances, got %+v for %s", c.ctx, c.artist.Name)
+		}
+	}
+
+	// C lists track 3 on its own album, so A-C is not a guest-only edge.
+	C.Tracks = []Track{{Artist: C, ID: "3", Name: "Also on C's record", Featured: []*Artists{A}}}
+	h := NewHelper()
+	for _, a := range []*Artists{A, B, C} {
+		h.ArtistMap[a.Name] = a
+	}
+	_, meta := buildGraph(h, C, nil)
+	if e := meta[EdgeKey{From: "A", To: "B"}]; !e.Guest {
+		t.Fatalf("A-B should be guest-only: %+v", e)
+	}
+	if e := meta[EdgeKey{From: "A", To: "C"}]; e.Guest || e.SharedCount != 2 || e.Evidence != "Also on C's record" {
+		t.Fatalf("A-C should prefer the own-release evidence: %+v", e)
+	}
+}
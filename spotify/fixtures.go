@@ -0,0 +1,171 @@
+package spotify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Fixture modes.
+const (
+	Record = "record" // pass requests on and save the responses
+	Replay = "replay" // answer requests from saved responses only
+)
+
+// Fixtures is an http.RoundTripper that records API responses to files in
+// Dir, or replays them from there without touching the network. Responses
+// are keyed by method, path, query and body, not host, so fixtures recorded
+// against Spotify replay against any base URL. Use it with SetTransport.
+type Fixtures struct {
+	Dir  string
+	Mode string // Record or Replay
+
+	// Next sends recorded requests; nil uses http.DefaultTransport.
+	Next http.RoundTripper
+
+	mu     sync.Mutex
+	misses []string // replayed requests without a fixture
+}
+
+// UseFixtures routes API requests through fixtures in dir. In Replay mode it
+// also sets a placeholder access token, since replayed requests need no
+// authorization.
+func UseFixtures(dir, mode string) (*Fixtures, error) {
+	f := &Fixtures{Dir: dir, Mode: mode}
+	switch mode {
+	case Record:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	case Replay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+		SetAccessToken("replay")
+	default:
+		return nil, fmt.Errorf("unknown fixture mode %q (want %s or %s)", mode, Record, Replay)
+	}
+	SetTransport(f)
+	return f, nil
+}
+
+// fixture is one saved response.
+type fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"` // path and query, without the host
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// savedHeaders are the response headers worth keeping in a fixture.
+var savedHeaders = []string{"Content-Type", "Retry-After"}
+
+func (f *Fixtures) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	path := filepath.Join(f.Dir, fixtureName(req, body))
+
+	if f.Mode == Replay {
+		b, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			// Answer like Spotify would rather than fail, which would make
+			// the caller retry.
+			f.mu.Lock()
+			f.misses = append(f.misses, req.Method+" "+req.URL.RequestURI())
+			f.mu.Unlock()
+			logger().Warn("no fixture for request", "method", req.Method, "url", req.URL.RequestURI(), "file", path)
+			msg, _ := json.Marshal("no recorded fixture for " + req.Method + " " + req.URL.RequestURI())
+			return response(req, http.StatusNotFound, http.Header{"Content-Type": {"application/json"}},
+				`{"error":{"status":404,"message":`+string(msg)+`}}`), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var fx fixture
+		if err := json.Unmarshal(b, &fx); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", path, err)
+		}
+		return response(req, fx.Status, fx.Header, fx.Body), nil
+	}
+
+	next := f.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	// Rate limits and server errors are retried; keep the answer that
+	// follows them instead.
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return resp, nil
+	}
+	fx := fixture{Method: req.Method, URL: req.URL.RequestURI(), Status: resp.StatusCode, Header: http.Header{}, Body: string(respBody)}
+	for _, h := range savedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			fx.Header.Set(h, v)
+		}
+	}
+	b, _ := json.MarshalIndent(fx, "", "  ")
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Misses lists the replayed requests that had no fixture, in order.
+func (f *Fixtures) Misses() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.misses...)
+}
+
+// fixtureName names the file of a request: its endpoint for readability,
+// then a digest of everything that tells requests apart. Go encodes query
+// parameters in sorted order, so their order does not matter.
+func fixtureName(req *http.Request, body []byte) string {
+	q := req.URL.Query()
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.Path + "?" + q.Encode() + "\n" + string(body)))
+	name := strings.NewReplacer("/", "_", "{", "", "}", "").Replace(endpointName(req.URL.Path))
+	return fmt.Sprintf("%s_%s_%s.json", strings.ToLower(req.Method), name, hex.EncodeToString(sum[:])[:12])
+}
+
+func response(req *http.Request, status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
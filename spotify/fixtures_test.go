@@ -0,0 +1,101 @@
+package spotify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// useTestAPI points the client at ts with a fixed token until the test ends.
+func useTestAPI(t *testing.T, ts *httptest.Server) {
+	oldClient := httpClient
+	SetBaseURL(ts.URL)
+	SetAccessToken("test")
+	t.Cleanup(func() {
+		httpClient = oldClient
+		SetBaseURL("")
+		SetAccessToken("")
+	})
+}
+
+func TestArtistAlbums_UsesBaseURL(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path + " " + r.Header.Get("Authorization")
+		w.Write([]byte(`{"items":[{"id":"al1"}],"next":null}`))
+	}))
+	defer ts.Close()
+	useTestAPI(t, ts)
+
+	body, err := ArtistAlbums("a1", FetchOptions{})
+	if err != nil || !strings.Contains(string(body), "al1") {
+		t.Fatalf("got %s, %v", body, err)
+	}
+	if got != "/v1/artists/a1/albums Bearer test" {
+		t.Fatalf("request was %q", got)
+	}
+}
+
+func TestFixtures_RecordThenReplay(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/v1/search":
+			w.Write([]byte(`{"artists":{"items":[{"id":"a1","name":"` + r.URL.Query().Get("q") + `"}]}}`))
+		case "/v1/albums/al1/tracks":
+			w.Write([]byte(`{"items":[{"id":"t1","name":"One"}],"next":null}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	useTestAPI(t, ts)
+	dir := t.TempDir()
+
+	if _, err := UseFixtures(dir, Record); err != nil {
+		t.Fatal(err)
+	}
+	searched, err := SearchArtist("Nina Simone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracks, err := GetAlbumTracks("al1", FetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Fatalf("expected 2 fixture files, got %d", len(files))
+	}
+
+	f, err := UseFixtures(dir, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetBaseURL("http://replay.invalid") // fixtures do not depend on the host
+	if again, err := SearchArtist("Nina Simone"); err != nil || string(again) != string(searched) {
+		t.Fatalf("replayed search %s (%v), recorded %s", again, err, searched)
+	}
+	if again, err := GetAlbumTracks("al1", FetchOptions{}); err != nil || string(again) != string(tracks) {
+		t.Fatalf("replayed tracks %s (%v), recorded %s", again, err, tracks)
+	}
+	if requests != 2 {
+		t.Fatalf("replay reached the server: %d requests", requests)
+	}
+
+	// An unrecorded request is answered with a 404, not retried.
+	if _, err := SearchArtist("Someone Else"); err != nil {
+		t.Fatal(err)
+	}
+	if misses := f.Misses(); len(misses) != 1 || !strings.Contains(misses[0], "Someone") {
+		t.Fatalf("misses = %q", misses)
+	}
+}
+
+func TestUseFixtures_RejectsUnknownMode(t *testing.T) {
+	if _, err := UseFixtures(t.TempDir(), "rewind"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,22 @@
+package spotify
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var clientLogger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger for the client. Requests are logged at debug
+// level, retries and unexpected statuses at warn. nil restores slog.Default.
+func SetLogger(l *slog.Logger) {
+	clientLogger.Store(l)
+}
+
+func logger() *slog.Logger {
+	l := clientLogger.Load()
+	if l == nil {
+		l = slog.Default()
+	}
+	return l.With("component", "spotify")
+}
@@ -0,0 +1,125 @@
+package spotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// playlistBatch is the most tracks Spotify adds to a playlist per request.
+const playlistBatch = 100
+
+// Playlist is a playlist created on the user's account.
+type Playlist struct {
+	ID  string `json:"id"`
+	URL string `json:"url"` // link to open it in Spotify
+}
+
+// doJSON sends body as JSON and returns the response, turning non-2xx
+// statuses into errors.
+func doJSON(method, endpoint string, body interface{}) ([]byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range getHeader() {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	resp, status, err := fetchWithRetry(req, 5)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 {
+		return nil, apiError(status, resp)
+	}
+	return resp, nil
+}
+
+// apiError describes a failed request, using Spotify's error message when
+// the body has one.
+func apiError(status int, body []byte) error {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		return fmt.Errorf("spotify: status %d: %s", status, e.Error.Message)
+	}
+	return fmt.Errorf("spotify: status %d", status)
+}
+
+// CurrentUserID returns the Spotify ID of the authorized user.
+func CurrentUserID() (string, error) {
+	body, err := doJSON("GET", apiURL("/v1/me"), nil)
+	if err != nil {
+		return "", err
+	}
+	var me struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &me); err != nil {
+		return "", err
+	}
+	if me.ID == "" {
+		return "", errors.New("spotify: no user ID in profile")
+	}
+	return me.ID, nil
+}
+
+// CreatePlaylist creates an empty playlist on the authorized user's account.
+// It needs the playlist-modify-private (or -public) scope.
+func CreatePlaylist(name, description string, public bool) (Playlist, error) {
+	user, err := CurrentUserID()
+	if err != nil {
+		return Playlist{}, err
+	}
+	body, err := doJSON("POST", apiURL("/v1/users/"+user+"/playlists"), map[string]interface{}{
+		"name":        name,
+		"description": description,
+		"public":      public,
+	})
+	if err != nil {
+		return Playlist{}, err
+	}
+	var created struct {
+		ID           string            `json:"id"`
+		ExternalURLs map[string]string `json:"external_urls"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		return Playlist{}, err
+	}
+	return Playlist{ID: created.ID, URL: created.ExternalURLs["spotify"]}, nil
+}
+
+// AddPlaylistTracks appends tracks to a playlist in order, in batches of
+// playlistBatch.
+func AddPlaylistTracks(playlistID string, trackIDs []string) error {
+	endpoint := apiURL("/v1/playlists/" + playlistID + "/tracks")
+	for i := 0; i < len(trackIDs); i += playlistBatch {
+		end := i + playlistBatch
+		if end > len(trackIDs) {
+			end = len(trackIDs)
+		}
+		uris := make([]string, 0, end-i)
+		for _, id := range trackIDs[i:end] {
+			uris = append(uris, "spotify:track:"+strings.TrimPrefix(id, "spotify:track:"))
+		}
+		if _, err := doJSON("POST", endpoint, map[string][]string{"uris": uris}); err != nil {
+			return fmt.Errorf("add tracks to playlist %s: %w", playlistID, err)
+		}
+	}
+	return nil
+}
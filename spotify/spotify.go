@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math"
 	"math/rand"
 	"net/http"
@@ -13,6 +12,8 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -28,12 +29,20 @@ type Auth struct {
 
 type Playback struct {
 	Progress float64     `json:"progress_ms"`
+	Playing  bool        `json:"is_playing"`
 	Item     interface{} `json:"item"`
 }
 
 type Queue struct {
 	Progress, Duration             float64
 	TrackName, TrackPhoto, TrackID string
+	Playing                        bool
+	Artists                        []Credit // credited artists, primary first
+}
+
+// Credit is an artist credited on a track.
+type Credit struct {
+	Name, ID string
 }
 
 type PaginatedItems struct {
@@ -49,6 +58,138 @@ type PaginatedItems struct {
 
 var httpClient = &http.Client{Timeout: 15 * time.Second}
 
+// DefaultBaseURL is where API requests go unless SetBaseURL says otherwise.
+const DefaultBaseURL = "https://api.spotify.com"
+
+var (
+	baseURL     = DefaultBaseURL
+	accessToken string // set by SetAccessToken; overrides the saved token
+)
+
+// SetBaseURL sends API requests to base (scheme and host, e.g. an httptest
+// server's URL) instead of DefaultBaseURL. Empty restores the default. Like
+// SetTransport and SetAccessToken, call it before making requests.
+func SetBaseURL(base string) {
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	baseURL = strings.TrimRight(base, "/")
+}
+
+// SetTransport sends API requests through rt, e.g. a Fixtures. nil restores
+// http.DefaultTransport.
+func SetTransport(rt http.RoundTripper) {
+	httpClient = &http.Client{Timeout: 15 * time.Second, Transport: rt}
+}
+
+// SetAccessToken makes requests use tok instead of the saved token, so they
+// never start the authorization flow. Empty restores the saved token.
+func SetAccessToken(tok string) {
+	accessToken = tok
+}
+
+// apiURL returns the URL of an API path such as "/v1/search".
+func apiURL(path string) string {
+	return baseURL + path
+}
+
+// CallStats counts the requests this process has sent to the Web API.
+type CallStats struct {
+	Calls         map[string]int // by endpoint, e.g. "artists/{id}/albums"
+	Responses     map[int]int    // by HTTP status code
+	Errors        int            // requests that got no response
+	ThrottleWaits int            // pauses for rate limiting
+	Throttled     time.Duration  // total time spent in those pauses
+}
+
+var callStats = struct {
+	sync.Mutex
+	CallStats
+}{CallStats: CallStats{Calls: make(map[string]int), Responses: make(map[int]int)}}
+
+// Stats returns a copy of the process-wide request counters. Subtract an
+// earlier copy with Since to measure one operation; concurrent operations are
+// not told apart.
+func Stats() CallStats {
+	callStats.Lock()
+	defer callStats.Unlock()
+	s := callStats.CallStats
+	s.Calls = make(map[string]int, len(callStats.Calls))
+	for k, v := range callStats.Calls {
+		s.Calls[k] = v
+	}
+	s.Responses = make(map[int]int, len(callStats.Responses))
+	for k, v := range callStats.Responses {
+		s.Responses[k] = v
+	}
+	return s
+}
+
+// Since returns the requests made between prev and s.
+func (s CallStats) Since(prev CallStats) CallStats {
+	d := CallStats{
+		Calls:         make(map[string]int),
+		Responses:     make(map[int]int),
+		Errors:        s.Errors - prev.Errors,
+		ThrottleWaits: s.ThrottleWaits - prev.ThrottleWaits,
+		Throttled:     s.Throttled - prev.Throttled,
+	}
+	for k, v := range s.Calls {
+		if n := v - prev.Calls[k]; n > 0 {
+			d.Calls[k] = n
+		}
+	}
+	for k, v := range s.Responses {
+		if n := v - prev.Responses[k]; n > 0 {
+			d.Responses[k] = n
+		}
+	}
+	return d
+}
+
+func countCall(u *url.URL) {
+	callStats.Lock()
+	callStats.Calls[endpointName(u.Path)]++
+	callStats.Unlock()
+}
+
+// countResponse records the outcome of one request; status 0 means it failed
+// before a response arrived.
+func countResponse(status int) {
+	callStats.Lock()
+	if status == 0 {
+		callStats.Errors++
+	} else {
+		callStats.Responses[status]++
+	}
+	callStats.Unlock()
+}
+
+// throttle sleeps for d and records it as a rate-limit pause.
+func throttle(d time.Duration) {
+	callStats.Lock()
+	callStats.ThrottleWaits++
+	callStats.Throttled += d
+	callStats.Unlock()
+	time.Sleep(d)
+}
+
+// endpointName turns a request path into an endpoint label, replacing IDs:
+// "/v1/artists/123/albums" becomes "artists/{id}/albums".
+func endpointName(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) > 0 && parts[0] == "v1" {
+		parts = parts[1:]
+	}
+	for i := 1; i < len(parts); i++ {
+		switch parts[i-1] {
+		case "artists", "albums", "tracks", "playlists", "users":
+			parts[i] = "{id}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
 func doRequest(method, endpoint string, header, query map[string]string) ([]byte, int, error) {
 	req, err := http.NewRequest(method, endpoint, nil)
 	if err != nil {
@@ -72,25 +213,41 @@ func doRequest(method, endpoint string, header, query map[string]string) ([]byte
 func fetchWithRetry(req *http.Request, maxRetries int) ([]byte, int, error) {
 	var lastErr error
 	var status int
+	log := logger().With("method", req.Method, "endpoint", endpointName(req.URL.Path))
 	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			// the previous attempt consumed the body
+			if body, err := req.GetBody(); err == nil {
+				req.Body = body
+			}
+		}
+		countCall(req.URL)
+		began := time.Now()
 		resp, err := httpClient.Do(req)
 		if err != nil {
+			countResponse(0)
+			log.Warn("request failed", "attempt", attempt+1, "err", err)
 			lastErr = err
 			time.Sleep(backoffDuration(attempt))
 			continue
 		}
 		status = resp.StatusCode
+		countResponse(status)
 		body, _ := ioReadAll(resp.Body)
 		resp.Body.Close()
+		log.Debug("request", "status", status, "attempt", attempt+1, "took", time.Since(began))
 
 		if status >= 200 && status < 300 {
 			return body, status, nil
 		}
 		if status == http.StatusTooManyRequests {
-			time.Sleep(retryAfterDelay(resp))
+			wait := retryAfterDelay(resp)
+			log.Warn("rate limited", "retry_after", wait, "attempt", attempt+1)
+			throttle(wait)
 			continue
 		}
 		if status >= 500 {
+			log.Warn("server error, retrying", "status", status, "attempt", attempt+1)
 			time.Sleep(backoffDuration(attempt))
 			continue
 		}
@@ -121,24 +278,53 @@ func backoffDuration(attempt int) time.Duration {
 // Auth utilities
 
 func getHeader() map[string]string {
+	if accessToken != "" {
+		return map[string]string{"Authorization": "Bearer " + accessToken}
+	}
 	tok, err := loadOrObtainToken()
 	if err != nil {
-		log.Fatalf("Spotify token error: %v", err)
+		logger().Error("no Spotify token", "err", err)
+		os.Exit(1)
 	}
 	return map[string]string{"Authorization": "Bearer " + tok.AccessToken}
 }
 
-func loadOrObtainToken() (*Auth, error) {
-	path := "./main/authToken.txt"
+const tokenPath = "./main/authToken.txt"
+
+// readToken loads the saved token without starting the authorization flow.
+func readToken(path string) (*Auth, error) {
 	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Auth
+	if err := json.Unmarshal(b, &t); err != nil || t.AccessToken == "" {
+		return nil, errors.New("invalid Spotify token")
+	}
+	if isExpired(&t) {
+		return nil, errors.New("Spotify token expired")
+	}
+	return &t, nil
+}
+
+// CheckToken reports whether a valid Spotify token is saved. Unlike API
+// calls it never starts the authorization flow, so it is safe for health
+// checks.
+func CheckToken() error {
+	_, err := readToken(tokenPath)
+	return err
+}
+
+func loadOrObtainToken() (*Auth, error) {
+	path := tokenPath
+	t, err := readToken(path)
 	if err == nil {
-		var t Auth
-		if json.Unmarshal(b, &t) == nil && t.AccessToken != "" && !isExpired(&t) {
-			return &t, nil
-		}
-		log.Println("Existing Spotify token expired or invalid.")
+		return t, nil
+	}
+	if os.IsNotExist(err) {
+		logger().Info("no Spotify token found; starting authorization flow")
 	} else {
-		log.Println("No Spotify token found; starting authorization flow...")
+		logger().Info("Spotify token unusable; starting authorization flow", "err", err)
 	}
 
 	if err := launchAuthFlow(); err != nil {
@@ -147,11 +333,8 @@ func loadOrObtainToken() (*Auth, error) {
 
 	deadline := time.Now().Add(2 * time.Minute)
 	for time.Now().Before(deadline) {
-		if b, err := os.ReadFile(path); err == nil {
-			var t Auth
-			if json.Unmarshal(b, &t) == nil && t.AccessToken != "" && !isExpired(&t) {
-				return &t, nil
-			}
+		if t, err := readToken(path); err == nil {
+			return t, nil
 		}
 		time.Sleep(1 * time.Second)
 	}
@@ -174,9 +357,9 @@ func launchAuthFlow() error {
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start auth server: %w", err)
 	}
-	log.Println("Auth server started, opening browser…")
+	logger().Info("auth server started, opening browser")
 	if err := exec.Command("xdg-open", "http://localhost:8392/").Start(); err != nil {
-		log.Printf("Please open http://localhost:8392/ manually: %v", err)
+		logger().Warn("cannot open browser; open http://localhost:8392/ manually", "err", err)
 	}
 	return nil
 }
@@ -193,28 +376,105 @@ func SearchArtist(artist string) ([]byte, error) {
 		"q":    artist,
 		"type": "artist",
 	}
-	body, _, err := doRequest("GET", "https://api.spotify.com/v1/search", header, q)
+	body, _, err := doRequest("GET", apiURL("/v1/search"), header, q)
 	return body, err
 }
 
-func ArtistAlbums(id string, limit int) ([]byte, error) {
-	base := fmt.Sprintf("https://api.spotify.com/v1/artists/%s/albums", id)
+// FetchOptions controls how much of an artist's catalog is fetched. Fetching
+// fewer albums and tracks is faster but finds fewer collaborations.
+type FetchOptions struct {
+	// IncludeGroups selects album groups: album, single, appears_on and
+	// compilation. Empty means album and single.
+	IncludeGroups []string
+	Market        string // ISO 3166-1 country code; empty means US
+	AlbumLimit    int    // albums per artist; 0 for the default, -1 for all
+	TrackLimit    int    // tracks per album; 0 or -1 for all
+}
+
+// DefaultAlbumLimit is the number of albums fetched per artist when
+// FetchOptions.AlbumLimit is 0.
+const DefaultAlbumLimit = 5
+
+// AlbumGroups lists the include groups Spotify accepts.
+var AlbumGroups = []string{"album", "single", "appears_on", "compilation"}
+
+// ParseIncludeGroups splits a comma-separated include_groups value and
+// rejects unknown groups.
+func ParseIncludeGroups(s string) ([]string, error) {
+	var groups []string
+	for _, g := range strings.Split(s, ",") {
+		g = strings.ToLower(strings.TrimSpace(g))
+		if g == "" {
+			continue
+		}
+		known := false
+		for _, ag := range AlbumGroups {
+			known = known || g == ag
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown album group %q (want %s)", g, strings.Join(AlbumGroups, ", "))
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+func (o FetchOptions) includeGroups() string {
+	if len(o.IncludeGroups) == 0 {
+		return "album,single"
+	}
+	return strings.Join(o.IncludeGroups, ",")
+}
+
+func (o FetchOptions) market() string {
+	if o.Market == "" {
+		return "US"
+	}
+	return o.Market
+}
+
+func (o FetchOptions) albumLimit() int {
+	switch {
+	case o.AlbumLimit == 0:
+		return DefaultAlbumLimit
+	case o.AlbumLimit < 0:
+		return math.MaxInt32
+	}
+	return o.AlbumLimit
+}
+
+func (o FetchOptions) trackLimit() int {
+	if o.TrackLimit <= 0 {
+		return math.MaxInt32
+	}
+	return o.TrackLimit
+}
+
+// pageSize asks for no more than limit items per page, up to Spotify's
+// maximum of 50.
+func pageSize(limit int) int {
+	if limit < 50 {
+		return limit
+	}
+	return 50
+}
+
+// ArtistAlbums fetches up to o's album limit of the artist's albums.
+func ArtistAlbums(id string, o FetchOptions) ([]byte, error) {
+	base := apiURL(fmt.Sprintf("/v1/artists/%s/albums", id))
 	header := getHeader()
 	header["Accept"], header["Content-Type"] = "application/json", "application/json"
 
-	pageSize := 50
-	totalLimit := limit
-	if limit < 0 {
-		totalLimit = math.MaxInt32
-	}
+	totalLimit := o.albumLimit()
+	size := pageSize(totalLimit)
 
 	var agg []interface{}
 	offset := 0
 	for {
 		params := map[string]string{
-			"include_groups": "album,single",
-			"market":         "US",
-			"limit":          strconv.Itoa(pageSize),
+			"include_groups": o.includeGroups(),
+			"market":         o.market(),
+			"limit":          strconv.Itoa(size),
 			"offset":         strconv.Itoa(offset),
 		}
 		body, status, err := doRequest("GET", base, header, params)
@@ -222,10 +482,10 @@ func ArtistAlbums(id string, limit int) ([]byte, error) {
 			return nil, err
 		}
 		if status == 429 {
-			time.Sleep(300 * time.Millisecond)
+			throttle(300 * time.Millisecond)
 		}
 		if status < 200 || status >= 300 {
-			log.Printf("warning: ArtistAlbums non-2xx status=%d for id=%s", status, id)
+			logger().Warn("unexpected status for artist albums", "status", status, "artist_id", id)
 		}
 		var page PaginatedItems
 		if err := json.Unmarshal(body, &page); err != nil {
@@ -235,7 +495,10 @@ func ArtistAlbums(id string, limit int) ([]byte, error) {
 		if len(agg) >= totalLimit || page.Next == nil || *page.Next == "" || len(page.Items) == 0 {
 			break
 		}
-		offset += pageSize
+		offset += size
+	}
+	if len(agg) > totalLimit {
+		agg = agg[:totalLimit]
 	}
 	out, _ := json.Marshal(struct {
 		Items []interface{} `json:"items"`
@@ -243,35 +506,43 @@ func ArtistAlbums(id string, limit int) ([]byte, error) {
 	return out, nil
 }
 
-func GetAlbumTracks(id string) ([]byte, error) {
-	base := fmt.Sprintf("https://api.spotify.com/v1/albums/%s/tracks", id)
+// GetAlbumTracks fetches up to o's track limit of the album's tracks.
+func GetAlbumTracks(id string, o FetchOptions) ([]byte, error) {
+	base := apiURL(fmt.Sprintf("/v1/albums/%s/tracks", id))
 	header := getHeader()
 	header["Accept"], header["Content-Type"] = "application/json", "application/json"
 
-	pageSize := 50
+	totalLimit := o.trackLimit()
+	size := pageSize(totalLimit)
 	var agg []interface{}
 	offset := 0
 	for {
 		params := map[string]string{
-			"limit":  strconv.Itoa(pageSize),
+			"limit":  strconv.Itoa(size),
 			"offset": strconv.Itoa(offset),
 		}
+		if o.Market != "" {
+			params["market"] = o.Market
+		}
 		body, status, err := doRequest("GET", base, header, params)
 		if err != nil {
 			return nil, err
 		}
 		if status < 200 || status >= 300 {
-			log.Printf("warning: GetAlbumTracks non-2xx status=%d for id=%s", status, id)
+			logger().Warn("unexpected status for album tracks", "status", status, "album_id", id)
 		}
 		var page PaginatedItems
 		if err := json.Unmarshal(body, &page); err != nil {
 			return nil, err
 		}
 		agg = append(agg, page.Items...)
-		if page.Next == nil || *page.Next == "" || len(page.Items) == 0 {
+		if len(agg) >= totalLimit || page.Next == nil || *page.Next == "" || len(page.Items) == 0 {
 			break
 		}
-		offset += pageSize
+		offset += size
+	}
+	if len(agg) > totalLimit {
+		agg = agg[:totalLimit]
 	}
 	out, _ := json.Marshal(struct {
 		Items []interface{} `json:"items"`
@@ -279,54 +550,133 @@ func GetAlbumTracks(id string) ([]byte, error) {
 	return out, nil
 }
 
+// Batch sizes of Spotify's several-items endpoints.
+const (
+	MaxArtistIDs = 50
+	MaxAlbumIDs  = 20
+)
+
+// GetArtists fetches full artist objects for ids, batching as many IDs per
+// request as Spotify allows. The result is {"artists": [...]}, in the order
+// of ids; unknown IDs come back as null.
+func GetArtists(ids []string) ([]byte, error) {
+	return getSeveral(apiURL("/v1/artists"), "artists", ids, MaxArtistIDs, getHeader(), nil)
+}
+
+// GetAlbums fetches full album objects, including their first page of tracks,
+// for ids. The result is {"albums": [...]}, in the order of ids.
+func GetAlbums(ids []string, o FetchOptions) ([]byte, error) {
+	var params map[string]string
+	if o.Market != "" {
+		params = map[string]string{"market": o.Market}
+	}
+	return getSeveral(apiURL("/v1/albums"), "albums", ids, MaxAlbumIDs, getHeader(), params)
+}
+
+// getSeveral calls a several-items endpoint in batches of up to batch IDs and
+// joins the key arrays of the responses.
+func getSeveral(endpoint, key string, ids []string, batch int, header, params map[string]string) ([]byte, error) {
+	header["Accept"], header["Content-Type"] = "application/json", "application/json"
+
+	agg := make([]json.RawMessage, 0, len(ids))
+	for start := 0; start < len(ids); start += batch {
+		end := start + batch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		q := map[string]string{"ids": strings.Join(ids[start:end], ",")}
+		for k, v := range params {
+			q[k] = v
+		}
+		body, status, err := doRequest("GET", endpoint, header, q)
+		if err != nil {
+			return nil, err
+		}
+		if status < 200 || status >= 300 {
+			return nil, fmt.Errorf("%s: status %d", endpoint, status)
+		}
+		var page map[string][]json.RawMessage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		agg = append(agg, page[key]...)
+	}
+	return json.Marshal(map[string][]json.RawMessage{key: agg})
+}
+
 // ========================================================== //
 // Playback utilities
 
 func reqPlayback() (Playback, []byte) {
-	ep := "https://api.spotify.com/v1/me/player/currently-playing?market=US"
+	ep := apiURL("/v1/me/player/currently-playing?market=US")
 	var p Playback
 	h := getHeader()
 	h["Accept"], h["Content-Type"] = "application/json", "application/json"
 	body, _, err := doRequest("GET", ep, h, nil)
 	if err != nil {
-		log.Println("reqPlayback error:", err)
+		logger().Warn("playback request failed", "err", err)
 		return p, nil
 	}
 	_ = json.Unmarshal(body, &p)
 	return p, body
 }
 
-func postSpotify(endpoint string, header, query map[string]string) {
-	_, status, err := doRequest("POST", endpoint, header, query)
+func sendPlayer(method, endpoint string, header, query map[string]string) error {
+	body, status, err := doRequest(method, endpoint, header, query)
 	if err != nil {
-		log.Printf("POST %s error: %v", endpoint, err)
-		return
+		logger().Warn("player request failed", "method", method, "url", endpoint, "err", err)
+		return err
+	}
+	logger().Debug("player request", "method", method, "url", endpoint, "status", status)
+	if status < 200 || status >= 300 {
+		return apiError(status, body)
 	}
-	log.Printf("POST %s status %d", endpoint, status)
+	return nil
 }
 
-func AddQueue(tracks []string) {
-	log.Printf("Adding %d tracks to queue", len(tracks))
-	headers := getHeader()
-	headers["Content-Type"], headers["Accept"] = "application/json", "application/json"
-	uri := "spotify:track:"
-	for _, t := range tracks {
-		postSpotify("https://api.spotify.com/v1/me/player/queue", headers, map[string]string{"uri": uri + t})
+// PlayTracks starts playing tracks, in order, on the user's active device,
+// replacing whatever was playing so the first of them plays right away.
+// Spotify answers 404 when no device is active.
+func PlayTracks(tracks []string) error {
+	logger().Info("starting playback", "tracks", len(tracks))
+	uris := make([]string, len(tracks))
+	for i, t := range tracks {
+		uris[i] = "spotify:track:" + t
 	}
-	postSpotify("https://api.spotify.com/v1/me/player/next", headers, nil)
+	_, err := doJSON("PUT", apiURL("/v1/me/player/play"), map[string][]string{"uris": uris})
+	return err
+}
+
+// playerActions maps the actions Controller accepts to their requests.
+var playerActions = map[string]struct{ method, path string }{
+	"play":     {"PUT", "play"},
+	"pause":    {"PUT", "pause"},
+	"next":     {"POST", "next"},
+	"previous": {"POST", "previous"},
 }
 
-func Controller(endpoint string) {
-	log.Println("Invoking controller:", endpoint)
+// Controller sends a playback command to the user's active device: play,
+// pause, next or previous.
+func Controller(action string) error {
+	a, ok := playerActions[action]
+	if !ok {
+		return fmt.Errorf("unknown player action %q", action)
+	}
 	headers := getHeader()
 	headers["Content-Type"], headers["Accept"] = "application/json", "application/json"
-	postSpotify(endpoint, headers, nil)
+	return sendPlayer(a.method, apiURL("/v1/me/player/"+a.path), headers, nil)
 }
 
 func GetPlayback() Queue {
 	pb, _ := reqPlayback()
+	return playbackQueue(pb)
+}
+
+// playbackQueue extracts the fields we show from a currently-playing response.
+func playbackQueue(pb Playback) Queue {
 	var q Queue
 	q.Progress = pb.Progress
+	q.Playing = pb.Playing
 
 	itemMap, ok := pb.Item.(map[string]interface{})
 	if !ok {
@@ -341,6 +691,19 @@ func GetPlayback() Queue {
 	if id, ok := itemMap["id"].(string); ok {
 		q.TrackID = id
 	}
+	if arts, ok := itemMap["artists"].([]interface{}); ok {
+		for _, art := range arts {
+			m, ok := art.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := m["name"].(string)
+			id, _ := m["id"].(string)
+			if name != "" {
+				q.Artists = append(q.Artists, Credit{Name: name, ID: id})
+			}
+		}
+	}
 	if album, ok := itemMap["album"].(map[string]interface{}); ok {
 		if imgs, ok := album["images"].([]interface{}); ok && len(imgs) > 1 {
 			if m, ok := imgs[1].(map[string]interface{}); ok {
@@ -2,8 +2,12 @@ package spotify
 
 import (
 	"bytes"
+	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -184,3 +188,213 @@ func TestIOReadAll_ReturnsData(t *testing.T) {
 		t.Fatalf("ioReadAll mismatch: got %q want %q", string(buf), string(data))
 	}
 }
+
+func TestParseIncludeGroups(t *testing.T) {
+	got, err := ParseIncludeGroups(" Album, appears_on,,compilation ")
+	if err != nil || len(got) != 3 || got[0] != "album" || got[1] != "appears_on" || got[2] != "compilation" {
+		t.Fatalf("unexpected groups %v (err %v)", got, err)
+	}
+	if _, err := ParseIncludeGroups("album,mixtape"); err == nil {
+		t.Fatalf("expected an error for an unknown group")
+	}
+}
+
+func TestFetchOptions_Defaults(t *testing.T) {
+	var o FetchOptions
+	if o.includeGroups() != "album,single" || o.market() != "US" || o.albumLimit() != DefaultAlbumLimit || o.trackLimit() != math.MaxInt32 {
+		t.Fatalf("unexpected defaults: %q %q %d %d", o.includeGroups(), o.market(), o.albumLimit(), o.trackLimit())
+	}
+	o = FetchOptions{IncludeGroups: []string{"appears_on"}, Market: "DE", AlbumLimit: -1, TrackLimit: 3}
+	if o.includeGroups() != "appears_on" || o.market() != "DE" || o.albumLimit() != math.MaxInt32 || o.trackLimit() != 3 {
+		t.Fatalf("options not honored: %q %q %d %d", o.includeGroups(), o.market(), o.albumLimit(), o.trackLimit())
+	}
+	if pageSize(3) != 3 || pageSize(math.MaxInt32) != 50 {
+		t.Fatalf("unexpected page sizes %d %d", pageSize(3), pageSize(math.MaxInt32))
+	}
+}
+
+func TestGetSeveral_BatchesIDs(t *testing.T) {
+	var batches []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids := r.URL.Query().Get("ids")
+		batches = append(batches, ids)
+		var items []string
+		for _, id := range strings.Split(ids, ",") {
+			items = append(items, `{"id":"`+id+`"}`)
+		}
+		_, _ = w.Write([]byte(`{"artists":[` + strings.Join(items, ",") + `]}`))
+	}))
+	defer ts.Close()
+
+	oldClient := httpClient
+	httpClient = ts.Client()
+	defer func() { httpClient = oldClient }()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		ids = append(ids, strconv.Itoa(i))
+	}
+	body, err := getSeveral(ts.URL, "artists", ids, 2, map[string]string{}, nil)
+	if err != nil {
+		t.Fatalf("getSeveral error: %v", err)
+	}
+	if len(batches) != 3 || batches[0] != "0,1" || batches[2] != "4" {
+		t.Fatalf("unexpected batches %q", batches)
+	}
+	if want := `{"artists":[{"id":"0"},{"id":"1"},{"id":"2"},{"id":"3"},{"id":"4"}]}`; string(body) != want {
+		t.Fatalf("got %s, want %s", body, want)
+	}
+}
+
+func TestEndpointName(t *testing.T) {
+	cases := map[string]string{
+		"/v1/artists/7dGJo4/albums": "artists/{id}/albums",
+		"/v1/albums/abc/tracks":     "albums/{id}/tracks",
+		"/v1/artists":               "artists",
+		"/v1/search":                "search",
+		"/v1/me/player/queue":       "me/player/queue",
+	}
+	for path, want := range cases {
+		if got := endpointName(path); got != want {
+			t.Errorf("endpointName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestStats_CountsCallsAndThrottling(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	oldClient := httpClient
+	httpClient = ts.Client()
+	defer func() { httpClient = oldClient }()
+
+	before := Stats()
+	if _, _, err := doRequest("GET", ts.URL+"/v1/albums/xyz/tracks", nil, nil); err != nil {
+		t.Fatalf("doRequest error: %v", err)
+	}
+	d := Stats().Since(before)
+	if d.Calls["albums/{id}/tracks"] != 2 || d.ThrottleWaits != 1 {
+		t.Fatalf("unexpected stats %+v", d)
+	}
+	if d.Responses[http.StatusTooManyRequests] != 1 || d.Responses[http.StatusOK] != 1 || d.Errors != 0 {
+		t.Fatalf("unexpected response counts %+v", d.Responses)
+	}
+}
+
+func TestFetchWithRetry_ResendsBody(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	oldClient := httpClient
+	httpClient = ts.Client()
+	defer func() { httpClient = oldClient }()
+
+	req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(`{"uris":[]}`))
+	if _, status, err := fetchWithRetry(req, 2); err != nil || status != http.StatusCreated {
+		t.Fatalf("status=%d err=%v", status, err)
+	}
+	if len(bodies) != 2 || bodies[1] != `{"uris":[]}` {
+		t.Fatalf("bodies = %q", bodies)
+	}
+}
+
+func TestAPIError_UsesSpotifyMessage(t *testing.T) {
+	err := apiError(403, []byte(`{"error":{"status":403,"message":"Insufficient client scope"}}`))
+	if err == nil || !strings.Contains(err.Error(), "Insufficient client scope") {
+		t.Fatalf("got %v", err)
+	}
+	if err := apiError(500, []byte("oops")); err == nil || err.Error() != "spotify: status 500" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestPlayTracks_StartsPlaybackWithURIs(t *testing.T) {
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioReadAll(r.Body)
+		got = append(got, r.Method+" "+r.URL.Path+" "+string(b))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	useTestAPI(t, ts)
+
+	if err := PlayTracks([]string{"t1", "t2"}); err != nil {
+		t.Fatalf("PlayTracks: %v", err)
+	}
+	want := `PUT /v1/me/player/play {"uris":["spotify:track:t1","spotify:track:t2"]}`
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("requests = %q, want [%q]", got, want)
+	}
+}
+
+func TestController_RejectsUnknownAction(t *testing.T) {
+	if err := Controller("rewind"); err == nil {
+		t.Fatal("expected an error for an unknown action")
+	}
+	for action, a := range playerActions {
+		if a.method != "PUT" && a.method != "POST" {
+			t.Errorf("%s uses %s", action, a.method)
+		}
+	}
+}
+
+func TestPlaybackQueue_ReadsCredits(t *testing.T) {
+	var pb Playback
+	body := `{"progress_ms": 1000, "is_playing": true, "item": {"id": "t1", "name": "Song", "duration_ms": 4000,
+		"artists": [{"name": "Host", "id": "h"}, {"name": "Guest", "id": "g"}]}}`
+	if err := json.Unmarshal([]byte(body), &pb); err != nil {
+		t.Fatal(err)
+	}
+	q := playbackQueue(pb)
+	if q.TrackID != "t1" || !q.Playing || q.Duration != 4000 {
+		t.Fatalf("unexpected queue %+v", q)
+	}
+	if len(q.Artists) != 2 || q.Artists[0] != (Credit{Name: "Host", ID: "h"}) || q.Artists[1].Name != "Guest" {
+		t.Fatalf("unexpected credits %+v", q.Artists)
+	}
+}
+
+func TestReadToken(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	if _, err := readToken(write("ok.json", `{"access_token":"abc","expiry":"`+future+`"}`)); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if _, err := readToken(write("old.json", `{"access_token":"abc","expiry":"`+past+`"}`)); err == nil {
+		t.Fatal("expired token accepted")
+	}
+	if _, err := readToken(write("empty.json", `{}`)); err == nil {
+		t.Fatal("token without access_token accepted")
+	}
+	if _, err := readToken(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Fatalf("missing file: got %v", err)
+	}
+}